@@ -1,6 +1,9 @@
 package main
 
 import (
+	"log"
+	"os"
+
 	"go.uber.org/fx"
 
 	fxModules "myScalidraw/infra/fx"
@@ -12,6 +15,13 @@ func main() {
 
 	_ = godotenv.Load(".env")
 
+	if len(os.Args) > 1 && fxModules.HasCommand(os.Args[1]) {
+		if err := fxModules.RunCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("%s failed: %v", os.Args[1], err)
+		}
+		return
+	}
+
 	fx.New(
 		fxModules.AllModules,
 	).Run()
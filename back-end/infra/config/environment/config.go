@@ -5,6 +5,11 @@ import (
 	"myScalidraw/pkg/projectError"
 )
 
+const (
+	StorageDriverMinIO = "minio"
+	StorageDriverLocal = "local"
+)
+
 type Config struct {
 	HTTP struct {
 		Url  string
@@ -13,6 +18,15 @@ type Config struct {
 	DB struct {
 		URL_DB string
 	}
+	STORAGE struct {
+		Driver      string
+		LocalPath   string
+		Compression string
+	}
+	ENCRYPTION struct {
+		MasterKeys  string
+		ActiveKeyID string
+	}
 	MINIO struct {
 		Endpoint  string
 		AccessKey string
@@ -20,6 +34,12 @@ type Config struct {
 		Bucket    string
 		UseSSL    bool
 	}
+	TRASH struct {
+		RetentionDays int
+	}
+	EXPORT struct {
+		FontDir string
+	}
 	URL_SHORTENED_PREFIX string
 	JWT_SECRET           string
 	FRONTEND_URL         string
@@ -57,31 +77,69 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
-	minioEndpoint, err := getString("MINIO_ENDPOINT", "Error loading MinIO Endpoint")
-	if err != nil {
-		return nil, err
+	storageDriver := env.GetEnvOrDefault("STORAGE_DRIVER", StorageDriverMinIO)
+	if storageDriver != StorageDriverMinIO && storageDriver != StorageDriverLocal {
+		return nil, &projectError.Error{
+			Code:    projectError.EINVALID,
+			Message: "Error loading Storage Driver: expected minio or local",
+		}
 	}
 
-	minioAccessKey, err := getString("MINIO_ACCESS_KEY", "Error loading MinIO Access Key")
-	if err != nil {
-		return nil, err
+	storageLocalPath := env.GetEnvOrDefault("STORAGE_LOCAL_PATH", "./data")
+
+	storageCompression := env.GetEnvOrDefault("STORAGE_COMPRESSION", "zstd")
+	if storageCompression != "zstd" && storageCompression != "gzip" && storageCompression != "none" {
+		return nil, &projectError.Error{
+			Code:    projectError.EINVALID,
+			Message: "Error loading Storage Compression: expected zstd, gzip or none",
+		}
 	}
 
-	minioSecretKey, err := getString("MINIO_SECRET_KEY", "Error loading MinIO Secret Key")
-	if err != nil {
-		return nil, err
+	encryptionMasterKeys := env.GetEnvOrDefault("STORAGE_ENCRYPTION_KEYS", "")
+	encryptionActiveKeyID := env.GetEnvOrDefault("STORAGE_ENCRYPTION_KEY_ID", "")
+	if encryptionMasterKeys != "" && encryptionActiveKeyID == "" {
+		return nil, &projectError.Error{
+			Code:    projectError.EINVALID,
+			Message: "Error loading Storage Encryption Key ID: required when STORAGE_ENCRYPTION_KEYS is set",
+		}
 	}
 
-	minioBucket, err := getString("MINIO_BUCKET", "Error loading MinIO Bucket")
-	if err != nil {
-		return nil, err
+	var minioEndpoint, minioAccessKey, minioSecretKey, minioBucket string
+	var minioUseSSL bool
+	if storageDriver == StorageDriverMinIO {
+		minioEndpoint, err = getString("MINIO_ENDPOINT", "Error loading MinIO Endpoint")
+		if err != nil {
+			return nil, err
+		}
+
+		minioAccessKey, err = getString("MINIO_ACCESS_KEY", "Error loading MinIO Access Key")
+		if err != nil {
+			return nil, err
+		}
+
+		minioSecretKey, err = getString("MINIO_SECRET_KEY", "Error loading MinIO Secret Key")
+		if err != nil {
+			return nil, err
+		}
+
+		minioBucket, err = getString("MINIO_BUCKET", "Error loading MinIO Bucket")
+		if err != nil {
+			return nil, err
+		}
+
+		minioUseSSL, err = getBool("MINIO_USE_SSL", "Error loading MinIO UseSSL")
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	minioUseSSL, err := getBool("MINIO_USE_SSL", "Error loading MinIO UseSSL")
+	trashRetentionDays, err := getIntOrDefault("TRASH_RETENTION_DAYS", 30, "Error loading Trash Retention Days")
 	if err != nil {
 		return nil, err
 	}
 
+	exportFontDir := env.GetEnvOrDefault("EXPORT_FONT_DIR", "./fonts")
+
 	return &Config{
 		HTTP: struct {
 			Url  string
@@ -95,6 +153,22 @@ func NewConfig() (*Config, error) {
 		}{
 			URL_DB: dbURL,
 		},
+		STORAGE: struct {
+			Driver      string
+			LocalPath   string
+			Compression string
+		}{
+			Driver:      storageDriver,
+			LocalPath:   storageLocalPath,
+			Compression: storageCompression,
+		},
+		ENCRYPTION: struct {
+			MasterKeys  string
+			ActiveKeyID string
+		}{
+			MasterKeys:  encryptionMasterKeys,
+			ActiveKeyID: encryptionActiveKeyID,
+		},
 		MINIO: struct {
 			Endpoint  string
 			AccessKey string
@@ -108,6 +182,16 @@ func NewConfig() (*Config, error) {
 			Bucket:    minioBucket,
 			UseSSL:    minioUseSSL,
 		},
+		TRASH: struct {
+			RetentionDays int
+		}{
+			RetentionDays: trashRetentionDays,
+		},
+		EXPORT: struct {
+			FontDir string
+		}{
+			FontDir: exportFontDir,
+		},
 		URL_SHORTENED_PREFIX: urlShortenedPrefix,
 		JWT_SECRET:           jwtSecret,
 		FRONTEND_URL:         frontendUrl,
@@ -128,6 +212,18 @@ func getInt(key, errorMessage string) (int, error) {
 
 }
 
+func getIntOrDefault(key string, defaultValue int, errorMessage string) (int, error) {
+	value, err := env.GetEnvAsIntOrDefault(key, defaultValue)
+	if err != nil {
+		return 0, &projectError.Error{
+			Code:    projectError.EINVALID,
+			Message: errorMessage,
+		}
+	}
+
+	return value, nil
+}
+
 func getString(key, errorMessage string) (string, error) {
 	value, err := env.GetEnvOrDie(key)
 	if err != nil {
@@ -0,0 +1,154 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"go.uber.org/fx"
+
+	"myScalidraw/infra/config/environment"
+	"myScalidraw/infra/storage"
+	"myScalidraw/internal/domain/useCase/file"
+)
+
+var commands = map[string]func(args []string) error{
+	"rotate-keys": rotateKeys,
+	"fsck":        fsck,
+	"compress":    compress,
+}
+
+func HasCommand(name string) bool {
+	_, ok := commands[name]
+	return ok
+}
+
+func RunCommand(name string, args []string) error {
+	command, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command: %s", name)
+	}
+	return command(args)
+}
+
+// runWith monta só os módulos que o comando usa, roda os hooks de início (as
+// migrações do banco, por exemplo), executa run e depois os hooks de parada,
+// que fecham o banco. As dependências chegam a run por fx.Populate.
+func runWith(run func() error, options ...fx.Option) error {
+	app := fx.New(append(options, fx.NopLogger)...)
+	if err := app.Err(); err != nil {
+		return err
+	}
+
+	startCtx, cancel := context.WithTimeout(context.Background(), app.StartTimeout())
+	defer cancel()
+	if err := app.Start(startCtx); err != nil {
+		return err
+	}
+
+	runErr := run()
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), app.StopTimeout())
+	defer cancel()
+	if err := app.Stop(stopCtx); err != nil && runErr == nil {
+		return err
+	}
+	return runErr
+}
+
+func rotateKeys(args []string) error {
+	flags := flag.NewFlagSet("rotate-keys", flag.ExitOnError)
+	reencrypt := flags.Bool("reencrypt", false, "re-encrypt contents with new data keys instead of only re-wrapping them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var store storage.ObjectStore
+	return runWith(
+		func() error {
+			encrypted, ok := storage.As[*storage.EncryptedStore](store)
+			if !ok {
+				return fmt.Errorf("storage encryption is not configured")
+			}
+
+			result, err := encrypted.RotateAll(*reencrypt)
+			log.Printf("Key rotation: %d objects scanned, %d rotated, %d encrypted for the first time",
+				result.Scanned, result.Rotated, result.Encrypted)
+			return err
+		},
+		ConfigModule,
+		StorageModule,
+		fx.Populate(&store),
+	)
+}
+
+func fsck(args []string) error {
+	flags := flag.NewFlagSet("fsck", flag.ExitOnError)
+	repair := flags.Bool("repair", false, "delete orphans, reparent dangling items and restore missing content")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var uc *file.FileUseCase
+	return runWith(
+		func() error {
+			report, err := uc.CheckConsistency(*repair)
+			if err != nil {
+				return err
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				return err
+			}
+
+			log.Printf("Consistency check: %d missing objects, %d orphaned objects, %d orphaned folder markers, %d dangling parents",
+				len(report.MissingObjects), len(report.OrphanedObjects), len(report.OrphanedFolderMarkers), len(report.DanglingParents))
+			return nil
+		},
+		ConfigModule,
+		DatabaseModule,
+		StorageModule,
+		RepositoryModule,
+		UseCaseModule,
+		fx.Populate(&uc),
+	)
+}
+
+// compress comprime os objetos gravados antes da compressão ser ativada. Roda
+// uma vez, depois de ligar STORAGE_COMPRESSION; os objetos novos já são
+// gravados comprimidos.
+func compress(args []string) error {
+	flags := flag.NewFlagSet("compress", flag.ExitOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var uc *file.FileUseCase
+	var config *environment.Config
+	return runWith(
+		func() error {
+			if config.STORAGE.Compression == storage.EncodingNone {
+				return fmt.Errorf("storage compression is disabled")
+			}
+
+			compressed, err := uc.CompressStoredObjects()
+			if err != nil {
+				return err
+			}
+
+			log.Printf("Compressed %d previously stored objects", compressed)
+			return nil
+		},
+		ConfigModule,
+		DatabaseModule,
+		StorageModule,
+		RepositoryModule,
+		UseCaseModule,
+		fx.Populate(&uc, &config),
+	)
+}
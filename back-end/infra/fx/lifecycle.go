@@ -5,13 +5,17 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"go.uber.org/fx"
 
 	"myScalidraw/infra/config/environment"
 	"myScalidraw/infra/database"
 	"myScalidraw/internal/delivery/httpserver"
+	"myScalidraw/internal/domain/events"
 	"myScalidraw/internal/domain/models"
+	"myScalidraw/internal/domain/useCase/collab"
+	"myScalidraw/internal/domain/useCase/file"
 	"myScalidraw/pkg/projectError"
 )
 
@@ -48,11 +52,11 @@ func RegisterDatabaseHooks(
 ) {
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
-			log.Printf("Connected to PostgreSQL database at %s:%d/%s\n",
+			log.Printf("Connected to PostgreSQL database at %s\n",
 				config.DB.URL_DB)
 
 			log.Println("Running automatic migrations...")
-			if err := db.AutoMigrate(&models.FileMetadata{}); err != nil {
+			if err := db.AutoMigrate(&models.FileMetadata{}, &models.FileRevision{}, &models.Blob{}, &models.OutboxEntry{}, &models.StagedObject{}, &models.ChangeEntry{}); err != nil {
 				return fmt.Errorf("failed to execute migrations: %w", err)
 			}
 			log.Println("Migrations completed successfully")
@@ -65,3 +69,170 @@ func RegisterDatabaseHooks(
 		},
 	})
 }
+
+const blobGracePeriod = time.Hour
+
+// changeJournalRetention é por quanto tempo um cliente pode ficar offline e
+// ainda sincronizar só o que mudou; depois disso recebe a árvore inteira.
+const changeJournalRetention = 30 * 24 * time.Hour
+
+func RegisterMaintenanceHooks(
+	lc fx.Lifecycle,
+	fileUseCase *file.FileUseCase,
+	config *environment.Config,
+) {
+	retention := time.Duration(config.TRASH.RetentionDays) * 24 * time.Hour
+	stop := make(chan struct{})
+
+	run := func() {
+		if config.TRASH.RetentionDays > 0 {
+			purged, err := fileUseCase.PurgeExpiredTrash(retention)
+			if err != nil {
+				log.Printf("Error purging expired trash: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("Purged %d expired trash items", purged)
+			}
+		}
+
+		collected, err := fileUseCase.CollectGarbage(blobGracePeriod)
+		if err != nil {
+			log.Printf("Error collecting unreferenced blobs: %v", err)
+		}
+		if collected > 0 {
+			log.Printf("Collected %d unreferenced blobs", collected)
+		}
+
+		pruned, err := fileUseCase.PruneChangeJournal(changeJournalRetention)
+		if err != nil {
+			log.Printf("Error pruning change journal: %v", err)
+		}
+		if pruned > 0 {
+			log.Printf("Pruned %d change journal entries", pruned)
+		}
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			if config.TRASH.RetentionDays > 0 {
+				log.Printf("Purging trash items older than %d days\n", config.TRASH.RetentionDays)
+			} else {
+				log.Println("Trash purge disabled")
+			}
+
+			go func() {
+				ticker := time.NewTicker(time.Hour)
+				defer ticker.Stop()
+
+				run()
+				for {
+					select {
+					case <-ticker.C:
+						run()
+					case <-stop:
+						return
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			close(stop)
+			return nil
+		},
+	})
+}
+
+// outboxGracePeriod dá tempo para a própria requisição promover os objetos
+// antes que o worker de recuperação assuma a operação.
+const outboxGracePeriod = time.Minute
+
+func RegisterRecoveryHooks(
+	lc fx.Lifecycle,
+	fileUseCase *file.FileUseCase,
+) {
+	stop := make(chan struct{})
+
+	run := func() {
+		recovered, err := fileUseCase.RecoverPendingOperations(outboxGracePeriod)
+		if err != nil {
+			log.Printf("Error recovering pending storage operations: %v", err)
+		}
+		if recovered > 0 {
+			log.Printf("Recovered %d pending storage operations", recovered)
+		}
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				ticker := time.NewTicker(outboxGracePeriod)
+				defer ticker.Stop()
+
+				run()
+				for {
+					select {
+					case <-ticker.C:
+						run()
+					case <-stop:
+						return
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			close(stop)
+			return nil
+		},
+	})
+}
+
+// RegisterThumbnailHooks roda o worker que gera as miniaturas fora das
+// requisições de gravação.
+func RegisterThumbnailHooks(
+	lc fx.Lifecycle,
+	fileUseCase *file.FileUseCase,
+) {
+	stop := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go fileUseCase.RunThumbnailWorker(stop)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			close(stop)
+			return nil
+		},
+	})
+}
+
+// RegisterCollabHooks grava as salas abertas antes do banco ser fechado.
+func RegisterCollabHooks(
+	lc fx.Lifecycle,
+	hub *collab.Hub,
+) {
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			log.Println("Saving open collaboration rooms...")
+			hub.Close()
+			return nil
+		},
+	})
+}
+
+// RegisterEventHooks fecha os streams de eventos antes do servidor parar, que
+// senão esperaria essas conexões indefinidamente.
+func RegisterEventHooks(
+	lc fx.Lifecycle,
+	broker *events.Broker,
+) {
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			log.Println("Closing event streams...")
+			broker.Close()
+			return nil
+		},
+	})
+}
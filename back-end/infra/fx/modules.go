@@ -1,14 +1,20 @@
 package fx
 
 import (
+	"log"
+
 	"myScalidraw/infra/config/environment"
 	"myScalidraw/infra/database"
 	"myScalidraw/infra/storage"
+	"myScalidraw/internal/delivery/handlers/collabHandlers"
 	"myScalidraw/internal/delivery/handlers/fileHandlers"
 	"myScalidraw/internal/delivery/httpserver"
+	"myScalidraw/internal/domain/events"
 	"myScalidraw/internal/domain/repository"
 	"myScalidraw/internal/domain/repository/impl"
+	"myScalidraw/internal/domain/useCase/collab"
 	"myScalidraw/internal/domain/useCase/file"
+	"myScalidraw/pkg/render"
 
 	"go.uber.org/fx"
 )
@@ -41,7 +47,41 @@ var StorageModule = fx.Options(
 			)
 		},
 	),
-	fx.Provide(storage.NewMinIO),
+	fx.Provide(
+		func(config *environment.Config, minioConfig storage.MinIOConfig) (storage.ObjectStore, error) {
+			var backend storage.ObjectStore
+			if config.STORAGE.Driver == environment.StorageDriverLocal {
+				localDisk, err := storage.NewLocalDisk(config.STORAGE.LocalPath)
+				if err != nil {
+					return nil, err
+				}
+				backend = localDisk
+			} else {
+				minioClient, err := storage.NewMinIO(minioConfig)
+				if err != nil {
+					return nil, err
+				}
+				backend = minioClient
+			}
+
+			if config.ENCRYPTION.MasterKeys != "" {
+				masterKeys, err := storage.ParseMasterKeys(config.ENCRYPTION.MasterKeys)
+				if err != nil {
+					return nil, err
+				}
+
+				encrypted, err := storage.NewEncryptedStore(backend, masterKeys, config.ENCRYPTION.ActiveKeyID)
+				if err != nil {
+					return nil, err
+				}
+				backend = encrypted
+			} else {
+				log.Printf("Warning: STORAGE_ENCRYPTION_KEYS is not set; objects are stored unencrypted")
+			}
+
+			return storage.NewCompressedStore(backend, config.STORAGE.Compression)
+		},
+	),
 )
 
 var ServerModule = fx.Options(
@@ -62,25 +102,69 @@ var RepositoryModule = fx.Options(
 	),
 
 	fx.Provide(
-		func(minioClient *storage.MinIO, db *database.DB, metadataRepo repository.FileMetadataRepository) repository.FileRepository {
-			return impl.NewFileRepositoryMinio(minioClient, db, metadataRepo)
+		func(db *database.DB) repository.FileRevisionRepository {
+			return impl.NewFileRevisionRepository(db)
+		},
+	),
+
+	fx.Provide(
+		func(db *database.DB) repository.BlobRepository {
+			return impl.NewBlobRepository(db)
+		},
+	),
+
+	fx.Provide(
+		func(db *database.DB) repository.OutboxRepository {
+			return impl.NewOutboxRepository(db)
+		},
+	),
+
+	fx.Provide(
+		func(store storage.ObjectStore, db *database.DB, metadataRepo repository.FileMetadataRepository, revisionRepo repository.FileRevisionRepository, blobRepo repository.BlobRepository, outboxRepo repository.OutboxRepository) repository.FileRepository {
+			return impl.NewFileRepositoryMinio(store, db, metadataRepo, revisionRepo, blobRepo, outboxRepo)
 		},
 	),
 )
 
 var UseCaseModule = fx.Options(
+	fx.Provide(events.NewBroker),
 	fx.Provide(
-		func(fileRepo repository.FileRepository, metadataRepo repository.FileMetadataRepository) *file.FileUseCase {
-			return file.NewFileUseCase(fileRepo, metadataRepo)
+		func(fileRepo repository.FileRepository, metadataRepo repository.FileMetadataRepository, broker *events.Broker) *file.FileUseCase {
+			return file.NewFileUseCase(fileRepo, metadataRepo, broker)
 		},
 	),
+	fx.Provide(collab.NewHub),
+)
+
+// BackgroundModule liga as tarefas de fundo do servidor. Os comandos de linha
+// de comando não o incluem.
+var BackgroundModule = fx.Options(
+	fx.Invoke(RegisterMaintenanceHooks),
+	fx.Invoke(RegisterCollabHooks),
+	fx.Invoke(RegisterEventHooks),
+	fx.Invoke(RegisterRecoveryHooks),
+	fx.Invoke(RegisterThumbnailHooks),
 )
 
 var HandlersModule = fx.Options(
-	fx.Provide(fileHandlers.NewFileHandler),
+	fx.Provide(
+		func(config *environment.Config) (*render.FontCatalog, error) {
+			fonts, err := render.LoadFontCatalog(config.EXPORT.FontDir)
+			if err != nil {
+				return nil, err
+			}
+			if fonts.Families() == 0 {
+				log.Printf("No fonts found in %s; exported SVGs will use fallback fonts", config.EXPORT.FontDir)
+			}
+			return fonts, nil
+		},
+		fileHandlers.NewFileHandler,
+	),
+	fx.Provide(collabHandlers.NewCollabHandler),
 	fx.Invoke(
-		func(server *httpserver.Server, fileHandler *fileHandlers.FileHandler) {
+		func(server *httpserver.Server, fileHandler *fileHandlers.FileHandler, collabHandler *collabHandlers.CollabHandler) {
 			fileHandler.RegisterRoutes(server.App)
+			collabHandler.RegisterRoutes(server.App)
 		},
 	),
 )
@@ -92,5 +176,6 @@ var AllModules = fx.Options(
 	ServerModule,
 	RepositoryModule,
 	UseCaseModule,
+	BackgroundModule,
 	HandlersModule,
 )
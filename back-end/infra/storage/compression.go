@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	MetaEncoding = "encoding"
+
+	EncodingNone = "none"
+	EncodingZstd = "zstd"
+	EncodingGzip = "gzip"
+)
+
+// CompressedStore comprime o conteúdo JSON antes de repassá-lo ao store
+// interno e descomprime na leitura, de acordo com o encoding gravado nos
+// metadados do objeto. Objetos sem encoding são devolvidos como estão.
+type CompressedStore struct {
+	inner    ObjectStore
+	encoding string
+	encoder  *zstd.Encoder
+	decoder  *zstd.Decoder
+}
+
+type Compressor interface {
+	CompressInPlace(key string) (ObjectInfo, bool, error)
+}
+
+func NewCompressedStore(inner ObjectStore, encoding string) (*CompressedStore, error) {
+	if encoding != EncodingNone && encoding != EncodingZstd && encoding != EncodingGzip {
+		return nil, fmt.Errorf("unsupported storage compression '%s'", encoding)
+	}
+
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+
+	return &CompressedStore{
+		inner:    inner,
+		encoding: encoding,
+		encoder:  encoder,
+		decoder:  decoder,
+	}, nil
+}
+
+func (c *CompressedStore) Unwrap() ObjectStore {
+	return c.inner
+}
+
+func isCompressible(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/json") ||
+		strings.HasPrefix(contentType, "application/vnd.excalidraw+json")
+}
+
+func (c *CompressedStore) compress(content []byte) ([]byte, error) {
+	switch c.encoding {
+	case EncodingZstd:
+		return c.encoder.EncodeAll(content, make([]byte, 0, len(content)/4)), nil
+	case EncodingGzip:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(content); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return content, nil
+	}
+}
+
+func (c *CompressedStore) decompress(content []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", EncodingNone:
+		return content, nil
+	case EncodingZstd:
+		return c.decoder.DecodeAll(content, nil)
+	case EncodingGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding '%s'", encoding)
+	}
+}
+
+func (c *CompressedStore) Put(key string, content []byte, opts PutOptions) (ObjectInfo, error) {
+	if c.encoding == EncodingNone || len(content) == 0 || !isCompressible(opts.ContentType) {
+		return c.inner.Put(key, content, opts)
+	}
+
+	compressed, err := c.compress(content)
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to compress object '%s': %w", key, err)
+	}
+
+	metadata := make(map[string]string, len(opts.Metadata)+1)
+	for k, v := range opts.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetaEncoding] = c.encoding
+
+	return c.inner.Put(key, compressed, PutOptions{ContentType: opts.ContentType, Metadata: metadata})
+}
+
+func (c *CompressedStore) Get(key string) ([]byte, ObjectInfo, error) {
+	content, info, err := c.inner.Get(key)
+	if err != nil {
+		return nil, info, err
+	}
+
+	decompressed, err := c.decompress(content, info.Metadata[MetaEncoding])
+	if err != nil {
+		return nil, info, fmt.Errorf("failed to decompress object '%s': %w", key, err)
+	}
+
+	return decompressed, info, nil
+}
+
+func (c *CompressedStore) Delete(key string) error {
+	return c.inner.Delete(key)
+}
+
+func (c *CompressedStore) List(prefix string) ([]ObjectInfo, error) {
+	return c.inner.List(prefix)
+}
+
+func (c *CompressedStore) Copy(srcKey, dstKey string) error {
+	return c.inner.Copy(srcKey, dstKey)
+}
+
+func (c *CompressedStore) Stat(key string) (ObjectInfo, error) {
+	return c.inner.Stat(key)
+}
+
+// CompressInPlace regrava um objeto antigo, ainda sem encoding, já comprimido.
+// Retorna false quando não havia nada a fazer.
+func (c *CompressedStore) CompressInPlace(key string) (ObjectInfo, bool, error) {
+	if c.encoding == EncodingNone {
+		return ObjectInfo{}, false, nil
+	}
+
+	info, err := c.inner.Stat(key)
+	if err != nil {
+		return ObjectInfo{}, false, err
+	}
+
+	if info.Size == 0 || info.Metadata[MetaEncoding] != "" || !isCompressible(info.ContentType) {
+		return info, false, nil
+	}
+
+	content, info, err := c.inner.Get(key)
+	if err != nil {
+		return ObjectInfo{}, false, err
+	}
+
+	compressed, err := c.compress(content)
+	if err != nil {
+		return ObjectInfo{}, false, fmt.Errorf("failed to compress object '%s': %w", key, err)
+	}
+
+	metadata := make(map[string]string, len(info.Metadata)+1)
+	for k, v := range info.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetaEncoding] = c.encoding
+
+	newInfo, err := c.inner.Put(key, compressed, PutOptions{ContentType: info.ContentType, Metadata: metadata})
+	if err != nil {
+		return ObjectInfo{}, false, err
+	}
+
+	log.Printf("Compressed object %s (%d -> %d bytes)", key, len(content), len(compressed))
+	return newInfo, true, nil
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func newCompressedStore(t *testing.T, inner ObjectStore, encoding string) *CompressedStore {
+	t.Helper()
+	store, err := NewCompressedStore(inner, encoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// largeScene repete elementos para que a compressão faça diferença.
+var largeScene = `{"type":"excalidraw","elements":[` +
+	strings.Repeat(`{"id":"a","type":"rectangle","x":0,"y":0,"strokeColor":"#1e1e1e"},`, 200) +
+	`{"id":"b","type":"ellipse"}]}`
+
+func TestCompressedStoreRoundTrip(t *testing.T) {
+	for _, encoding := range []string{EncodingZstd, EncodingGzip, EncodingNone} {
+		t.Run(encoding, func(t *testing.T) {
+			disk := newLocalDisk(t)
+			store := newCompressedStore(t, disk, encoding)
+
+			info, err := store.Put("scene.json", []byte(largeScene), PutOptions{ContentType: "application/json"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			raw, rawInfo, err := disk.Get("scene.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encoding == EncodingNone {
+				if info.Metadata[MetaEncoding] != "" || string(raw) != largeScene {
+					t.Error("store without compression changed the object")
+				}
+			} else if rawInfo.Metadata[MetaEncoding] != encoding || len(raw) >= len(largeScene) {
+				t.Errorf("stored %d bytes with encoding %q, want fewer than %d with %q", len(raw), rawInfo.Metadata[MetaEncoding], len(largeScene), encoding)
+			}
+
+			content, _, err := store.Get("scene.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != largeScene {
+				t.Error("content changed after the round trip")
+			}
+		})
+	}
+}
+
+func TestCompressedStoreSkipsOtherContent(t *testing.T) {
+	disk := newLocalDisk(t)
+	store := newCompressedStore(t, disk, EncodingZstd)
+	png := bytes.Repeat([]byte{0x89, 'P', 'N', 'G'}, 100)
+
+	info, err := store.Put("thumb.png", png, PutOptions{ContentType: "image/png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Metadata[MetaEncoding] != "" {
+		t.Errorf("image compressed with %q", info.Metadata[MetaEncoding])
+	}
+
+	if _, err := NewCompressedStore(disk, "brotli"); err == nil {
+		t.Error("unsupported encoding accepted")
+	}
+}
+
+func TestCompressedStoreRejectsUnknownEncoding(t *testing.T) {
+	disk := newLocalDisk(t)
+	store := newCompressedStore(t, disk, EncodingZstd)
+	if _, err := disk.Put("scene.json", []byte(largeScene), PutOptions{ContentType: "application/json", Metadata: map[string]string{MetaEncoding: "lz4"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := store.Get("scene.json"); err == nil {
+		t.Error("object with an unknown encoding was read")
+	}
+}
+
+func TestCompressInPlace(t *testing.T) {
+	disk := newLocalDisk(t)
+	if _, err := disk.Put("legacy.json", []byte(largeScene), PutOptions{ContentType: "application/json", Metadata: map[string]string{"file-id": "f1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := disk.Put("thumb.png", []byte("png"), PutOptions{ContentType: "image/png"}); err != nil {
+		t.Fatal(err)
+	}
+	store := newCompressedStore(t, disk, EncodingZstd)
+
+	info, changed, err := store.CompressInPlace("legacy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || info.Metadata[MetaEncoding] != EncodingZstd || info.Metadata["file-id"] != "f1" {
+		t.Errorf("changed = %v, metadata = %v", changed, info.Metadata)
+	}
+	compressed, _, err := disk.Get("legacy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rodar de novo não comprime duas vezes.
+	info, changed, err = store.CompressInPlace("legacy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || info.Metadata[MetaEncoding] != EncodingZstd {
+		t.Errorf("second run changed = %v, encoding = %q", changed, info.Metadata[MetaEncoding])
+	}
+	again, _, err := disk.Get("legacy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, compressed) {
+		t.Error("second run rewrote the object")
+	}
+
+	content, _, err := store.Get("legacy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != largeScene {
+		t.Error("content changed after compressing in place")
+	}
+
+	if _, changed, err := store.CompressInPlace("thumb.png"); err != nil || changed {
+		t.Errorf("image: changed = %v, err = %v", changed, err)
+	}
+	if _, changed, err := newCompressedStore(t, disk, EncodingNone).CompressInPlace("legacy.json"); err != nil || changed {
+		t.Errorf("store without compression: changed = %v, err = %v", changed, err)
+	}
+	if _, _, err := store.CompressInPlace("missing.json"); err == nil {
+		t.Error("missing object compressed")
+	}
+}
+
+// O servidor empilha compressão sobre criptografia; os dois precisam se
+// desfazer na ordem certa, inclusive para objetos antigos sem nenhum dos dois.
+func TestCompressedEncryptedStore(t *testing.T) {
+	disk := newLocalDisk(t)
+	if _, err := disk.Put("legacy.json", []byte(largeScene), PutOptions{ContentType: "application/json"}); err != nil {
+		t.Fatal(err)
+	}
+	encrypted := newEncryptedStore(t, disk, map[string][]byte{"k1": newMasterKey(t)}, "k1")
+	store := newCompressedStore(t, encrypted, EncodingZstd)
+
+	if _, err := store.Put("scene.json", []byte(largeScene), PutOptions{ContentType: "application/json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	compressor, ok := As[Compressor](store)
+	if !ok {
+		t.Fatal("compressor not found in the stack")
+	}
+	if _, changed, err := compressor.CompressInPlace("legacy.json"); err != nil || !changed {
+		t.Fatalf("changed = %v, err = %v", changed, err)
+	}
+	if _, ok := As[*EncryptedStore](store); !ok {
+		t.Error("encrypted store not found in the stack")
+	}
+
+	for _, key := range []string{"scene.json", "legacy.json"} {
+		raw, info, err := disk.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isEncrypted(info) || info.Metadata[MetaEncoding] != EncodingZstd {
+			t.Errorf("%s metadata = %v, want encrypted and compressed", key, info.Metadata)
+		}
+		if bytes.Contains(raw, []byte("rectangle")) {
+			t.Errorf("%s stored in plain text", key)
+		}
+
+		content, _, err := store.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != largeScene {
+			t.Errorf("%s changed after the round trip", key)
+		}
+	}
+}
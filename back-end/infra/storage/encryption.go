@@ -0,0 +1,300 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strings"
+)
+
+const (
+	MetaKeyID      = "key-id"
+	MetaWrappedKey = "wrapped-key"
+	MetaCipher     = "cipher"
+
+	cipherAES256GCM = "aes-256-gcm"
+)
+
+// EncryptedStore cifra cada objeto com uma chave de dados própria (AES-256-GCM).
+// A chave de dados é cifrada com a chave mestra ativa e guardada, junto com o
+// ID dessa chave mestra, nos metadados do objeto.
+type EncryptedStore struct {
+	inner       ObjectStore
+	masterKeys  map[string][]byte
+	activeKeyID string
+}
+
+type RotationResult struct {
+	Scanned   int
+	Rotated   int
+	Encrypted int
+}
+
+// ParseMasterKeys lê chaves no formato "id1:base64,id2:base64".
+func ParseMasterKeys(spec string) (map[string][]byte, error) {
+	keys := map[string][]byte{}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, encoded, found := strings.Cut(entry, ":")
+		if !found || id == "" {
+			return nil, fmt.Errorf("invalid master key entry '%s': expected id:base64", entry)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 for master key '%s': %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("master key '%s' must be 32 bytes, got %d", id, len(key))
+		}
+
+		keys[id] = key
+	}
+
+	return keys, nil
+}
+
+func NewEncryptedStore(inner ObjectStore, masterKeys map[string][]byte, activeKeyID string) (*EncryptedStore, error) {
+	if _, ok := masterKeys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("active master key '%s' is not configured", activeKeyID)
+	}
+
+	log.Printf("Encrypting stored objects with master key: %s", activeKeyID)
+	return &EncryptedStore{
+		inner:       inner,
+		masterKeys:  masterKeys,
+		activeKeyID: activeKeyID,
+	}, nil
+}
+
+func (e *EncryptedStore) Unwrap() ObjectStore {
+	return e.inner
+}
+
+func sealGCM(key []byte, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func openGCM(key []byte, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func isEncrypted(info ObjectInfo) bool {
+	return info.Metadata[MetaKeyID] != ""
+}
+
+func (e *EncryptedStore) wrapKey(dataKey []byte) (string, error) {
+	wrapped, err := sealGCM(e.masterKeys[e.activeKeyID], dataKey)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(wrapped), nil
+}
+
+func (e *EncryptedStore) unwrapKey(info ObjectInfo) ([]byte, error) {
+	keyID := info.Metadata[MetaKeyID]
+	masterKey, ok := e.masterKeys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("master key '%s' is not configured", keyID)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(info.Metadata[MetaWrappedKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid wrapped key: %w", err)
+	}
+
+	return openGCM(masterKey, wrapped)
+}
+
+func (e *EncryptedStore) Put(key string, content []byte, opts PutOptions) (ObjectInfo, error) {
+	if len(content) == 0 || opts.ContentType == FolderContentType {
+		return e.inner.Put(key, content, opts)
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	ciphertext, err := sealGCM(dataKey, content)
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to encrypt object '%s': %w", key, err)
+	}
+
+	wrappedKey, err := e.wrapKey(dataKey)
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to wrap data key for object '%s': %w", key, err)
+	}
+
+	metadata := make(map[string]string, len(opts.Metadata)+3)
+	for k, v := range opts.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetaKeyID] = e.activeKeyID
+	metadata[MetaWrappedKey] = wrappedKey
+	metadata[MetaCipher] = cipherAES256GCM
+
+	return e.inner.Put(key, ciphertext, PutOptions{ContentType: opts.ContentType, Metadata: metadata})
+}
+
+func (e *EncryptedStore) Get(key string) ([]byte, ObjectInfo, error) {
+	content, info, err := e.inner.Get(key)
+	if err != nil || !isEncrypted(info) {
+		return content, info, err
+	}
+
+	dataKey, err := e.unwrapKey(info)
+	if err != nil {
+		return nil, info, fmt.Errorf("failed to unwrap data key for object '%s': %w", key, err)
+	}
+
+	plaintext, err := openGCM(dataKey, content)
+	if err != nil {
+		return nil, info, fmt.Errorf("failed to decrypt object '%s': %w", key, err)
+	}
+
+	return plaintext, info, nil
+}
+
+func (e *EncryptedStore) Delete(key string) error {
+	return e.inner.Delete(key)
+}
+
+func (e *EncryptedStore) List(prefix string) ([]ObjectInfo, error) {
+	return e.inner.List(prefix)
+}
+
+func (e *EncryptedStore) Copy(srcKey, dstKey string) error {
+	return e.inner.Copy(srcKey, dstKey)
+}
+
+func (e *EncryptedStore) Stat(key string) (ObjectInfo, error) {
+	return e.inner.Stat(key)
+}
+
+// RotateKey passa um objeto para a chave mestra ativa. Por padrão só a chave de
+// dados é recifrada; com reencrypt o conteúdo ganha uma chave de dados nova.
+// Objetos ainda em texto claro são cifrados.
+func (e *EncryptedStore) RotateKey(key string, reencrypt bool) (bool, bool, error) {
+	info, err := e.inner.Stat(key)
+	if err != nil {
+		return false, false, err
+	}
+
+	if info.Size == 0 || info.ContentType == FolderContentType {
+		return false, false, nil
+	}
+
+	if !isEncrypted(info) || reencrypt {
+		content, info, err := e.Get(key)
+		if err != nil {
+			return false, false, err
+		}
+
+		metadata := make(map[string]string, len(info.Metadata))
+		for k, v := range info.Metadata {
+			metadata[k] = v
+		}
+
+		if _, err := e.Put(key, content, PutOptions{ContentType: info.ContentType, Metadata: metadata}); err != nil {
+			return false, false, err
+		}
+		return isEncrypted(info), !isEncrypted(info), nil
+	}
+
+	if info.Metadata[MetaKeyID] == e.activeKeyID {
+		return false, false, nil
+	}
+
+	ciphertext, info, err := e.inner.Get(key)
+	if err != nil {
+		return false, false, err
+	}
+
+	dataKey, err := e.unwrapKey(info)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to unwrap data key for object '%s': %w", key, err)
+	}
+
+	wrappedKey, err := e.wrapKey(dataKey)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to wrap data key for object '%s': %w", key, err)
+	}
+
+	metadata := make(map[string]string, len(info.Metadata))
+	for k, v := range info.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetaKeyID] = e.activeKeyID
+	metadata[MetaWrappedKey] = wrappedKey
+
+	if _, err := e.inner.Put(key, ciphertext, PutOptions{ContentType: info.ContentType, Metadata: metadata}); err != nil {
+		return false, false, err
+	}
+
+	return true, false, nil
+}
+
+func (e *EncryptedStore) RotateAll(reencrypt bool) (RotationResult, error) {
+	var result RotationResult
+
+	objects, err := e.inner.List("")
+	if err != nil {
+		return result, err
+	}
+
+	for _, object := range objects {
+		result.Scanned++
+
+		rotated, encrypted, err := e.RotateKey(object.Key, reencrypt)
+		if err != nil {
+			return result, fmt.Errorf("error rotating key of object '%s': %w", object.Key, err)
+		}
+		if rotated {
+			result.Rotated++
+		}
+		if encrypted {
+			result.Encrypted++
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+const sceneJSON = `{"type":"excalidraw","version":2,"elements":[{"id":"a","type":"rectangle"}]}`
+
+func newMasterKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newLocalDisk(t *testing.T) *LocalDisk {
+	t.Helper()
+	disk, err := NewLocalDisk(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return disk
+}
+
+func newEncryptedStore(t *testing.T, inner ObjectStore, keys map[string][]byte, active string) *EncryptedStore {
+	t.Helper()
+	store, err := NewEncryptedStore(inner, keys, active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestEncryptedStoreRoundTrip(t *testing.T) {
+	disk := newLocalDisk(t)
+	store := newEncryptedStore(t, disk, map[string][]byte{"k1": newMasterKey(t)}, "k1")
+
+	info, err := store.Put("file.json", []byte(sceneJSON), PutOptions{ContentType: "application/json", Metadata: map[string]string{"file-id": "f1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Metadata[MetaKeyID] != "k1" || info.Metadata[MetaCipher] != cipherAES256GCM || info.Metadata["file-id"] != "f1" {
+		t.Errorf("metadata = %v", info.Metadata)
+	}
+
+	raw, _, err := disk.Get("file.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("excalidraw")) {
+		t.Error("object stored in plain text")
+	}
+
+	content, _, err := store.Get("file.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != sceneJSON {
+		t.Errorf("content = %q, want %q", content, sceneJSON)
+	}
+}
+
+func TestEncryptedStoreSkipsFoldersAndEmptyObjects(t *testing.T) {
+	disk := newLocalDisk(t)
+	store := newEncryptedStore(t, disk, map[string][]byte{"k1": newMasterKey(t)}, "k1")
+
+	folder, err := store.Put("folder/", []byte("{}"), PutOptions{ContentType: FolderContentType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty, err := store.Put("empty.json", nil, PutOptions{ContentType: "application/json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if isEncrypted(folder) || isEncrypted(empty) {
+		t.Error("folder or empty object was encrypted")
+	}
+}
+
+func TestEncryptedStoreReadsPlainObjects(t *testing.T) {
+	disk := newLocalDisk(t)
+	if _, err := disk.Put("legacy.json", []byte(sceneJSON), PutOptions{ContentType: "application/json"}); err != nil {
+		t.Fatal(err)
+	}
+	store := newEncryptedStore(t, disk, map[string][]byte{"k1": newMasterKey(t)}, "k1")
+
+	content, _, err := store.Get("legacy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != sceneJSON {
+		t.Errorf("content = %q, want %q", content, sceneJSON)
+	}
+}
+
+func TestEncryptedStoreDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(content []byte, metadata, other map[string]string) []byte
+	}{
+		{"flipped ciphertext bit", func(content []byte, metadata, other map[string]string) []byte {
+			content[len(content)-1] ^= 0x01
+			return content
+		}},
+		{"truncated ciphertext", func(content []byte, metadata, other map[string]string) []byte {
+			return content[:len(content)-4]
+		}},
+		{"too short for a nonce", func(content []byte, metadata, other map[string]string) []byte {
+			return content[:4]
+		}},
+		{"flipped wrapped key bit", func(content []byte, metadata, other map[string]string) []byte {
+			wrapped, _ := base64.StdEncoding.DecodeString(metadata[MetaWrappedKey])
+			wrapped[len(wrapped)/2] ^= 0x01
+			metadata[MetaWrappedKey] = base64.StdEncoding.EncodeToString(wrapped)
+			return content
+		}},
+		{"wrapped key of another object", func(content []byte, metadata, other map[string]string) []byte {
+			metadata[MetaWrappedKey] = other[MetaWrappedKey]
+			return content
+		}},
+		{"unknown master key", func(content []byte, metadata, other map[string]string) []byte {
+			metadata[MetaKeyID] = "k9"
+			return content
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			disk := newLocalDisk(t)
+			store := newEncryptedStore(t, disk, map[string][]byte{"k1": newMasterKey(t)}, "k1")
+
+			if _, err := store.Put("file.json", []byte(sceneJSON), PutOptions{ContentType: "application/json"}); err != nil {
+				t.Fatal(err)
+			}
+			other, err := store.Put("other.json", []byte(sceneJSON), PutOptions{ContentType: "application/json"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			content, info, err := disk.Get("file.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			content = test.tamper(content, info.Metadata, other.Metadata)
+			if _, err := disk.Put("file.json", content, PutOptions{ContentType: info.ContentType, Metadata: info.Metadata}); err != nil {
+				t.Fatal(err)
+			}
+
+			plaintext, _, err := store.Get("file.json")
+			if err == nil {
+				t.Fatalf("tampered object decrypted to %q", plaintext)
+			}
+			if plaintext != nil {
+				t.Errorf("tampered object returned content %q", plaintext)
+			}
+		})
+	}
+}
+
+func TestRotateAllRewrapsDataKeys(t *testing.T) {
+	disk := newLocalDisk(t)
+	oldKey, newKey := newMasterKey(t), newMasterKey(t)
+
+	before := newEncryptedStore(t, disk, map[string][]byte{"k1": oldKey}, "k1")
+	if _, err := before.Put("a.json", []byte(sceneJSON), PutOptions{ContentType: "application/json"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := disk.Put("plain.json", []byte(sceneJSON), PutOptions{ContentType: "application/json"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := disk.Put("folder/", []byte("{}"), PutOptions{ContentType: FolderContentType}); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, _, err := disk.Get("a.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := newEncryptedStore(t, disk, map[string][]byte{"k1": oldKey, "k2": newKey}, "k2")
+	result, err := store.RotateAll(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Scanned != 3 || result.Rotated != 1 || result.Encrypted != 1 {
+		t.Errorf("result = %+v, want 3 scanned, 1 rotated, 1 encrypted", result)
+	}
+
+	rotated, info, err := disk.Get("a.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Metadata[MetaKeyID] != "k2" {
+		t.Errorf("key id = %q, want k2", info.Metadata[MetaKeyID])
+	}
+	if !bytes.Equal(rotated, ciphertext) {
+		t.Error("re-wrap changed the ciphertext")
+	}
+
+	// Só com a chave nova os objetos continuam legíveis.
+	after := newEncryptedStore(t, disk, map[string][]byte{"k2": newKey}, "k2")
+	for _, key := range []string{"a.json", "plain.json"} {
+		content, _, err := after.Get(key)
+		if err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		if string(content) != sceneJSON {
+			t.Errorf("%s = %q, want %q", key, content, sceneJSON)
+		}
+	}
+	if _, _, err := before.Get("a.json"); err == nil || !strings.Contains(err.Error(), "k2") {
+		t.Errorf("old key read a rotated object: %v", err)
+	}
+
+	again, err := store.RotateAll(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Rotated != 0 || again.Encrypted != 0 {
+		t.Errorf("second rotation = %+v, want nothing to do", again)
+	}
+}
+
+func TestRotateKeyReencrypt(t *testing.T) {
+	disk := newLocalDisk(t)
+	store := newEncryptedStore(t, disk, map[string][]byte{"k1": newMasterKey(t)}, "k1")
+	if _, err := store.Put("a.json", []byte(sceneJSON), PutOptions{ContentType: "application/json"}); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, before, err := disk.Get("a.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, encrypted, err := store.RotateKey("a.json", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rotated || encrypted {
+		t.Errorf("rotated, encrypted = %v, %v, want true, false", rotated, encrypted)
+	}
+
+	reencrypted, after, err := disk.Get("a.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(reencrypted, ciphertext) || after.Metadata[MetaWrappedKey] == before.Metadata[MetaWrappedKey] {
+		t.Error("reencrypt kept the data key")
+	}
+
+	content, _, err := store.Get("a.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != sceneJSON {
+		t.Errorf("content = %q, want %q", content, sceneJSON)
+	}
+}
+
+func TestParseMasterKeys(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+
+	keys, err := ParseMasterKeys(" k1:" + key + ", k2:" + key + ",")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || len(keys["k1"]) != 32 || len(keys["k2"]) != 32 {
+		t.Errorf("keys = %v", keys)
+	}
+
+	invalid := []string{
+		key,
+		":" + key,
+		"k1:not-base64!",
+		"k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+	}
+	for _, spec := range invalid {
+		if _, err := ParseMasterKeys(spec); err == nil {
+			t.Errorf("spec %q accepted", spec)
+		}
+	}
+
+	if _, err := NewEncryptedStore(newLocalDisk(t), keys, "k3"); err == nil {
+		t.Error("store accepted an active key that is not configured")
+	}
+}
@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	localMetaDir    = ".meta"
+	localFolderFile = ".folder"
+)
+
+type localMeta struct {
+	ContentType string            `json:"contentType"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// LocalDisk guarda os objetos como arquivos comuns dentro de Root. Os metadados
+// de cada objeto ficam em Root/.meta e as pastas vazias são marcadas com um
+// arquivo .folder, reproduzindo os marcadores de pasta do MinIO.
+type LocalDisk struct {
+	Root string
+}
+
+func NewLocalDisk(root string) (*LocalDisk, error) {
+	if root == "" {
+		return nil, fmt.Errorf("local storage path cannot be empty")
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve local storage path '%s': %w", root, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(absRoot, localMetaDir), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create local storage at '%s': %w", absRoot, err)
+	}
+
+	log.Printf("Using local disk storage at %s", absRoot)
+	return &LocalDisk{Root: absRoot}, nil
+}
+
+func (d *LocalDisk) objectPath(key string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("object key cannot be empty")
+	}
+
+	name := key
+	if strings.HasSuffix(key, "/") {
+		name = key + localFolderFile
+	}
+
+	cleaned := path.Clean("/" + name)
+	if cleaned == "/" || strings.HasPrefix(cleaned, "/"+localMetaDir+"/") {
+		return "", fmt.Errorf("invalid object key '%s'", key)
+	}
+
+	return filepath.Join(d.Root, filepath.FromSlash(cleaned)), nil
+}
+
+func (d *LocalDisk) metaPath(objectPath string) string {
+	rel, _ := filepath.Rel(d.Root, objectPath)
+	return filepath.Join(d.Root, localMetaDir, rel+".json")
+}
+
+func (d *LocalDisk) Put(key string, content []byte, opts PutOptions) (ObjectInfo, error) {
+	objectPath, err := d.objectPath(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	meta := localMeta{
+		ContentType: contentType,
+		Metadata:    normalizeMetadata(opts.Metadata),
+	}
+
+	if err := writeFileAtomic(objectPath, content); err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to write object '%s' to disk: %w", key, err)
+	}
+
+	if err := d.writeMeta(objectPath, meta); err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to write metadata for object '%s': %w", key, err)
+	}
+
+	log.Printf("Successfully stored object: %s (size: %d bytes)", key, len(content))
+	return d.Stat(key)
+}
+
+func (d *LocalDisk) Get(key string) ([]byte, ObjectInfo, error) {
+	objectPath, err := d.objectPath(key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+
+	content, err := os.ReadFile(objectPath)
+	if err != nil {
+		return nil, ObjectInfo{}, wrapLocalError(key, err)
+	}
+
+	info, err := d.Stat(key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+
+	return content, info, nil
+}
+
+func (d *LocalDisk) Delete(key string) error {
+	objectPath, err := d.objectPath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(objectPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete object '%s' from disk: %w", key, err)
+	}
+
+	if err := os.Remove(d.metaPath(objectPath)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete metadata for object '%s': %w", key, err)
+	}
+
+	log.Printf("Successfully deleted object: %s", key)
+	return nil
+}
+
+func (d *LocalDisk) List(prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+
+	err := filepath.WalkDir(d.Root, func(objectPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(d.Root, objectPath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if rel == localMetaDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if strings.HasPrefix(entry.Name(), ".tmp-") {
+			return nil
+		}
+
+		key := rel
+		if path.Base(rel) == localFolderFile {
+			key = strings.TrimSuffix(rel, localFolderFile)
+		}
+
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := d.Stat(key)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, info)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in '%s': %w", d.Root, err)
+	}
+
+	log.Printf("Successfully listed %d objects from %s", len(objects), d.Root)
+	return objects, nil
+}
+
+func (d *LocalDisk) Copy(srcKey, dstKey string) error {
+	srcPath, err := d.objectPath(srcKey)
+	if err != nil {
+		return err
+	}
+
+	dstPath, err := d.objectPath(dstKey)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(srcPath)
+	if err != nil {
+		return wrapLocalError(srcKey, err)
+	}
+
+	meta, err := d.readMeta(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata for object '%s': %w", srcKey, err)
+	}
+
+	if err := writeFileAtomic(dstPath, content); err != nil {
+		return fmt.Errorf("failed to copy object '%s' to '%s': %w", srcKey, dstKey, err)
+	}
+
+	if err := d.writeMeta(dstPath, meta); err != nil {
+		return fmt.Errorf("failed to write metadata for object '%s': %w", dstKey, err)
+	}
+
+	log.Printf("Successfully copied object from %s to %s", srcKey, dstKey)
+	return nil
+}
+
+func (d *LocalDisk) Stat(key string) (ObjectInfo, error) {
+	objectPath, err := d.objectPath(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	stat, err := os.Stat(objectPath)
+	if err != nil {
+		return ObjectInfo{}, wrapLocalError(key, err)
+	}
+
+	meta, err := d.readMeta(objectPath)
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to read metadata for object '%s': %w", key, err)
+	}
+
+	return ObjectInfo{
+		Key:          key,
+		Size:         stat.Size(),
+		ContentType:  meta.ContentType,
+		LastModified: stat.ModTime(),
+		Metadata:     meta.Metadata,
+	}, nil
+}
+
+func (d *LocalDisk) readMeta(objectPath string) (localMeta, error) {
+	var meta localMeta
+
+	content, err := os.ReadFile(d.metaPath(objectPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return meta, nil
+	}
+	if err != nil {
+		return meta, err
+	}
+
+	err = json.Unmarshal(content, &meta)
+	return meta, err
+}
+
+func (d *LocalDisk) writeMeta(objectPath string, meta localMeta) error {
+	content, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(d.metaPath(objectPath), content)
+}
+
+func writeFileAtomic(filePath string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filePath)
+}
+
+func wrapLocalError(key string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("object '%s': %w", key, ErrObjectNotFound)
+	}
+	return fmt.Errorf("failed to access object '%s' on disk: %w", key, err)
+}
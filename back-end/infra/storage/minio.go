@@ -68,24 +68,9 @@ func NewMinIO(config MinIOConfig) (*MinIO, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create bucket '%s' - check access key permissions: %w", config.Bucket, err)
 		}
-
-		policy := fmt.Sprintf(`{
-			"Version": "2012-10-17",
-			"Statement": [
-				{
-					"Effect": "Allow",
-					"Principal": "*",
-					"Action": ["s3:GetObject"],
-					"Resource": ["arn:aws:s3:::%s/*"]
-				}
-			]
-		}`, config.Bucket)
-
-		err = client.SetBucketPolicy(ctx, config.Bucket, policy)
-		if err != nil {
-			return nil, fmt.Errorf("failed to set bucket policy - check access key permissions: %w", err)
-		}
-		log.Printf("Bucket '%s' created successfully with public read policy", config.Bucket)
+		// O bucket fica privado: todo acesso passa pela API, que é quem decifra
+		// e descomprime os objetos.
+		log.Printf("Bucket '%s' created successfully", config.Bucket)
 	} else {
 		log.Printf("Using existing bucket: %s", config.Bucket)
 	}
@@ -97,74 +82,84 @@ func NewMinIO(config MinIOConfig) (*MinIO, error) {
 	}, nil
 }
 
-func (m *MinIO) UploadFile(fileID string, content []byte) (string, error) {
-	if fileID == "" {
-		return "", fmt.Errorf("file ID cannot be empty")
+func (m *MinIO) Put(key string, content []byte, opts PutOptions) (ObjectInfo, error) {
+	if key == "" {
+		return ObjectInfo{}, fmt.Errorf("object key cannot be empty")
 	}
-	if len(content) == 0 {
-		return "", fmt.Errorf("file content cannot be empty")
+
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
 	}
 
-	contentType := "application/json"
-	objectName := fileID + ".json"
+	ctx := context.Background()
 	reader := bytes.NewReader(content)
 
-	ctx := context.Background()
-	_, err := m.Client.PutObject(ctx, m.Bucket, objectName, reader, int64(len(content)),
-		minio.PutObjectOptions{ContentType: contentType})
+	info, err := m.Client.PutObject(ctx, m.Bucket, key, reader, int64(len(content)),
+		minio.PutObjectOptions{ContentType: contentType, UserMetadata: opts.Metadata})
 	if err != nil {
-		return "", fmt.Errorf("failed to upload file '%s' to MinIO: %w", fileID, err)
+		return ObjectInfo{}, fmt.Errorf("failed to upload object '%s' to MinIO: %w", key, err)
 	}
 
-	log.Printf("Successfully uploaded file: %s (size: %d bytes)", fileID, len(content))
-	return fmt.Sprintf("http://%s/%s/%s", m.Client.EndpointURL().Host, m.Bucket, objectName), nil
+	log.Printf("Successfully uploaded object: %s (size: %d bytes)", key, len(content))
+	return ObjectInfo{
+		Key:          key,
+		Size:         info.Size,
+		ContentType:  contentType,
+		LastModified: info.LastModified,
+		Metadata:     normalizeMetadata(opts.Metadata),
+	}, nil
 }
 
-func (m *MinIO) GetFile(fileID string) ([]byte, error) {
-	if fileID == "" {
-		return nil, fmt.Errorf("file ID cannot be empty")
+func (m *MinIO) Get(key string) ([]byte, ObjectInfo, error) {
+	if key == "" {
+		return nil, ObjectInfo{}, fmt.Errorf("object key cannot be empty")
 	}
 
-	objectName := fileID + ".json"
 	ctx := context.Background()
 
-	object, err := m.Client.GetObject(ctx, m.Bucket, objectName, minio.GetObjectOptions{})
+	object, err := m.Client.GetObject(ctx, m.Bucket, key, minio.GetObjectOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get file '%s' from MinIO: %w", fileID, err)
+		return nil, ObjectInfo{}, m.wrapError(key, err)
 	}
 	defer object.Close()
 
+	stat, err := object.Stat()
+	if err != nil {
+		return nil, ObjectInfo{}, m.wrapError(key, err)
+	}
+
 	content, err := io.ReadAll(object)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file '%s' content: %w", fileID, err)
+		return nil, ObjectInfo{}, fmt.Errorf("failed to read object '%s' content: %w", key, err)
 	}
 
-	log.Printf("Successfully retrieved file: %s (size: %d bytes)", fileID, len(content))
-	return content, nil
+	log.Printf("Successfully retrieved object: %s (size: %d bytes)", key, len(content))
+	return content, toObjectInfo(stat), nil
 }
 
-func (m *MinIO) DeleteFile(fileID string) error {
-	if fileID == "" {
-		return fmt.Errorf("file ID cannot be empty")
+func (m *MinIO) Delete(key string) error {
+	if key == "" {
+		return fmt.Errorf("object key cannot be empty")
 	}
 
-	objectName := fileID + ".json"
 	ctx := context.Background()
 
-	err := m.Client.RemoveObject(ctx, m.Bucket, objectName, minio.RemoveObjectOptions{})
+	err := m.Client.RemoveObject(ctx, m.Bucket, key, minio.RemoveObjectOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to delete file '%s' from MinIO: %w", fileID, err)
+		return fmt.Errorf("failed to delete object '%s' from MinIO: %w", key, err)
 	}
 
-	log.Printf("Successfully deleted file: %s", fileID)
+	log.Printf("Successfully deleted object: %s", key)
 	return nil
 }
 
-func (m *MinIO) ListFiles() ([]string, error) {
-	var files []string
+func (m *MinIO) List(prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
 	ctx := context.Background()
 
 	objectCh := m.Client.ListObjects(ctx, m.Bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
 		Recursive: true,
 	})
 
@@ -172,90 +167,87 @@ func (m *MinIO) ListFiles() ([]string, error) {
 		if object.Err != nil {
 			return nil, fmt.Errorf("error listing objects in MinIO bucket '%s': %w", m.Bucket, object.Err)
 		}
-		files = append(files, object.Key)
+		objects = append(objects, toObjectInfo(object))
 	}
 
-	log.Printf("Successfully listed %d files from bucket: %s", len(files), m.Bucket)
-	return files, nil
+	log.Printf("Successfully listed %d objects from bucket: %s", len(objects), m.Bucket)
+	return objects, nil
 }
 
-func (m *MinIO) GetFileURL(fileID string) string {
-	if fileID == "" {
-		log.Printf("Warning: GetFileURL called with empty file ID")
-		return ""
+func (m *MinIO) Copy(srcKey, dstKey string) error {
+	if srcKey == "" || dstKey == "" {
+		return fmt.Errorf("object keys cannot be empty")
 	}
 
-	objectName := fileID + ".json"
 	ctx := context.Background()
 
-	presignedURL, err := m.Client.PresignedGetObject(ctx, m.Bucket, objectName, time.Hour*24*7, nil)
-	if err != nil {
-		log.Printf("Failed to generate presigned URL for file '%s': %v", fileID, err)
-		return ""
+	src := minio.CopySrcOptions{
+		Bucket: m.Bucket,
+		Object: srcKey,
 	}
 
-	log.Printf("Generated presigned URL for file: %s (expires in 7 days)", fileID)
-	return presignedURL.String()
-}
-
-func (m *MinIO) CreateFolder(folderPath string) error {
-	if folderPath == "" {
-		return fmt.Errorf("folder path cannot be empty")
+	dst := minio.CopyDestOptions{
+		Bucket: m.Bucket,
+		Object: dstKey,
 	}
 
-	if !strings.HasSuffix(folderPath, "/") {
-		folderPath += "/"
+	_, err := m.Client.CopyObject(ctx, dst, src)
+	if err != nil {
+		return m.wrapError(srcKey, err)
 	}
 
-	if strings.HasPrefix(folderPath, "/") {
-		folderPath = folderPath[1:]
+	log.Printf("Successfully copied object from %s to %s", srcKey, dstKey)
+	return nil
+}
+
+func (m *MinIO) Stat(key string) (ObjectInfo, error) {
+	if key == "" {
+		return ObjectInfo{}, fmt.Errorf("object key cannot be empty")
 	}
 
 	ctx := context.Background()
-	reader := bytes.NewReader([]byte{})
 
-	_, err := m.Client.PutObject(ctx, m.Bucket, folderPath, reader, 0,
-		minio.PutObjectOptions{ContentType: "application/x-directory"})
+	stat, err := m.Client.StatObject(ctx, m.Bucket, key, minio.StatObjectOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to create folder '%s' in MinIO: %w", folderPath, err)
+		return ObjectInfo{}, m.wrapError(key, err)
 	}
 
-	log.Printf("Successfully created folder: %s", folderPath)
-	return nil
+	return toObjectInfo(stat), nil
 }
 
-func (m *MinIO) MoveFile(oldFileID, newFileID string) error {
-	if oldFileID == "" || newFileID == "" {
-		return fmt.Errorf("file IDs cannot be empty")
+func (m *MinIO) GetObjectURL(key string) string {
+	if key == "" {
+		log.Printf("Warning: GetObjectURL called with empty key")
+		return ""
 	}
 
-	oldObjectName := oldFileID + ".json"
-	newObjectName := newFileID + ".json"
-
 	ctx := context.Background()
 
-	src := minio.CopySrcOptions{
-		Bucket: m.Bucket,
-		Object: oldObjectName,
+	presignedURL, err := m.Client.PresignedGetObject(ctx, m.Bucket, key, time.Hour*24*7, nil)
+	if err != nil {
+		log.Printf("Failed to generate presigned URL for object '%s': %v", key, err)
+		return ""
 	}
 
-	dst := minio.CopyDestOptions{
-		Bucket: m.Bucket,
-		Object: newObjectName,
-	}
+	log.Printf("Generated presigned URL for object: %s (expires in 7 days)", key)
+	return presignedURL.String()
+}
 
-	_, err := m.Client.CopyObject(ctx, dst, src)
-	if err != nil {
-		return fmt.Errorf("failed to copy file from '%s' to '%s': %w", oldFileID, newFileID, err)
+func (m *MinIO) wrapError(key string, err error) error {
+	if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+		return fmt.Errorf("object '%s': %w", key, ErrObjectNotFound)
 	}
+	return fmt.Errorf("failed to access object '%s' in MinIO: %w", key, err)
+}
 
-	err = m.Client.RemoveObject(ctx, m.Bucket, oldObjectName, minio.RemoveObjectOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to remove old file '%s': %w", oldFileID, err)
+func toObjectInfo(object minio.ObjectInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:          object.Key,
+		Size:         object.Size,
+		ContentType:  object.ContentType,
+		LastModified: object.LastModified,
+		Metadata:     normalizeMetadata(object.UserMetadata),
 	}
-
-	log.Printf("Successfully moved file from %s to %s", oldFileID, newFileID)
-	return nil
 }
 
 var Module = fx.Options(
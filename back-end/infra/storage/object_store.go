@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrObjectNotFound = errors.New("object not found")
+
+const FolderContentType = "application/x-directory"
+
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+	Metadata     map[string]string
+}
+
+type PutOptions struct {
+	ContentType string
+	Metadata    map[string]string
+}
+
+type ObjectStore interface {
+	Put(key string, content []byte, opts PutOptions) (ObjectInfo, error)
+	Get(key string) ([]byte, ObjectInfo, error)
+	Delete(key string) error
+	List(prefix string) ([]ObjectInfo, error)
+	Copy(srcKey, dstKey string) error
+	Stat(key string) (ObjectInfo, error)
+}
+
+// Wrapper é implementado pelos stores que decoram outro store.
+type Wrapper interface {
+	Unwrap() ObjectStore
+}
+
+// As percorre a cadeia de stores procurando um que implemente T.
+func As[T any](store ObjectStore) (T, bool) {
+	for store != nil {
+		if target, ok := store.(T); ok {
+			return target, true
+		}
+
+		wrapper, ok := store.(Wrapper)
+		if !ok {
+			break
+		}
+		store = wrapper.Unwrap()
+	}
+
+	var zero T
+	return zero, false
+}
+
+// FolderPrefix separa os marcadores de pasta, cujas chaves vêm de nomes
+// escolhidos pelo usuário, das chaves internas como blobs/ e staging/. Sem
+// ele, uma pasta "staging" na raiz teria o marcador dentro do staging.
+const FolderPrefix = "folders/"
+
+func FolderKey(folderPath string) string {
+	folderPath = strings.TrimPrefix(folderPath, "/")
+	if !strings.HasSuffix(folderPath, "/") {
+		folderPath += "/"
+	}
+	return FolderPrefix + folderPath
+}
+
+func normalizeMetadata(metadata map[string]string) map[string]string {
+	normalized := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		normalized[strings.ToLower(key)] = value
+	}
+	return normalized
+}
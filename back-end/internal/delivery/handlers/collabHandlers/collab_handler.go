@@ -0,0 +1,135 @@
+package collabHandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+
+	"myScalidraw/internal/domain/useCase/collab"
+	"myScalidraw/pkg/projectError"
+)
+
+const (
+	maxMessageSize = 16 << 20
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+)
+
+type CollabHandler struct {
+	hub *collab.Hub
+}
+
+func NewCollabHandler(hub *collab.Hub) *CollabHandler {
+	return &CollabHandler{
+		hub: hub,
+	}
+}
+
+func (h *CollabHandler) RegisterRoutes(app *fiber.App) {
+	api := app.Group("/api")
+
+	api.Get("/files/:id/collab", h.RequireUpgrade, websocket.New(h.Collaborate))
+}
+
+func (h *CollabHandler) RequireUpgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return c.Status(http.StatusUpgradeRequired).JSON(fiber.Map{"error": "websocket upgrade required"})
+	}
+	return c.Next()
+}
+
+// Collaborate liga uma conexão à sala do arquivo. Cada conexão tem uma
+// goroutine de escrita, que esvazia a fila do participante, e o loop de
+// leitura, que entrega as mensagens à sala.
+func (h *CollabHandler) Collaborate(conn *websocket.Conn) {
+	defer conn.Close()
+
+	fileID := conn.Params("id")
+	username := conn.Query("username", "anonymous")
+
+	room, participant, err := h.hub.Join(fileID, username)
+	if err != nil {
+		conn.WriteJSON(collab.Message{Type: collab.MessageError, Message: projectError.ErrorMessage(err)})
+		return
+	}
+	defer h.hub.Leave(room, participant)
+
+	// A conexão volta para o pool do Fiber quando o handler retorna, então a
+	// goroutine de escrita precisa terminar antes disso.
+	done := make(chan struct{})
+	var writer sync.WaitGroup
+	writer.Add(1)
+	go func() {
+		defer writer.Done()
+		writePump(conn, participant, done)
+	}()
+	defer func() {
+		close(done)
+		writer.Wait()
+	}()
+
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, content, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("Collaboration connection for %s closed: %v", fileID, err)
+			}
+			return
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+
+		var message collab.Message
+		if err := decoder.Decode(&message); err != nil {
+			room.Reject(participant, err)
+			continue
+		}
+
+		room.Handle(participant, message)
+	}
+}
+
+func writePump(conn *websocket.Conn, participant *collab.Participant, done <-chan struct{}) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case content, ok := <-participant.Send:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, []byte{})
+				conn.Close()
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, content); err != nil {
+				conn.Close()
+				return
+			}
+
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				conn.Close()
+				return
+			}
+
+		case <-done:
+			return
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"myScalidraw/internal/domain/models"
+	"myScalidraw/pkg/projectError"
 	"myScalidraw/pkg/uuid"
 
 	"github.com/gofiber/fiber/v2"
@@ -98,7 +99,10 @@ func (h *FileHandler) CreateFile(c *fiber.Ctx) error {
 
 	err = h.fileUseCase.CreateFile(metadata, content)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "error creating file"})
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error creating file",
+			"details": projectError.ErrorMessage(err),
+		})
 	}
 
 	fileItem := metadata.ToFileItem()
@@ -172,7 +176,10 @@ func (h *FileHandler) UploadFile(c *fiber.Ctx) error {
 
 	err = h.fileUseCase.CreateFile(metadata, validatedContent)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "error uploading file"})
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error uploading file",
+			"details": projectError.ErrorMessage(err),
+		})
 	}
 
 	response := map[string]interface{}{
@@ -0,0 +1,50 @@
+package fileHandlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"myScalidraw/pkg/projectError"
+)
+
+// DiffFile compara duas versões de um desenho. "from" e "to" são números de
+// revisão (vazio = conteúdo atual); com "against" o lado "to" é outro arquivo
+// e "to" é a revisão dele.
+func (h *FileHandler) DiffFile(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	fromRevision := c.QueryInt("from", 0)
+	toRevision := c.QueryInt("to", 0)
+	if fromRevision < 0 || toRevision < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid revision"})
+	}
+
+	toID := id
+	if against := c.Query("against"); against != "" {
+		toID = against
+	}
+
+	for _, fileID := range []string{id, toID} {
+		metadata, err := h.fileUseCase.GetMetadata(fileID)
+		if err != nil {
+			return c.Status(errorStatus(err)).JSON(fiber.Map{
+				"error":   "error comparing files",
+				"details": projectError.ErrorMessage(err),
+			})
+		}
+		if metadata.IsFolder {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "folders cannot be compared"})
+		}
+	}
+
+	diff, err := h.fileUseCase.DiffFiles(id, fromRevision, toID, toRevision)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error comparing files",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	return c.JSON(diff)
+}
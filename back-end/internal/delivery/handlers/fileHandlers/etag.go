@@ -0,0 +1,52 @@
+package fileHandlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"myScalidraw/pkg/projectError"
+)
+
+// O ETag de um arquivo é o seu contador de revisões, que muda a cada gravação.
+
+func formatETag(revision int) string {
+	return `"` + strconv.Itoa(revision) + `"`
+}
+
+// parseIfMatch devolve a revisão pedida no If-Match. any indica "*", que só
+// exige que o arquivo exista.
+func parseIfMatch(header string) (revision int, any bool, ok bool) {
+	value := strings.TrimSpace(header)
+	if value == "*" {
+		return 0, true, true
+	}
+
+	value = strings.TrimPrefix(value, "W/")
+	value = strings.Trim(value, `"`)
+
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision < 0 {
+		return 0, false, false
+	}
+	return revision, false, true
+}
+
+// preconditionFailed responde 412 com a versão atual do arquivo, para que o
+// cliente possa recarregar ou mesclar antes de tentar de novo.
+func (h *FileHandler) preconditionFailed(c *fiber.Ctx, id string, err error) error {
+	current, getErr := h.fileUseCase.GetFileByID(id)
+	if getErr != nil || current == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "file not found"})
+	}
+
+	c.Set(fiber.HeaderETag, formatETag(current.Revision))
+	return c.Status(http.StatusPreconditionFailed).JSON(fiber.Map{
+		"error":    "file was modified by someone else",
+		"details":  projectError.ErrorMessage(err),
+		"revision": current.Revision,
+		"file":     current,
+	})
+}
@@ -0,0 +1,76 @@
+package fileHandlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// heartbeatInterval mantém a conexão viva em proxies que derrubam streams
+// ociosos e detecta clientes que já foram embora.
+const heartbeatInterval = 15 * time.Second
+
+// StreamEvents envia as alterações da árvore como Server-Sent Events. Cada
+// evento traz o tipo no campo event e o item afetado no data.
+func (h *FileHandler) StreamEvents(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	subscription, unsubscribe := h.fileUseCase.SubscribeEvents()
+
+	// O WriteTimeout do servidor vale para a resposta inteira, então o prazo
+	// é renovado a cada escrita para o stream não cair depois de alguns
+	// segundos.
+	conn := c.Context().Conn()
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		write := func(content string) bool {
+			conn.SetWriteDeadline(time.Now().Add(heartbeatInterval * 2))
+			if _, err := w.WriteString(content); err != nil {
+				return false
+			}
+			return w.Flush() == nil
+		}
+
+		if !write("retry: 3000\n\n") {
+			return
+		}
+
+		for {
+			select {
+			case event, ok := <-subscription:
+				if !ok {
+					return
+				}
+
+				data, err := json.Marshal(event)
+				if err != nil {
+					log.Printf("Error encoding %s event: %v", event.Type, err)
+					continue
+				}
+
+				if !write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)) {
+					return
+				}
+
+			case <-ticker.C:
+				if !write(": heartbeat\n\n") {
+					return
+				}
+			}
+		}
+	})
+
+	return nil
+}
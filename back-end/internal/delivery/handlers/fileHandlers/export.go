@@ -0,0 +1,150 @@
+package fileHandlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"myScalidraw/pkg/projectError"
+	"myScalidraw/pkg/render"
+)
+
+const defaultExportPadding = 10
+
+// parseRenderOptions lê background (padrão true), dark, padding e frame. Ao
+// exportar um frame o padding padrão é zero, para a imagem ter o tamanho dele.
+func parseRenderOptions(c *fiber.Ctx) (render.Options, error) {
+	options := render.Options{
+		Background: true,
+		Frame:      c.Query("frame"),
+		Padding:    defaultExportPadding,
+	}
+	if options.Frame != "" {
+		options.Padding = 0
+	}
+
+	if value := c.Query("background"); value != "" {
+		background, err := strconv.ParseBool(value)
+		if err != nil {
+			return options, projectError.Errorf(projectError.EINVALID, "invalid background: %s", value)
+		}
+		options.Background = background
+	}
+
+	if value := c.Query("dark"); value != "" {
+		dark, err := strconv.ParseBool(value)
+		if err != nil {
+			return options, projectError.Errorf(projectError.EINVALID, "invalid dark: %s", value)
+		}
+		options.DarkMode = dark
+	}
+
+	if value := c.Query("padding"); value != "" {
+		padding, err := strconv.ParseFloat(value, 64)
+		if err != nil || padding < 0 || padding > 1000 {
+			return options, projectError.Errorf(projectError.EINVALID, "invalid padding: %s", value)
+		}
+		options.Padding = padding
+	}
+
+	return options, nil
+}
+
+// exportETag muda com a revisão e com as opções, já que cada combinação gera
+// uma imagem diferente.
+func exportETag(revision int, options ...interface{}) string {
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "%+v", options)
+	return fmt.Sprintf(`"%d-%08x"`, revision, hash.Sum32())
+}
+
+// ExportSVG renderiza a revisão atual do desenho como SVG.
+func (h *FileHandler) ExportSVG(c *fiber.Ctx) error {
+	svgOptions := render.SVGOptions{Fonts: h.fonts}
+
+	return h.export(c, "image/svg+xml", svgOptions, false, func(w io.Writer, pages []*render.Drawing) error {
+		return render.WriteSVG(w, pages[0], svgOptions)
+	})
+}
+
+// ExportPNG rasteriza o desenho. "scale" multiplica o tamanho em pixels (padrão
+// 1, no máximo render.MaxPNGScale).
+func (h *FileHandler) ExportPNG(c *fiber.Ctx) error {
+	pngOptions := render.PNGOptions{Scale: 1}
+	if value := c.Query("scale"); value != "" {
+		scale, err := strconv.ParseFloat(value, 64)
+		if err != nil || scale <= 0 || scale > render.MaxPNGScale {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error":   "invalid export options",
+				"details": fmt.Sprintf("scale must be greater than 0 and at most %d", render.MaxPNGScale),
+			})
+		}
+		pngOptions.Scale = scale
+	}
+
+	return h.export(c, "image/png", pngOptions, false, func(w io.Writer, pages []*render.Drawing) error {
+		return render.WritePNG(w, pages[0], pngOptions)
+	})
+}
+
+// ExportPDF gera uma página por frame, na ordem da cena, ou uma página com o
+// desenho inteiro quando não há frames. Com "frame" sai só aquele frame.
+func (h *FileHandler) ExportPDF(c *fiber.Ctx) error {
+	return h.export(c, "application/pdf", nil, true, render.WritePDF)
+}
+
+// export trata o que é comum aos formatos: opções, carga do desenho, ETag e
+// If-None-Match. formatOptions entra no ETag junto com as opções de render.
+// Sem pages, write recebe uma página só.
+func (h *FileHandler) export(c *fiber.Ctx, contentType string, formatOptions interface{}, pages bool, write func(io.Writer, []*render.Drawing) error) error {
+	options, err := parseRenderOptions(c)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "invalid export options",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	var drawings []*render.Drawing
+	var revision int
+	if pages {
+		drawings, revision, err = h.fileUseCase.RenderPages(c.Params("id"), options)
+	} else {
+		var drawing *render.Drawing
+		drawing, revision, err = h.fileUseCase.RenderFile(c.Params("id"), options)
+		drawings = []*render.Drawing{drawing}
+	}
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error exporting file",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	etag := exportETag(revision, options, formatOptions)
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	if c.Get(fiber.HeaderIfNoneMatch) == etag {
+		return c.SendStatus(http.StatusNotModified)
+	}
+
+	var output bytes.Buffer
+	if err := write(&output, drawings); err != nil {
+		if errors.Is(err, render.ErrImageTooLarge) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error":   "error exporting file",
+				"details": err.Error(),
+			})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "error exporting file"})
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Send(output.Bytes())
+}
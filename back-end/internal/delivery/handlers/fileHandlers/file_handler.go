@@ -2,20 +2,29 @@ package fileHandlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
 
+	"myScalidraw/internal/domain/models"
+	"myScalidraw/internal/domain/scene"
 	"myScalidraw/internal/domain/useCase/file"
+	"myScalidraw/pkg/projectError"
+	"myScalidraw/pkg/render"
 )
 
 type FileHandler struct {
 	fileUseCase *file.FileUseCase
+
+	// fonts são as fontes do Excalidraw embutidas nos SVGs exportados.
+	fonts *render.FontCatalog
 }
 
-func NewFileHandler(fileUseCase *file.FileUseCase) *FileHandler {
+func NewFileHandler(fileUseCase *file.FileUseCase, fonts *render.FontCatalog) *FileHandler {
 	return &FileHandler{
 		fileUseCase: fileUseCase,
+		fonts:       fonts,
 	}
 }
 
@@ -23,28 +32,90 @@ func (h *FileHandler) RegisterRoutes(app *fiber.App) {
 	api := app.Group("/api")
 
 	api.Get("/files", h.GetFiles)
+	api.Get("/tree", h.GetTree)
 	api.Get("/files/:id", h.GetFileByID)
 	api.Post("/files", h.CreateFile)
 	api.Post("/files/upload", h.UploadFile)
 	api.Put("/files/:id", h.SaveFile)
+	api.Patch("/files/:id", h.PatchFile)
 	api.Put("/files/:id/rename", h.RenameFile)
+	api.Put("/files/:id/move", h.MoveFile)
+	api.Post("/files/:id/copy", h.CopyFile)
 	api.Delete("/files/:id", h.DeleteFile)
 
+	api.Get("/files/:id/revisions", h.GetRevisions)
+	api.Get("/files/:id/revisions/:rev", h.GetRevision)
+	api.Post("/files/:id/revisions/:rev/restore", h.RestoreRevision)
+	api.Get("/files/:id/diff", h.DiffFile)
+	api.Get("/files/:id/export.svg", h.ExportSVG)
+	api.Get("/files/:id/export.png", h.ExportPNG)
+	api.Get("/files/:id/export.pdf", h.ExportPDF)
+	api.Get("/thumbnails/:digest.png", h.GetThumbnail)
+
+	api.Get("/trash", h.GetTrash)
+	api.Post("/trash/:id/restore", h.RestoreFromTrash)
+	api.Delete("/trash/:id", h.PurgeFromTrash)
+
+	api.Get("/events", h.StreamEvents)
+	api.Get("/sync", h.Sync)
+
+	api.Get("/paths/*", h.GetPath)
+	api.Put("/paths/*", h.SavePath)
+	api.Delete("/paths/*", h.DeletePath)
+
 	api.Get("/ping", h.Ping)
 }
 
+func errorStatus(err error) int {
+	switch projectError.ErrorCode(err) {
+	case projectError.ENOTFOUND:
+		return http.StatusNotFound
+	case projectError.EINVALID:
+		return http.StatusBadRequest
+	case projectError.ECONFLICT:
+		return http.StatusConflict
+	case projectError.EPRECONDITION:
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func (h *FileHandler) Ping(c *fiber.Ctx) error {
 	return c.SendString("pong")
 }
 
+// GetFiles lista os arquivos em páginas de "limit" itens, ou de
+// file.DefaultListLimit sem ele. O cursor da próxima página vai no header
+// X-Next-Cursor.
 func (h *FileHandler) GetFiles(c *fiber.Ctx) error {
-	files := h.fileUseCase.GetFiles()
+	query, err := parseFileQuery(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error":   "invalid query",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	files, next, err := h.fileUseCase.ListFiles(query)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error listing files",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	if next != "" {
+		c.Set("X-Next-Cursor", next)
+	}
 	return c.JSON(files)
 }
 
 func (h *FileHandler) GetFileByID(c *fiber.Ctx) error {
-	id := c.Params("id")
+	return h.getFile(c, c.Params("id"))
+}
 
+func (h *FileHandler) getFile(c *fiber.Ctx, id string) error {
 	file, err := h.fileUseCase.GetFileByID(id)
 
 	if err != nil {
@@ -62,11 +133,14 @@ func (h *FileHandler) GetFileByID(c *fiber.Ctx) error {
 		"parentId":     file.ParentID,
 		"lastModified": file.LastModified,
 		"path":         file.Path,
+		"revision":     file.Revision,
 	}
 
 	if !file.IsFolder {
-		// Buscar o conteúdo original diretamente do repositório
-		content, err := h.fileUseCase.GetFileContent(id)
+		c.Set(fiber.HeaderETag, formatETag(file.Revision))
+
+		// Buscar o conteúdo da mesma revisão informada no ETag
+		content, err := h.fileUseCase.GetFileContentAt(id, file.Revision)
 		if err == nil && content != "" {
 			response["content"] = content
 		} else if file.Data != nil {
@@ -80,20 +154,27 @@ func (h *FileHandler) GetFileByID(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
+type mergedSaveResponse struct {
+	*models.FileItem
+	Merged    bool             `json:"merged"`
+	Conflicts []scene.Conflict `json:"conflicts"`
+}
+
 func (h *FileHandler) SaveFile(c *fiber.Ctx) error {
-	id := c.Params("id")
+	return h.saveFile(c, c.Params("id"))
+}
 
+// sceneBody valida o corpo de uma gravação e completa os campos de topo que o
+// Excalidraw espera encontrar no arquivo. Quem chama escreve a resposta de erro.
+func sceneBody(c *fiber.Ctx) ([]byte, error) {
 	fileContent := c.Body()
 	if len(fileContent) == 0 {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "empty file content"})
+		return nil, projectError.Errorf(projectError.EINVALID, "empty file content")
 	}
 
 	var jsonData map[string]interface{}
 	if err := json.Unmarshal(fileContent, &jsonData); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
-			"error":   "content must be valid JSON",
-			"details": err.Error(),
-		})
+		return nil, projectError.Errorf(projectError.EINVALID, "content must be valid JSON: %v", err)
 	}
 
 	if jsonData["type"] == nil {
@@ -108,21 +189,67 @@ func (h *FileHandler) SaveFile(c *fiber.Ctx) error {
 
 	validatedContent, err := json.Marshal(jsonData)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "error processing JSON"})
+		return nil, fmt.Errorf("error processing JSON: %w", err)
 	}
+	return validatedContent, nil
+}
 
-	err = h.fileUseCase.SaveFile(id, string(validatedContent))
+func (h *FileHandler) saveFile(c *fiber.Ctx, id string) error {
+	validatedContent, err := sceneBody(c)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "error saving file"})
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "invalid file content",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	// Com If-Match desatualizado a gravação é mesclada com a versão atual,
+	// a menos que o cliente peça merge=false.
+	result := &file.SaveResult{Content: string(validatedContent)}
+	ifMatch := c.Get(fiber.HeaderIfMatch)
+	expected, anyRevision, ok := parseIfMatch(ifMatch)
+	switch {
+	case ifMatch == "" || anyRevision:
+		result.Revision, err = h.fileUseCase.SaveFile(id, result.Content)
+	case !ok:
+		return h.preconditionFailed(c, id, projectError.Errorf(projectError.EPRECONDITION, "invalid If-Match header"))
+	case !c.QueryBool("merge", true):
+		result.Revision, err = h.fileUseCase.SaveFileIfMatch(id, result.Content, expected)
+	default:
+		result, err = h.fileUseCase.SaveFileMerging(id, result.Content, expected)
 	}
 
-	updatedFile, err := h.fileUseCase.GetFileByID(id)
 	if err != nil {
+		if projectError.ErrorCode(err) == projectError.EPRECONDITION {
+			return h.preconditionFailed(c, id, err)
+		}
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error saving file",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	c.Set(fiber.HeaderETag, formatETag(result.Revision.Revision))
+
+	updatedFile, err := h.fileUseCase.GetFileByID(id)
+	if err != nil || updatedFile == nil {
 
 		return c.JSON(fiber.Map{"message": "file saved successfully"})
 	}
 
-	return c.JSON(updatedFile)
+	if !result.Merged {
+		return c.JSON(updatedFile)
+	}
+
+	// Devolve exatamente a cena gravada, que o cliente deve adotar.
+	updatedFile.Data = result.Scene
+	updatedFile.Revision = result.Revision.Revision
+
+	return c.JSON(mergedSaveResponse{
+		FileItem:  updatedFile,
+		Merged:    true,
+		Conflicts: result.Conflicts,
+	})
 }
 
 func (h *FileHandler) RenameFile(c *fiber.Ctx) error {
@@ -142,7 +269,10 @@ func (h *FileHandler) RenameFile(c *fiber.Ctx) error {
 
 	err := h.fileUseCase.RenameFile(id, request.Name)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "error renaming file"})
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error renaming file",
+			"details": projectError.ErrorMessage(err),
+		})
 	}
 
 	updatedFile, err := h.fileUseCase.GetFileByID(id)
@@ -154,13 +284,81 @@ func (h *FileHandler) RenameFile(c *fiber.Ctx) error {
 	return c.JSON(updatedFile)
 }
 
-func (h *FileHandler) DeleteFile(c *fiber.Ctx) error {
+func (h *FileHandler) MoveFile(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var request struct {
+		ParentID string `json:"parentId"`
+	}
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "error parsing request body"})
+	}
+
+	err := h.fileUseCase.MoveFile(id, request.ParentID)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error moving file",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	updatedFile, err := h.fileUseCase.GetFileByID(id)
+	if err != nil {
+
+		return c.JSON(fiber.Map{"message": "file moved successfully"})
+	}
+
+	return c.JSON(updatedFile)
+}
+
+func (h *FileHandler) CopyFile(c *fiber.Ctx) error {
 	id := c.Params("id")
 
+	var request struct {
+		ParentID *string `json:"parentId"`
+		Name     string  `json:"name"`
+	}
+
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "error parsing request body"})
+		}
+	}
+
+	source, err := h.fileUseCase.GetMetadata(id)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error copying file",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	parentID := source.ParentID
+	if request.ParentID != nil {
+		parentID = *request.ParentID
+	}
+
+	copied, err := h.fileUseCase.CopyFile(id, parentID, request.Name)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error copying file",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	return c.JSON(copied.ToFileItem())
+}
+
+func (h *FileHandler) DeleteFile(c *fiber.Ctx) error {
+	return h.deleteFile(c, c.Params("id"))
+}
+
+func (h *FileHandler) deleteFile(c *fiber.Ctx, id string) error {
 	err := h.fileUseCase.DeleteFile(id)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "error deleting file"})
+		return c.Status(errorStatus(err)).JSON(fiber.Map{"error": "error deleting file"})
 	}
 
-	return c.JSON(fiber.Map{"message": "file deleted successfully"})
+	return c.JSON(fiber.Map{"message": "file moved to trash"})
 }
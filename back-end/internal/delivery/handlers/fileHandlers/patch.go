@@ -0,0 +1,57 @@
+package fileHandlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"myScalidraw/internal/domain/scene"
+	"myScalidraw/pkg/projectError"
+)
+
+// PatchFile recebe só o que mudou na cena: elementos inseridos ou alterados,
+// ids removidos, chaves do appState e arquivos novos. A resposta traz apenas a
+// nova revisão, sem devolver a cena inteira.
+func (h *FileHandler) PatchFile(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	patch, err := scene.ParsePatch(c.Body())
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error":   "invalid patch",
+			"details": err.Error(),
+		})
+	}
+	if patch.IsEmpty() {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "patch is empty"})
+	}
+
+	var requiredRevision *int
+	if ifMatch := c.Get(fiber.HeaderIfMatch); ifMatch != "" {
+		expected, anyRevision, ok := parseIfMatch(ifMatch)
+		if !ok {
+			return h.preconditionFailed(c, id, projectError.Errorf(projectError.EPRECONDITION, "invalid If-Match header"))
+		}
+		if !anyRevision && !c.QueryBool("merge", true) {
+			requiredRevision = &expected
+		}
+	}
+
+	result, err := h.fileUseCase.PatchFile(id, patch, requiredRevision)
+	if err != nil {
+		if projectError.ErrorCode(err) == projectError.EPRECONDITION {
+			return h.preconditionFailed(c, id, err)
+		}
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error patching file",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	c.Set(fiber.HeaderETag, formatETag(result.Revision.Revision))
+	return c.JSON(fiber.Map{
+		"id":        id,
+		"revision":  result.Revision.Revision,
+		"conflicts": result.Conflicts,
+	})
+}
@@ -0,0 +1,106 @@
+package fileHandlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+
+	"myScalidraw/pkg/projectError"
+)
+
+// As rotas /api/paths/* aceitam o caminho de FileMetadata.Path no lugar do ID,
+// por exemplo /api/paths/Architecture/Payments/flow.excalidraw. O ID continua
+// sendo a forma canônica: as respostas são as mesmas de /api/files/:id.
+// Fora da lixeira cada caminho tem no máximo um item, garantido por um índice
+// único no banco.
+
+func (h *FileHandler) resolvePath(c *fiber.Ctx) (string, error) {
+	filePath, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		return "", projectError.Errorf(projectError.EINVALID, "invalid path")
+	}
+
+	metadata, err := h.fileUseCase.ResolvePath(filePath)
+	if err != nil {
+		return "", err
+	}
+	return metadata.ID, nil
+}
+
+func (h *FileHandler) GetPath(c *fiber.Ctx) error {
+	id, err := h.resolvePath(c)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error resolving path",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	return h.getFile(c, id)
+}
+
+// SavePath grava um desenho existente ou cria um novo no caminho. Com
+// parents=true as pastas que faltarem são criadas. If-None-Match: * só
+// permite criar.
+func (h *FileHandler) SavePath(c *fiber.Ctx) error {
+	id, err := h.resolvePath(c)
+	if err == nil {
+		if c.Get(fiber.HeaderIfNoneMatch) == "*" {
+			return h.preconditionFailed(c, id, projectError.Errorf(projectError.EPRECONDITION, "path already exists"))
+		}
+
+		file, getErr := h.fileUseCase.GetFileByID(id)
+		if getErr == nil && file != nil && file.IsFolder {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "cannot save content to a folder"})
+		}
+		return h.saveFile(c, id)
+	}
+
+	if projectError.ErrorCode(err) != projectError.ENOTFOUND {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error resolving path",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	if ifMatch := c.Get(fiber.HeaderIfMatch); ifMatch != "" {
+		return c.Status(http.StatusPreconditionFailed).JSON(fiber.Map{
+			"error":   "file does not exist",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	content, err := sceneBody(c)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "invalid file content",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	filePath, _ := url.PathUnescape(c.Params("*"))
+	metadata, err := h.fileUseCase.CreateFileAtPath(filePath, content, c.QueryBool("parents", false))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error creating file",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	c.Set(fiber.HeaderETag, formatETag(metadata.Revision))
+	c.Set(fiber.HeaderLocation, "/api/files/"+metadata.ID)
+	return c.Status(http.StatusCreated).JSON(metadata.ToFileItem())
+}
+
+func (h *FileHandler) DeletePath(c *fiber.Ctx) error {
+	id, err := h.resolvePath(c)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error deleting file",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	return h.deleteFile(c, id)
+}
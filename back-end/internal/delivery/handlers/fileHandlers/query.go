@@ -0,0 +1,75 @@
+package fileHandlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"myScalidraw/internal/domain/models"
+	"myScalidraw/pkg/projectError"
+)
+
+// parseFileQuery lê os parâmetros da listagem: limit, cursor, sort, order,
+// parentId (vazio = raiz), isFolder, modifiedSince (epoch em ms, como o
+// lastModified dos itens, ou RFC 3339) e prefix.
+func parseFileQuery(c *fiber.Ctx) (*models.FileQuery, error) {
+	query := &models.FileQuery{
+		SortBy:     c.Query("sort", models.SortByName),
+		NamePrefix: c.Query("prefix"),
+	}
+
+	switch c.Query("order", "asc") {
+	case "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return nil, projectError.Errorf(projectError.EINVALID, "order must be asc or desc")
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return nil, projectError.Errorf(projectError.EINVALID, "invalid limit: %s", value)
+		}
+		query.Limit = limit
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := models.DecodeFileCursor(value)
+		if err != nil {
+			return nil, projectError.Errorf(projectError.EINVALID, "invalid cursor")
+		}
+		query.After = cursor
+	}
+
+	if c.Context().QueryArgs().Has("parentId") {
+		parentID := c.Query("parentId")
+		query.ParentID = &parentID
+	}
+
+	if value := c.Query("isFolder"); value != "" {
+		isFolder, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, projectError.Errorf(projectError.EINVALID, "invalid isFolder: %s", value)
+		}
+		query.IsFolder = &isFolder
+	}
+
+	if value := c.Query("modifiedSince"); value != "" {
+		since, err := parseTimestamp(value)
+		if err != nil {
+			return nil, projectError.Errorf(projectError.EINVALID, "invalid modifiedSince: %s", value)
+		}
+		query.ModifiedSince = &since
+	}
+
+	return query, nil
+}
+
+func parseTimestamp(value string) (time.Time, error) {
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(millis), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,54 @@
+package fileHandlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *FileHandler) GetRevisions(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	revisions, err := h.fileUseCase.GetRevisions(id)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{"error": "error fetching revisions"})
+	}
+
+	return c.JSON(revisions)
+}
+
+func (h *FileHandler) GetRevision(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	revision, err := c.ParamsInt("rev")
+	if err != nil || revision <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid revision"})
+	}
+
+	content, err := h.fileUseCase.GetRevisionContent(id, revision)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{"error": "error fetching revision"})
+	}
+
+	return c.JSON(fiber.Map{
+		"id":       id,
+		"revision": revision,
+		"content":  content,
+	})
+}
+
+func (h *FileHandler) RestoreRevision(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	revision, err := c.ParamsInt("rev")
+	if err != nil || revision <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid revision"})
+	}
+
+	restored, err := h.fileUseCase.RestoreRevision(id, revision)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{"error": "error restoring revision"})
+	}
+
+	return c.JSON(restored)
+}
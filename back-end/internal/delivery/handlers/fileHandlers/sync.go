@@ -0,0 +1,25 @@
+package fileHandlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"myScalidraw/internal/domain/useCase/file"
+	"myScalidraw/pkg/projectError"
+)
+
+// Sync devolve as alterações desde o cursor em "since". Sem cursor devolve a
+// árvore inteira; o cursor da resposta vai na próxima chamada.
+func (h *FileHandler) Sync(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", file.DefaultSyncLimit)
+
+	result, err := h.fileUseCase.Sync(c.Query("since"), limit)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error syncing files",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(result)
+}
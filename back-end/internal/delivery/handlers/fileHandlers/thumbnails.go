@@ -0,0 +1,40 @@
+package fileHandlers
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/gofiber/fiber/v2"
+
+	"myScalidraw/pkg/projectError"
+)
+
+var digestPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// GetThumbnail serve a miniatura pelo digest do conteúdo. Como a URL muda
+// sempre que o conteúdo muda, a resposta pode ficar em cache indefinidamente.
+func (h *FileHandler) GetThumbnail(c *fiber.Ctx) error {
+	digest := c.Params("digest")
+	if !digestPattern.MatchString(digest) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "thumbnail not found"})
+	}
+
+	etag := `"` + digest + `"`
+	c.Set(fiber.HeaderCacheControl, "public, max-age=31536000, immutable")
+	c.Set(fiber.HeaderETag, etag)
+	if c.Get(fiber.HeaderIfNoneMatch) == etag {
+		return c.SendStatus(http.StatusNotModified)
+	}
+
+	thumbnail, err := h.fileUseCase.GetThumbnail(digest)
+	if err != nil {
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error loading thumbnail",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "image/png")
+	return c.Send(thumbnail)
+}
@@ -0,0 +1,51 @@
+package fileHandlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"myScalidraw/internal/domain/models"
+)
+
+type TrashItem struct {
+	models.FileItem
+	DeletedAt int64 `json:"deletedAt"`
+}
+
+func (h *FileHandler) GetTrash(c *fiber.Ctx) error {
+	trash, err := h.fileUseCase.GetTrash()
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{"error": "error fetching trash"})
+	}
+
+	items := make([]TrashItem, 0, len(trash))
+	for _, metadata := range trash {
+		items = append(items, TrashItem{
+			FileItem:  metadata.ToFileItem(),
+			DeletedAt: metadata.DeletedAt.Time.Unix() * 1000,
+		})
+	}
+
+	return c.JSON(items)
+}
+
+func (h *FileHandler) RestoreFromTrash(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	metadata, err := h.fileUseCase.RestoreFromTrash(id)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{"error": "error restoring file"})
+	}
+
+	return c.JSON(metadata.ToFileItem())
+}
+
+func (h *FileHandler) PurgeFromTrash(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	err := h.fileUseCase.PurgeFromTrash(id)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{"error": "error purging file"})
+	}
+
+	return c.JSON(fiber.Map{"message": "file permanently deleted"})
+}
@@ -0,0 +1,28 @@
+package fileHandlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"myScalidraw/pkg/projectError"
+)
+
+// GetTree devolve os itens aninhados abaixo de "root" (padrão: raiz). "depth"
+// limita quantos níveis vêm expandidos; sem ele a árvore vem inteira.
+func (h *FileHandler) GetTree(c *fiber.Ctx) error {
+	depth := c.QueryInt("depth", 0)
+	if depth < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid depth"})
+	}
+
+	tree, err := h.fileUseCase.GetTree(c.Query("root"), depth)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error":   "error loading tree",
+			"details": projectError.ErrorMessage(err),
+		})
+	}
+
+	return c.JSON(tree)
+}
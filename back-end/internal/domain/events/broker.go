@@ -0,0 +1,97 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"myScalidraw/internal/domain/models"
+)
+
+const (
+	FileCreated = "created"
+	FileSaved   = "saved"
+	FileRenamed = "renamed"
+	FileMoved   = "moved"
+	FileDeleted = "deleted"
+)
+
+const subscriberBuffer = 64
+
+type Event struct {
+	ID        uint64           `json:"id"`
+	Type      string           `json:"type"`
+	Item      *models.FileItem `json:"item"`
+	Timestamp int64            `json:"timestamp"`
+}
+
+// Broker distribui os eventos para todos os inscritos. Quem não consome a
+// tempo perde eventos em vez de atrasar quem publicou.
+type Broker struct {
+	mu          sync.Mutex
+	sequence    uint64
+	subscribers map[chan Event]struct{}
+	closed      bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: map[chan Event]struct{}{},
+	}
+}
+
+func (b *Broker) Publish(eventType string, item *models.FileItem) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sequence++
+	event := Event{
+		ID:        b.sequence,
+		Type:      eventType,
+		Item:      item,
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe devolve o canal de eventos e a função que cancela a inscrição.
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	subscriber := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(subscriber)
+		return subscriber, func() {}
+	}
+	b.subscribers[subscriber] = struct{}{}
+
+	return subscriber, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[subscriber]; ok {
+			delete(b.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// Close encerra todas as inscrições. As conexões que acompanham os eventos
+// terminam e deixam o servidor desligar.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for subscriber := range b.subscribers {
+		delete(b.subscribers, subscriber)
+		close(subscriber)
+	}
+}
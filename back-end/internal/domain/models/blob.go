@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+type Blob struct {
+	Digest    string    `json:"digest" gorm:"primaryKey"`
+	Size      int64     `json:"size"`
+	RefCount  int64     `json:"refCount"`
+	Encoding  string    `json:"encoding,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type BlobList []*Blob
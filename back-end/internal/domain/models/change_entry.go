@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// ChangeEntry marca que os metadados de um arquivo mudaram. O journal não
+// guarda o estado: quem sincroniza lê a versão atual do item, ou um tombstone
+// se ele não existir mais.
+type ChangeEntry struct {
+	Sequence  uint64    `json:"sequence" gorm:"primaryKey;autoIncrement"`
+	FileID    string    `json:"fileId" gorm:"index"`
+	CreatedAt time.Time `json:"createdAt" gorm:"index"`
+}
+
+type ChangeEntryList []*ChangeEntry
+
+type SyncChange struct {
+	ID      string    `json:"id"`
+	Deleted bool      `json:"deleted,omitempty"`
+	Item    *FileItem `json:"item,omitempty"`
+}
+
+// SyncResult é a resposta do /api/sync. Com Reset o cliente deve descartar a
+// cópia local e ficar só com os itens de Changes.
+type SyncResult struct {
+	Cursor  string       `json:"cursor"`
+	Reset   bool         `json:"reset"`
+	HasMore bool         `json:"hasMore"`
+	Changes []SyncChange `json:"changes"`
+}
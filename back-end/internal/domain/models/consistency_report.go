@@ -0,0 +1,27 @@
+package models
+
+const (
+	IssueMissingObject        = "missing_object"
+	IssueOrphanedObject       = "orphaned_object"
+	IssueOrphanedFolderMarker = "orphaned_folder_marker"
+	IssueDanglingParent       = "dangling_parent"
+)
+
+type ConsistencyIssue struct {
+	Kind     string `json:"kind"`
+	Key      string `json:"key,omitempty"`
+	FileID   string `json:"fileId,omitempty"`
+	Detail   string `json:"detail"`
+	Repaired bool   `json:"repaired"`
+}
+
+type ConsistencyReport struct {
+	MissingObjects        []ConsistencyIssue `json:"missingObjects"`
+	OrphanedObjects       []ConsistencyIssue `json:"orphanedObjects"`
+	OrphanedFolderMarkers []ConsistencyIssue `json:"orphanedFolderMarkers"`
+	DanglingParents       []ConsistencyIssue `json:"danglingParents"`
+}
+
+func (r *ConsistencyReport) IssueCount() int {
+	return len(r.MissingObjects) + len(r.OrphanedObjects) + len(r.OrphanedFolderMarkers) + len(r.DanglingParents)
+}
@@ -5,9 +5,12 @@ type FileItem struct {
 	Name         string      `json:"name"`
 	IsFolder     bool        `json:"isFolder"`
 	Children     []FileItem  `json:"children,omitempty"`
+	HasChildren  bool        `json:"hasChildren,omitempty"`
 	Data         interface{} `json:"data,omitempty"`
 	LastModified int64       `json:"lastModified,omitempty"`
 	ParentID     string      `json:"parentId,omitempty"`
 	IsExpanded   bool        `json:"isExpanded,omitempty"`
 	Path         string      `json:"path,omitempty"`
+	Revision     int         `json:"revision,omitempty"`
+	ThumbnailURL string      `json:"thumbnailUrl,omitempty"`
 }
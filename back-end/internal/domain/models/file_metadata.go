@@ -1,6 +1,7 @@
 package models
 
 import (
+	"sort"
 	"strings"
 	"time"
 
@@ -8,79 +9,136 @@ import (
 )
 
 type FileMetadata struct {
-	ID           string         `json:"id" gorm:"primaryKey"`
-	Name         string         `json:"name"`
-	IsFolder     bool           `json:"isFolder"`
-	ParentID     string         `json:"parentId"`
-	StoragePath  string         `json:"storagePath"`
-	Path         string         `json:"path"`
-	ContentType  string         `json:"contentType"`
-	Size         int64          `json:"size"`
-	LastModified time.Time      `json:"lastModified"`
-	CreatedAt    time.Time      `json:"createdAt"`
-	UpdatedAt    time.Time      `json:"updatedAt"`
-	DeletedAt    gorm.DeletedAt `json:"deletedAt" gorm:"index"`
+	ID              string         `json:"id" gorm:"primaryKey"`
+	Name            string         `json:"name"`
+	IsFolder        bool           `json:"isFolder"`
+	ParentID        string         `json:"parentId"`
+	StoragePath     string         `json:"storagePath"`
+	Path            string         `json:"path" gorm:"uniqueIndex:idx_file_metadata_active_path,where:deleted_at IS NULL"`
+	ContentType     string         `json:"contentType"`
+	Size            int64          `json:"size"`
+	Revision        int            `json:"revision"`
+	ContentDigest   string         `json:"contentDigest,omitempty" gorm:"index"`
+	ContentEncoding string         `json:"contentEncoding,omitempty"`
+	LastModified    time.Time      `json:"lastModified"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	UpdatedAt       time.Time      `json:"updatedAt"`
+	DeletedAt       gorm.DeletedAt `json:"deletedAt" gorm:"index"`
+	TrashRootID     string         `json:"trashRootId,omitempty" gorm:"index"`
+
+	// HasChildren só é preenchido pelo GetSubtree.
+	HasChildren bool `json:"-" gorm:"->;-:migration"`
 }
 
 func (fm *FileMetadata) ToFileItem() FileItem {
-	return FileItem{
+	item := FileItem{
 		ID:           fm.ID,
 		Name:         fm.Name,
 		IsFolder:     fm.IsFolder,
 		ParentID:     fm.ParentID,
 		LastModified: fm.LastModified.Unix() * 1000,
 		Path:         fm.Path,
+		Revision:     fm.Revision,
 	}
+	if !fm.IsFolder && fm.ContentDigest != "" {
+		item.ThumbnailURL = ThumbnailURL(fm.ContentDigest)
+	}
+	return item
+}
+
+// ThumbnailURL aponta para a miniatura do conteúdo. A URL muda junto com o
+// conteúdo, então pode ficar em cache para sempre.
+func ThumbnailURL(digest string) string {
+	return "/api/thumbnails/" + digest + ".png"
 }
 
 type FileMetadataList []*FileMetadata
 
 func (list FileMetadataList) ToFileSystem() []FileItem {
+	return list.ToTree("", 0)
+}
 
-	itemMap := make(map[string]*FileItem)
-	metadataMap := make(map[string]*FileMetadata)
+// ToTree monta a árvore abaixo de rootID ("" para a raiz) com no máximo depth
+// níveis; depth 0 não tem limite. Pastas no limite vêm sem Children, só com
+// HasChildren, para serem expandidas depois. Itens cujo pai não está na lista
+// ficam na raiz, a não ser que o pai seja o próprio rootID.
+func (list FileMetadataList) ToTree(rootID string, depth int) []FileItem {
+	known := make(map[string]bool, len(list))
+	for _, metadata := range list {
+		if metadata != nil {
+			known[metadata.ID] = true
+		}
+	}
 
-	// Primeiro passo: converter todos os metadados para FileItem
+	children := make(map[string]FileMetadataList)
 	for _, metadata := range list {
 		if metadata == nil {
-
 			continue
 		}
 
-		item := metadata.ToFileItem()
-
-		// Normalizar path se estiver com problema
-		if strings.Contains(item.Path, "//") {
-			item.Path = strings.ReplaceAll(item.Path, "//", "/")
-
+		parentID := metadata.ParentID
+		if parentID != rootID && (parentID == "null" || !known[parentID]) {
+			parentID = ""
 		}
+		children[parentID] = append(children[parentID], metadata)
+	}
 
-		itemMap[item.ID] = &item
-		metadataMap[metadata.ID] = metadata
-
+	for _, siblings := range children {
+		siblings.sortForTree()
 	}
 
-	var rootItems []FileItem
+	// visited evita laço infinito se algum parentId formar um ciclo.
+	visited := map[string]bool{}
 
-	for _, item := range itemMap {
-		if item.ParentID == "" || item.ParentID == "null" {
+	var build func(parentID string, level int) []FileItem
+	build = func(parentID string, level int) []FileItem {
+		items := make([]FileItem, 0, len(children[parentID]))
 
-			rootItems = append(rootItems, *item)
-		} else {
-			if parent, ok := itemMap[item.ParentID]; ok {
-				if parent.Children == nil {
-					parent.Children = []FileItem{}
-				}
-				parent.Children = append(parent.Children, *item)
+		for _, metadata := range children[parentID] {
+			if visited[metadata.ID] {
+				continue
+			}
+			visited[metadata.ID] = true
 
-			} else {
+			item := metadata.ToFileItem()
 
-				rootItems = append(rootItems, *item)
+			// Normalizar path se estiver com problema
+			if strings.Contains(item.Path, "//") {
+				item.Path = strings.ReplaceAll(item.Path, "//", "/")
 			}
+
+			item.HasChildren = len(children[metadata.ID]) > 0 || metadata.HasChildren
+			if item.HasChildren && (depth == 0 || level < depth) {
+				item.Children = build(metadata.ID, level+1)
+			}
+
+			items = append(items, item)
 		}
+
+		return items
 	}
 
-	return rootItems
+	return build(rootID, 1)
+}
+
+// sortForTree ordena pastas antes de arquivos e depois pelo nome. O ID desempata
+// nomes iguais para a ordem não variar entre chamadas.
+func (list FileMetadataList) sortForTree() {
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.IsFolder != b.IsFolder {
+			return a.IsFolder
+		}
+
+		nameA, nameB := strings.ToLower(a.Name), strings.ToLower(b.Name)
+		if nameA != nameB {
+			return nameA < nameB
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
 }
 
 func (list FileMetadataList) ToFlatList() []FileItem {
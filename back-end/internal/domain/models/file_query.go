@@ -0,0 +1,120 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+const (
+	SortByName         = "name"
+	SortByLastModified = "lastModified"
+	SortBySize         = "size"
+)
+
+// FileQuery descreve uma página da listagem. Filtros nil não restringem;
+// ParentID apontando para "" lista a raiz. Limit 0 usa o tamanho de página
+// padrão do caso de uso.
+type FileQuery struct {
+	ParentID      *string
+	IsFolder      *bool
+	ModifiedSince *time.Time
+	NamePrefix    string
+	SortBy        string
+	Descending    bool
+	Limit         int
+	After         *FileCursor
+}
+
+// FilterHash resume os filtros da consulta, para que um cursor não seja usado
+// com filtros diferentes daqueles da página que o gerou.
+func (q *FileQuery) FilterHash() string {
+	filters := struct {
+		ParentID      *string    `json:"p,omitempty"`
+		IsFolder      *bool      `json:"f,omitempty"`
+		ModifiedSince *time.Time `json:"m,omitempty"`
+		NamePrefix    string     `json:"n,omitempty"`
+	}{q.ParentID, q.IsFolder, nil, q.NamePrefix}
+	if q.ModifiedSince != nil {
+		since := q.ModifiedSince.UTC()
+		filters.ModifiedSince = &since
+	}
+
+	content, _ := json.Marshal(filters)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:8])
+}
+
+// FileCursor aponta para o último item de uma página: o valor da coluna de
+// ordenação e o ID, que desempata itens com o mesmo valor. A direção e o
+// resumo dos filtros vão junto e precisam bater com a consulta seguinte.
+type FileCursor struct {
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	Filter     string `json:"f"`
+	Value      string `json:"v"`
+	ID         string `json:"id"`
+}
+
+func NewFileCursor(query *FileQuery, metadata *FileMetadata) *FileCursor {
+	cursor := &FileCursor{
+		SortBy:     query.SortBy,
+		Descending: query.Descending,
+		Filter:     query.FilterHash(),
+		ID:         metadata.ID,
+	}
+
+	switch query.SortBy {
+	case SortByLastModified:
+		cursor.Value = metadata.LastModified.UTC().Format(time.RFC3339Nano)
+	case SortBySize:
+		cursor.Value = strconv.FormatInt(metadata.Size, 10)
+	default:
+		cursor.Value = metadata.Name
+	}
+
+	return cursor
+}
+
+func (c *FileCursor) Encode() string {
+	content, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(content)
+}
+
+func DecodeFileCursor(value string) (*FileCursor, error) {
+	content, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor FileCursor
+	if err := json.Unmarshal(content, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.ID == "" {
+		return nil, errors.New("cursor without id")
+	}
+	return &cursor, nil
+}
+
+// Matches diz se o cursor foi gerado por uma consulta com a mesma ordenação,
+// direção e filtros de query.
+func (c *FileCursor) Matches(query *FileQuery) bool {
+	return c.SortBy == query.SortBy && c.Descending == query.Descending && c.Filter == query.FilterHash()
+}
+
+// SortValue converte o valor do cursor para o tipo da coluna de ordenação.
+func (c *FileCursor) SortValue() (interface{}, error) {
+	switch c.SortBy {
+	case SortByLastModified:
+		return time.Parse(time.RFC3339Nano, c.Value)
+	case SortBySize:
+		return strconv.ParseInt(c.Value, 10, 64)
+	default:
+		return c.Value, nil
+	}
+}
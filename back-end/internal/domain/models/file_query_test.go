@@ -0,0 +1,151 @@
+package models
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileCursorRoundTrip(t *testing.T) {
+	modified := time.Date(2024, 6, 10, 12, 30, 45, 123456789, time.FixedZone("BRT", -3*60*60))
+	metadata := &FileMetadata{ID: "f-1", Name: "Diagrama – v2 %_.excalidraw", Size: 4096, LastModified: modified}
+
+	tests := []struct {
+		sortBy string
+		want   interface{}
+	}{
+		{SortByName, metadata.Name},
+		{SortBySize, int64(4096)},
+		{SortByLastModified, modified.UTC()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.sortBy, func(t *testing.T) {
+			cursor := NewFileCursor(&FileQuery{SortBy: test.sortBy}, metadata)
+
+			decoded, err := DecodeFileCursor(cursor.Encode())
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, cursor) {
+				t.Errorf("decoded = %+v, want %+v", decoded, cursor)
+			}
+
+			value, err := decoded.SortValue()
+			if err != nil {
+				t.Fatalf("sort value: %v", err)
+			}
+			if modifiedValue, ok := value.(time.Time); ok {
+				if !modifiedValue.Equal(modified) {
+					t.Errorf("sort value = %v, want %v", modifiedValue, modified)
+				}
+				return
+			}
+			if value != test.want {
+				t.Errorf("sort value = %#v, want %#v", value, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeFileCursorInvalid(t *testing.T) {
+	encode := func(content string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(content))
+	}
+
+	tests := map[string]string{
+		"empty":            "",
+		"not base64":       "not a cursor!",
+		"padded base64":    base64.URLEncoding.EncodeToString([]byte(`{"s":"name","v":"a","id":"x"}`)),
+		"not JSON":         encode("name:a:x"),
+		"JSON array":       encode(`["name","a","x"]`),
+		"wrong field type": encode(`{"s":"name","v":1,"id":"x"}`),
+		"missing id":       encode(`{"s":"name","v":"a"}`),
+	}
+
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			if cursor, err := DecodeFileCursor(value); err == nil {
+				t.Errorf("cursor %q decoded to %+v", value, cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeFileCursorTampered(t *testing.T) {
+	encoded := NewFileCursor(&FileQuery{SortBy: SortByName}, &FileMetadata{ID: "f-1", Name: "a"}).Encode()
+
+	tampered := encoded[:len(encoded)/2] + "*" + encoded[len(encoded)/2:]
+	if _, err := DecodeFileCursor(tampered); err == nil {
+		t.Errorf("tampered cursor %q accepted", tampered)
+	}
+
+	if _, err := DecodeFileCursor(encoded[:len(encoded)-3]); err == nil {
+		t.Error("truncated cursor accepted")
+	}
+}
+
+func TestFileCursorSortValueInvalid(t *testing.T) {
+	tests := []*FileCursor{
+		{SortBy: SortBySize, Value: "12kb", ID: "x"},
+		{SortBy: SortByLastModified, Value: "yesterday", ID: "x"},
+	}
+
+	for _, cursor := range tests {
+		if value, err := cursor.SortValue(); err == nil {
+			t.Errorf("cursor %+v gave sort value %v", cursor, value)
+		}
+	}
+}
+
+func TestFileCursorEncodeIsURLSafe(t *testing.T) {
+	encoded := NewFileCursor(&FileQuery{SortBy: SortByName}, &FileMetadata{ID: "f-1", Name: "??>>~~"}).Encode()
+	if strings.ContainsAny(encoded, "+/=") {
+		t.Errorf("cursor %q is not URL safe", encoded)
+	}
+}
+
+func TestFileCursorMatches(t *testing.T) {
+	root, other := "", "folder"
+	folders := true
+	since := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+	base := FileQuery{SortBy: SortByName, ParentID: &root, IsFolder: &folders, ModifiedSince: &since, NamePrefix: "a"}
+	encoded := NewFileCursor(&base, &FileMetadata{ID: "f-1", Name: "a"}).Encode()
+
+	cursor, err := DecodeFileCursor(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// O mesmo instante em outro fuso é o mesmo filtro.
+	sameSince := since.In(time.FixedZone("BRT", -3*60*60))
+	same := base
+	same.ModifiedSince = &sameSince
+	same.Limit = 10
+	if !cursor.Matches(&same) {
+		t.Error("cursor rejected by the query that created it")
+	}
+
+	noFolders := false
+	later := since.Add(time.Hour)
+	tests := map[string]func(q *FileQuery){
+		"sort":           func(q *FileQuery) { q.SortBy = SortBySize },
+		"order":          func(q *FileQuery) { q.Descending = true },
+		"parent":         func(q *FileQuery) { q.ParentID = &other },
+		"no parent":      func(q *FileQuery) { q.ParentID = nil },
+		"is folder":      func(q *FileQuery) { q.IsFolder = &noFolders },
+		"modified since": func(q *FileQuery) { q.ModifiedSince = &later },
+		"prefix":         func(q *FileQuery) { q.NamePrefix = "b" },
+	}
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			query := base
+			change(&query)
+			if cursor.Matches(&query) {
+				t.Errorf("cursor accepted with a different %s", name)
+			}
+		})
+	}
+}
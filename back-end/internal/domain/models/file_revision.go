@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+type FileRevision struct {
+	ID           string    `json:"id" gorm:"primaryKey"`
+	FileID       string    `json:"fileId" gorm:"uniqueIndex:idx_file_revisions_file_revision"`
+	Revision     int       `json:"revision" gorm:"uniqueIndex:idx_file_revisions_file_revision"`
+	StoragePath  string    `json:"-"`
+	Digest       string    `json:"digest,omitempty" gorm:"index"`
+	Size         int64     `json:"size"`
+	RestoredFrom int       `json:"restoredFrom,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type FileRevisionList []*FileRevision
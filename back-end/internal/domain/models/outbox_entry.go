@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	OutboxPromote      = "promote"
+	OutboxDelete       = "delete"
+	OutboxCreateFolder = "create_folder"
+)
+
+// OutboxEntry registra, na mesma transação dos metadados, uma operação no
+// storage que ainda precisa ser concluída depois do commit.
+type OutboxEntry struct {
+	ID         string    `json:"id" gorm:"primaryKey"`
+	Operation  string    `json:"operation"`
+	FileID     string    `json:"fileId" gorm:"index"`
+	StagingKey string    `json:"stagingKey,omitempty"`
+	ObjectKey  string    `json:"objectKey" gorm:"index"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"index"`
+}
+
+type OutboxEntryList []*OutboxEntry
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// StagedObject reserva uma chave de staging. A reserva é confirmada antes do
+// upload, fora da transação que usa o objeto, e essa transação a mantém
+// travada até o commit, quando ela é trocada pela entrada do outbox. Uma
+// reserva que outra conexão consegue travar não tem mais dono.
+type StagedObject struct {
+	Key       string    `json:"key" gorm:"primaryKey"`
+	FileID    string    `json:"fileId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type StagedObjectList []*StagedObject
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"time"
+
+	"myScalidraw/internal/domain/models"
+)
+
+type BlobRepository interface {
+	GetAll() (models.BlobList, error)
+
+	GetByDigest(digest string) (*models.Blob, error)
+
+	GetByDigestForUpdate(digest string) (*models.Blob, error)
+
+	Acquire(blob *models.Blob) error
+
+	Release(digest string) error
+
+	GetUnreferenced(before time.Time) (models.BlobList, error)
+
+	DeleteUnreferenced(digest string) (bool, error)
+
+	UpdateEncoding(digest string, encoding string) error
+}
@@ -1,19 +1,59 @@
 package repository
 
 import (
+	"time"
+
 	"myScalidraw/internal/domain/models"
 )
 
 type FileMetadataRepository interface {
 	GetAll() (models.FileMetadataList, error)
 
+	GetAllIncludingTrash() (models.FileMetadataList, error)
+
+	List(query *models.FileQuery) (models.FileMetadataList, error)
+
+	GetSubtree(rootID string, depth int) (models.FileMetadataList, error)
+
 	GetByID(id string) (*models.FileMetadata, error)
 
+	GetByIDForUpdate(id string) (*models.FileMetadata, error)
+
 	GetByParentID(parentID string) (models.FileMetadataList, error)
 
+	GetByPath(path string) (*models.FileMetadata, error)
+
+	GetByIDsIncludingTrash(ids []string) (models.FileMetadataList, error)
+
 	Create(metadata *models.FileMetadata) error
 
 	Update(metadata *models.FileMetadata) error
 
 	Delete(id string) error
+
+	MoveToTrash(ids []string, rootID string) error
+
+	GetTrash() (models.FileMetadataList, error)
+
+	GetTrashedByID(id string) (*models.FileMetadata, error)
+
+	GetTrashedByRootID(rootID string) (models.FileMetadataList, error)
+
+	GetTrashExpiredBefore(before time.Time) (models.FileMetadataList, error)
+
+	RestoreFromTrash(rootID string) error
+
+	Purge(id string) error
+
+	UpdateContentEncoding(id string, encoding string) error
+
+	UpdateContentEncodingByDigest(digest string, encoding string) error
+
+	GetChangesSince(sequence uint64, limit int) (models.ChangeEntryList, error)
+
+	GetSettledSequence(before time.Time) (uint64, error)
+
+	GetOldestSequence() (uint64, error)
+
+	PruneChangesBefore(before time.Time) (int64, error)
 }
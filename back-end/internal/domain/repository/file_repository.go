@@ -1,16 +1,36 @@
 package repository
 
 import (
+	"time"
+
 	"myScalidraw/internal/domain/models"
 )
 
 type FileRepository interface {
 	GetFileSystem() []models.FileItem
 	GetFileByID(id string) *models.FileItem
-	SaveFile(id string, content string) error
+	SaveFile(id string, content string) (*models.FileRevision, error)
+	SaveFileIfMatch(id string, content string, revision int) (*models.FileRevision, error)
+	CreateFile(metadata *models.FileMetadata, content []byte) error
 	GetFileContent(id string) (string, error)
 	UploadFile(id string, content []byte) error
 	CreateFolder(folderPath string) error
 	DeleteFile(id string) error
-	RenameFile(id string, newName string) error
+	RenameFile(id string, newName string) (models.FileMetadataList, error)
+	MoveFile(id string, parentID string) (models.FileMetadataList, error)
+	CopyFile(id string, parentID string, newName string) (models.FileMetadataList, error)
+	GetRevisions(id string) (models.FileRevisionList, error)
+	GetRevisionContent(id string, revision int) (string, error)
+	RestoreRevision(id string, revision int) (*models.FileRevision, error)
+	GetTrash() (models.FileMetadataList, error)
+	RestoreFromTrash(id string) (*models.FileMetadata, error)
+	PurgeFromTrash(id string) error
+	GetThumbnail(digest string) ([]byte, error)
+	HasThumbnail(digest string) (bool, error)
+	SaveThumbnail(digest string, content []byte) error
+	GetBlobContent(digest string) ([]byte, error)
+	CollectGarbage(before time.Time) (int, error)
+	CompressStoredObjects() (int, error)
+	CheckConsistency(repair bool) (*models.ConsistencyReport, error)
+	RecoverPendingOperations(before time.Time) (int, error)
 }
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"myScalidraw/internal/domain/models"
+)
+
+type FileRevisionRepository interface {
+	GetAll() (models.FileRevisionList, error)
+
+	GetByFileID(fileID string) (models.FileRevisionList, error)
+
+	GetByFileIDAndRevision(fileID string, revision int) (*models.FileRevision, error)
+
+	Create(revision *models.FileRevision) error
+
+	DeleteByFileID(fileID string) error
+}
@@ -0,0 +1,177 @@
+package impl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"myScalidraw/infra/storage"
+	"myScalidraw/internal/domain/models"
+
+	"gorm.io/gorm"
+)
+
+// O conteúdo das cenas é guardado uma única vez sob o seu SHA-256. Cada revisão
+// conta como uma referência ao blob; blobs sem referências são removidos pelo
+// CollectGarbage depois de um período de carência. Blobs novos passam pelo
+// staging e só chegam em blobs/ depois do commit da transação.
+//
+// A gravação trava a linha do blob até o fim da transação e o CollectGarbage
+// só apaga o objeto com a linha travada, antes de apagá-la. Assim uma gravação
+// que encontra o blob com referências sabe que o objeto vai continuar lá, e
+// uma que não encontra a linha, ou a encontra sem referências, grava o
+// conteúdo de novo em vez de apontar para um objeto que pode estar sumindo.
+
+func blobKey(digest string) string {
+	return "blobs/" + digest
+}
+
+func contentDigest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *FileRepositoryMinioImpl) putBlob(fileID string, content []byte) (*models.Blob, error) {
+	if len(content) == 0 {
+		return nil, fmt.Errorf("file content cannot be empty")
+	}
+
+	digest := contentDigest(content)
+
+	existing, err := r.blobRepo.GetByDigestForUpdate(digest)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("error checking blob %s: %w", digest, err)
+	}
+	if err == nil && existing.RefCount > 0 {
+		return &models.Blob{
+			Digest:   digest,
+			Size:     int64(len(content)),
+			Encoding: existing.Encoding,
+		}, nil
+	}
+
+	info, err := r.stageObject(fileID, blobKey(digest), content)
+	if err != nil {
+		return nil, fmt.Errorf("error storing blob %s: %w", digest, err)
+	}
+
+	return &models.Blob{
+		Digest:   digest,
+		Size:     int64(len(content)),
+		Encoding: info.Metadata[storage.MetaEncoding],
+	}, nil
+}
+
+func (r *FileRepositoryMinioImpl) getBlob(digest string) ([]byte, error) {
+	content, _, err := r.store.Get(blobKey(digest))
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		if staged, ok := r.readStaged(blobKey(digest)); ok {
+			return staged, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching blob %s: %w", digest, err)
+	}
+	return content, nil
+}
+
+// CompressStoredObjects comprime os objetos gravados antes da compressão
+// existir e atualiza o encoding registrado nos metadados.
+func (r *FileRepositoryMinioImpl) CompressStoredObjects() (int, error) {
+	compressor, ok := storage.As[storage.Compressor](r.store)
+	if !ok {
+		return 0, nil
+	}
+
+	objects, err := r.store.List("")
+	if err != nil {
+		return 0, fmt.Errorf("error listing objects: %w", err)
+	}
+
+	compressed := 0
+	for _, object := range objects {
+		info, changed, err := compressor.CompressInPlace(object.Key)
+		if err != nil {
+			log.Printf("Error compressing object %s: %v", object.Key, err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		compressed++
+
+		encoding := info.Metadata[storage.MetaEncoding]
+		if digest, isBlob := strings.CutPrefix(object.Key, "blobs/"); isBlob {
+			if err := r.blobRepo.UpdateEncoding(digest, encoding); err != nil {
+				return compressed, fmt.Errorf("error updating blob %s: %w", digest, err)
+			}
+			if err := r.metadataRepo.UpdateContentEncodingByDigest(digest, encoding); err != nil {
+				return compressed, fmt.Errorf("error updating metadata for blob %s: %w", digest, err)
+			}
+		} else if !strings.Contains(object.Key, "/") && strings.HasSuffix(object.Key, ".json") {
+			id := strings.TrimSuffix(object.Key, ".json")
+			if err := r.metadataRepo.UpdateContentEncoding(id, encoding); err != nil {
+				return compressed, fmt.Errorf("error updating metadata for %s: %w", id, err)
+			}
+		}
+	}
+
+	return compressed, nil
+}
+
+func (r *FileRepositoryMinioImpl) CollectGarbage(before time.Time) (int, error) {
+	blobs, err := r.blobRepo.GetUnreferenced(before)
+	if err != nil {
+		return 0, fmt.Errorf("error listing unreferenced blobs: %w", err)
+	}
+
+	collected := 0
+	for _, blob := range blobs {
+		deleted, err := r.collectBlob(blob.Digest, before)
+		if err != nil {
+			log.Printf("Error collecting blob %s: %v", blob.Digest, err)
+			continue
+		}
+		if !deleted {
+			continue
+		}
+
+		if err := r.store.Delete(thumbnailKey(blob.Digest)); err != nil {
+			log.Printf("Error deleting thumbnail of blob %s: %v", blob.Digest, err)
+		}
+		collected++
+	}
+
+	return collected, nil
+}
+
+// collectBlob apaga o objeto e depois a linha do blob numa transação que
+// mantém a linha travada, conferindo de novo que ele continua sem referências.
+// Se o objeto não puder ser apagado a linha fica para a próxima coleta.
+func (r *FileRepositoryMinioImpl) collectBlob(digest string, before time.Time) (bool, error) {
+	deleted := false
+	err := r.atomically(func(tx *FileRepositoryMinioImpl) error {
+		blob, err := tx.blobRepo.GetByDigestForUpdate(digest)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if blob.RefCount > 0 || !blob.UpdatedAt.Before(before) {
+			return nil
+		}
+
+		if err := tx.store.Delete(blobKey(digest)); err != nil {
+			return fmt.Errorf("error deleting blob object: %w", err)
+		}
+
+		deleted, err = tx.blobRepo.DeleteUnreferenced(digest)
+		return err
+	})
+	return deleted, err
+}
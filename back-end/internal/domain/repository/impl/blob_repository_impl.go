@@ -0,0 +1,99 @@
+package impl
+
+import (
+	"time"
+
+	"myScalidraw/infra/database"
+	"myScalidraw/internal/domain/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BlobRepositoryImpl struct {
+	db *database.DB
+}
+
+func NewBlobRepository(db *database.DB) *BlobRepositoryImpl {
+	return &BlobRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *BlobRepositoryImpl) GetAll() (models.BlobList, error) {
+	var blobs models.BlobList
+	result := r.db.Find(&blobs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return blobs, nil
+}
+
+func (r *BlobRepositoryImpl) GetByDigest(digest string) (*models.Blob, error) {
+	var blob models.Blob
+	result := r.db.First(&blob, "digest = ?", digest)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &blob, nil
+}
+
+func (r *BlobRepositoryImpl) GetByDigestForUpdate(digest string) (*models.Blob, error) {
+	var blob models.Blob
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&blob, "digest = ?", digest)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &blob, nil
+}
+
+func (r *BlobRepositoryImpl) Acquire(blob *models.Blob) error {
+	now := time.Now()
+	blob.RefCount = 1
+	blob.CreatedAt = now
+	blob.UpdatedAt = now
+
+	result := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "digest"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"ref_count":  gorm.Expr("blobs.ref_count + 1"),
+			"encoding":   gorm.Expr("excluded.encoding"),
+			"updated_at": now,
+		}),
+	}).Create(blob)
+	return result.Error
+}
+
+func (r *BlobRepositoryImpl) Release(digest string) error {
+	result := r.db.Model(&models.Blob{}).
+		Where("digest = ? AND ref_count > 0", digest).
+		Updates(map[string]interface{}{
+			"ref_count":  gorm.Expr("ref_count - 1"),
+			"updated_at": time.Now(),
+		})
+	return result.Error
+}
+
+func (r *BlobRepositoryImpl) UpdateEncoding(digest string, encoding string) error {
+	result := r.db.Model(&models.Blob{}).
+		Where("digest = ?", digest).
+		Update("encoding", encoding)
+	return result.Error
+}
+
+func (r *BlobRepositoryImpl) GetUnreferenced(before time.Time) (models.BlobList, error) {
+	var blobs models.BlobList
+	result := r.db.Find(&blobs, "ref_count <= 0 AND updated_at < ?", before)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return blobs, nil
+}
+
+func (r *BlobRepositoryImpl) DeleteUnreferenced(digest string) (bool, error) {
+	result := r.db.Delete(&models.Blob{}, "digest = ? AND ref_count <= 0", digest)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,237 @@
+package impl
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"myScalidraw/infra/storage"
+	"myScalidraw/internal/domain/models"
+)
+
+// objectGracePeriod evita que o fsck trate como órfão um objeto que acabou de
+// ser gravado e cujo registro no banco ainda não foi criado.
+const objectGracePeriod = time.Hour
+
+// CheckConsistency compara os metadados do Postgres com os objetos do storage.
+// Com repair, os órfãos são apagados, itens com pai inexistente voltam para a
+// raiz e arquivos sem conteúdo são restaurados da última revisão disponível
+// ou, se nenhuma existir, movidos para a lixeira.
+func (r *FileRepositoryMinioImpl) CheckConsistency(repair bool) (*models.ConsistencyReport, error) {
+	report := &models.ConsistencyReport{}
+
+	objects, err := r.store.List("")
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects: %w", err)
+	}
+
+	existing := make(map[string]storage.ObjectInfo, len(objects))
+	for _, object := range objects {
+		existing[object.Key] = object
+	}
+
+	metadata, err := r.metadataRepo.GetAllIncludingTrash()
+	if err != nil {
+		return nil, fmt.Errorf("error listing metadata: %w", err)
+	}
+
+	revisions, err := r.revisionRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("error listing revisions: %w", err)
+	}
+
+	blobs, err := r.blobRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("error listing blobs: %w", err)
+	}
+
+	revisionsByFile := map[string]models.FileRevisionList{}
+	for _, revision := range revisions {
+		revisionsByFile[revision.FileID] = append(revisionsByFile[revision.FileID], revision)
+	}
+	for _, fileRevisions := range revisionsByFile {
+		sort.Slice(fileRevisions, func(i, j int) bool {
+			return fileRevisions[i].Revision > fileRevisions[j].Revision
+		})
+	}
+
+	// O desenho de exemplo pode existir sem metadados; não é órfão.
+	referenced := map[string]bool{objectKey(sampleFileID): true}
+	markers := map[string]bool{}
+	active := map[string]*models.FileMetadata{}
+	// contentKeys são os objetos com o conteúdo atual de algum arquivo. A falta
+	// deles é reportada uma vez só, por arquivo, no laço que sabe repará-la.
+	contentKeys := map[string]bool{}
+
+	for _, item := range metadata {
+		if !item.DeletedAt.Valid {
+			active[item.ID] = item
+		}
+		if item.IsFolder {
+			markers[storage.FolderKey(item.Path)] = true
+			continue
+		}
+		// O <id>.json antigo só continua em uso enquanto o arquivo não tem blob.
+		if item.ContentDigest == "" {
+			referenced[objectKey(item.ID)] = true
+			contentKeys[objectKey(item.ID)] = true
+		} else {
+			contentKeys[blobKey(item.ContentDigest)] = true
+		}
+	}
+
+	for _, blob := range blobs {
+		referenced[blobKey(blob.Digest)] = true
+		// A miniatura é opcional; só vira órfã quando o blob deixa de existir.
+		referenced[thumbnailKey(blob.Digest)] = true
+		if _, ok := existing[blobKey(blob.Digest)]; !ok && !contentKeys[blobKey(blob.Digest)] {
+			report.MissingObjects = append(report.MissingObjects, models.ConsistencyIssue{
+				Kind:   models.IssueMissingObject,
+				Key:    blobKey(blob.Digest),
+				Detail: fmt.Sprintf("blob with %d references has no object", blob.RefCount),
+			})
+		}
+	}
+
+	for _, revision := range revisions {
+		if revision.Digest != "" {
+			continue
+		}
+		key := objectKey(revision.StoragePath)
+		referenced[key] = true
+		if _, ok := existing[key]; !ok && !contentKeys[key] {
+			report.MissingObjects = append(report.MissingObjects, models.ConsistencyIssue{
+				Kind:   models.IssueMissingObject,
+				Key:    key,
+				FileID: revision.FileID,
+				Detail: fmt.Sprintf("revision %d has no object", revision.Revision),
+			})
+		}
+	}
+
+	for _, item := range metadata {
+		if item.IsFolder {
+			continue
+		}
+
+		key := objectKey(item.ID)
+		if item.ContentDigest != "" {
+			key = blobKey(item.ContentDigest)
+		}
+		if _, ok := existing[key]; ok {
+			continue
+		}
+
+		issue := models.ConsistencyIssue{
+			Kind:   models.IssueMissingObject,
+			Key:    key,
+			FileID: item.ID,
+			Detail: fmt.Sprintf("content of '%s' is missing", item.Path),
+		}
+		if repair {
+			issue.Repaired, issue.Detail = r.repairMissingContent(item, revisionsByFile[item.ID], existing, issue.Detail)
+		}
+		report.MissingObjects = append(report.MissingObjects, issue)
+	}
+
+	for _, item := range active {
+		if item.ParentID == "" {
+			continue
+		}
+		if _, ok := active[item.ParentID]; ok {
+			continue
+		}
+
+		issue := models.ConsistencyIssue{
+			Kind:   models.IssueDanglingParent,
+			FileID: item.ID,
+			Detail: fmt.Sprintf("parent %s of '%s' does not exist", item.ParentID, item.Path),
+		}
+		if repair {
+			err := r.atomically(func(tx *FileRepositoryMinioImpl) error {
+				item.ParentID = ""
+				return tx.updateChildPaths(item, "", &models.FileMetadataList{})
+			})
+			if err != nil {
+				log.Printf("Error moving %s to root: %v", item.ID, err)
+			} else {
+				issue.Repaired = true
+				issue.Detail += "; moved to root"
+			}
+		}
+		report.DanglingParents = append(report.DanglingParents, issue)
+	}
+
+	cutoff := time.Now().Add(-objectGracePeriod)
+	for _, object := range objects {
+		// Objetos em staging pertencem ao worker de recuperação.
+		if referenced[object.Key] || markers[object.Key] || isStagingKey(object.Key) {
+			continue
+		}
+
+		issue := models.ConsistencyIssue{Key: object.Key}
+		if strings.HasSuffix(object.Key, "/") {
+			issue.Kind = models.IssueOrphanedFolderMarker
+			issue.Detail = "folder marker without a matching folder"
+		} else {
+			issue.Kind = models.IssueOrphanedObject
+			issue.Detail = fmt.Sprintf("object of %d bytes is not referenced", object.Size)
+		}
+
+		if repair && object.LastModified.Before(cutoff) {
+			if err := r.store.Delete(object.Key); err != nil {
+				log.Printf("Error deleting orphaned object %s: %v", object.Key, err)
+			} else {
+				issue.Repaired = true
+			}
+		}
+
+		if issue.Kind == models.IssueOrphanedFolderMarker {
+			report.OrphanedFolderMarkers = append(report.OrphanedFolderMarkers, issue)
+		} else {
+			report.OrphanedObjects = append(report.OrphanedObjects, issue)
+		}
+	}
+
+	return report, nil
+}
+
+func (r *FileRepositoryMinioImpl) repairMissingContent(item *models.FileMetadata, revisions models.FileRevisionList, existing map[string]storage.ObjectInfo, detail string) (bool, string) {
+	// Itens na lixeira não podem ser atualizados; ficam só no relatório.
+	if item.DeletedAt.Valid {
+		return false, detail
+	}
+
+	for _, revision := range revisions {
+		if revision.Digest == "" || revision.Digest == item.ContentDigest {
+			continue
+		}
+		if _, ok := existing[blobKey(revision.Digest)]; !ok {
+			continue
+		}
+
+		blob, err := r.blobRepo.GetByDigest(revision.Digest)
+		if err != nil {
+			log.Printf("Error fetching blob %s: %v", revision.Digest, err)
+			continue
+		}
+
+		err = r.atomically(func(tx *FileRepositoryMinioImpl) error {
+			_, err := tx.addRevision(item, blob, revision.Revision)
+			return err
+		})
+		if err != nil {
+			log.Printf("Error restoring %s from revision %d: %v", item.ID, revision.Revision, err)
+			return false, detail
+		}
+		return true, fmt.Sprintf("%s; restored from revision %d", detail, revision.Revision)
+	}
+
+	if err := r.metadataRepo.MoveToTrash([]string{item.ID}, item.ID); err != nil {
+		log.Printf("Error moving %s to trash: %v", item.ID, err)
+		return false, detail
+	}
+	return true, detail + "; no usable revision, moved to trash"
+}
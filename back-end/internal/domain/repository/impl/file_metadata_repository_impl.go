@@ -1,8 +1,18 @@
 package impl
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"myScalidraw/infra/database"
 	"myScalidraw/internal/domain/models"
+	"myScalidraw/pkg/projectError"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type FileMetadataRepositoryImpl struct {
@@ -24,6 +34,120 @@ func (r *FileMetadataRepositoryImpl) GetAll() (models.FileMetadataList, error) {
 	return metadata, nil
 }
 
+func (r *FileMetadataRepositoryImpl) GetAllIncludingTrash() (models.FileMetadataList, error) {
+	var metadata models.FileMetadataList
+	result := r.db.Unscoped().Find(&metadata)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return metadata, nil
+}
+
+var sortColumns = map[string]string{
+	models.SortByName:         "name",
+	models.SortByLastModified: "last_modified",
+	models.SortBySize:         "size",
+}
+
+// List aplica filtros, ordenação e paginação por keyset no próprio SQL. A
+// página seguinte começa depois de (valor, id) do cursor, então inserções e
+// remoções entre as chamadas não duplicam nem pulam itens.
+func (r *FileMetadataRepositoryImpl) List(query *models.FileQuery) (models.FileMetadataList, error) {
+	column, ok := sortColumns[query.SortBy]
+	if !ok {
+		column = sortColumns[models.SortByName]
+	}
+
+	direction, comparison := "ASC", ">"
+	if query.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	db := r.db.Model(&models.FileMetadata{})
+
+	if query.ParentID != nil {
+		db = db.Where("parent_id = ?", *query.ParentID)
+	}
+	if query.IsFolder != nil {
+		db = db.Where("is_folder = ?", *query.IsFolder)
+	}
+	if query.ModifiedSince != nil {
+		db = db.Where("last_modified >= ?", *query.ModifiedSince)
+	}
+	if query.NamePrefix != "" {
+		db = db.Where(`LOWER(name) LIKE ? ESCAPE '\'`, escapeLike(strings.ToLower(query.NamePrefix))+"%")
+	}
+
+	if query.After != nil {
+		value, err := query.After.SortValue()
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(
+			fmt.Sprintf("%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?)", column, comparison),
+			value, value, query.After.ID,
+		)
+	}
+
+	db = db.Order(column + " " + direction).Order("id " + direction)
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	var metadata models.FileMetadataList
+	result := db.Find(&metadata)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return metadata, nil
+}
+
+// maxTreeDepth limita a recursão do GetSubtree quando depth é 0, para um
+// parentId em ciclo não prender a query.
+const maxTreeDepth = 64
+
+// GetSubtree devolve os itens até depth níveis abaixo de rootID ("" para a
+// raiz), sem carregar o resto da tabela. Na raiz entram também os itens cujo
+// pai não existe mais, como no ToTree. HasChildren vem de um EXISTS, então as
+// pastas no limite sabem se têm filhos sem que eles sejam lidos.
+func (r *FileMetadataRepositoryImpl) GetSubtree(rootID string, depth int) (models.FileMetadataList, error) {
+	if depth <= 0 || depth > maxTreeDepth {
+		depth = maxTreeDepth
+	}
+
+	start := "f.parent_id = @root"
+	if rootID == "" {
+		start = `(f.parent_id IN ('', 'null') OR NOT EXISTS (
+			SELECT 1 FROM file_metadata p WHERE p.id = f.parent_id AND p.deleted_at IS NULL))`
+	}
+
+	var metadata models.FileMetadataList
+	result := r.db.Raw(`
+		WITH RECURSIVE tree (id, level) AS (
+			SELECT f.id, 1 FROM file_metadata f
+			WHERE f.deleted_at IS NULL AND `+start+`
+			UNION ALL
+			SELECT c.id, tree.level + 1 FROM file_metadata c
+			JOIN tree ON c.parent_id = tree.id
+			WHERE c.deleted_at IS NULL AND tree.level < @depth
+		)
+		SELECT m.*, EXISTS (
+			SELECT 1 FROM file_metadata c WHERE c.parent_id = m.id AND c.deleted_at IS NULL
+		) AS has_children
+		FROM file_metadata m
+		WHERE m.deleted_at IS NULL AND m.id IN (SELECT id FROM tree)`,
+		sql.Named("root", rootID), sql.Named("depth", depth),
+	).Scan(&metadata)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return metadata, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
 func (r *FileMetadataRepositoryImpl) GetByID(id string) (*models.FileMetadata, error) {
 	var metadata models.FileMetadata
 	result := r.db.First(&metadata, "id = ?", id)
@@ -33,6 +157,16 @@ func (r *FileMetadataRepositoryImpl) GetByID(id string) (*models.FileMetadata, e
 	return &metadata, nil
 }
 
+// GetByIDForUpdate trava a linha até o fim da transação corrente.
+func (r *FileMetadataRepositoryImpl) GetByIDForUpdate(id string) (*models.FileMetadata, error) {
+	var metadata models.FileMetadata
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&metadata, "id = ?", id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &metadata, nil
+}
+
 func (r *FileMetadataRepositoryImpl) GetByParentID(parentID string) (models.FileMetadataList, error) {
 	var metadata models.FileMetadataList
 	result := r.db.Find(&metadata, "parent_id = ?", parentID)
@@ -42,17 +176,205 @@ func (r *FileMetadataRepositoryImpl) GetByParentID(parentID string) (models.File
 	return metadata, nil
 }
 
+// GetByPath devolve o item fora da lixeira no caminho. O índice único
+// idx_file_metadata_active_path garante que há no máximo um.
+func (r *FileMetadataRepositoryImpl) GetByPath(path string) (*models.FileMetadata, error) {
+	var metadata models.FileMetadata
+	result := r.db.Where("path = ?", path).First(&metadata)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &metadata, nil
+}
+
+func (r *FileMetadataRepositoryImpl) GetByIDsIncludingTrash(ids []string) (models.FileMetadataList, error) {
+	var metadata models.FileMetadataList
+	if len(ids) == 0 {
+		return metadata, nil
+	}
+
+	result := r.db.Unscoped().Find(&metadata, "id IN ?", ids)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return metadata, nil
+}
+
+// journaled executa write e registra uma entrada no journal para cada id na
+// mesma transação. Dentro de uma transação já aberta vira um savepoint.
+func (r *FileMetadataRepositoryImpl) journaled(ids []string, write func(db *gorm.DB) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := write(tx); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		now := time.Now()
+		entries := make(models.ChangeEntryList, 0, len(ids))
+		for _, id := range ids {
+			entries = append(entries, &models.ChangeEntry{FileID: id, CreatedAt: now})
+		}
+		return tx.Create(&entries).Error
+	})
+}
+
 func (r *FileMetadataRepositoryImpl) Create(metadata *models.FileMetadata) error {
-	result := r.db.Create(metadata)
-	return result.Error
+	return r.journaled([]string{metadata.ID}, func(db *gorm.DB) error {
+		return pathConflict(metadata, db.Create(metadata).Error)
+	})
 }
 
 func (r *FileMetadataRepositoryImpl) Update(metadata *models.FileMetadata) error {
-	result := r.db.Save(metadata)
-	return result.Error
+	return r.journaled([]string{metadata.ID}, func(db *gorm.DB) error {
+		return pathConflict(metadata, db.Save(metadata).Error)
+	})
+}
+
+// pathConflict traduz a violação do índice único de caminho. Os IDs são UUIDs
+// gerados pelo servidor, então uma chave duplicada só pode vir do caminho.
+func pathConflict(metadata *models.FileMetadata, err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return projectError.Errorf(projectError.ECONFLICT, "path already exists: %s", metadata.Path)
+	}
+	return err
 }
 
 func (r *FileMetadataRepositoryImpl) Delete(id string) error {
-	result := r.db.Unscoped().Delete(&models.FileMetadata{}, "id = ?", id)
+	return r.journaled([]string{id}, func(db *gorm.DB) error {
+		return db.Delete(&models.FileMetadata{}, "id = ?", id).Error
+	})
+}
+
+func (r *FileMetadataRepositoryImpl) MoveToTrash(ids []string, rootID string) error {
+	return r.journaled(ids, func(db *gorm.DB) error {
+		return db.Model(&models.FileMetadata{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"trash_root_id": rootID,
+				"deleted_at":    time.Now(),
+			}).Error
+	})
+}
+
+func (r *FileMetadataRepositoryImpl) GetTrash() (models.FileMetadataList, error) {
+	var metadata models.FileMetadataList
+	result := r.db.Unscoped().
+		Where("deleted_at IS NOT NULL AND trash_root_id = id").
+		Order("deleted_at desc").
+		Find(&metadata)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return metadata, nil
+}
+
+func (r *FileMetadataRepositoryImpl) GetTrashedByID(id string) (*models.FileMetadata, error) {
+	var metadata models.FileMetadata
+	result := r.db.Unscoped().First(&metadata, "id = ? AND deleted_at IS NOT NULL", id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &metadata, nil
+}
+
+func (r *FileMetadataRepositoryImpl) GetTrashedByRootID(rootID string) (models.FileMetadataList, error) {
+	var metadata models.FileMetadataList
+	result := r.db.Unscoped().Find(&metadata, "trash_root_id = ? AND deleted_at IS NOT NULL", rootID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return metadata, nil
+}
+
+func (r *FileMetadataRepositoryImpl) GetTrashExpiredBefore(before time.Time) (models.FileMetadataList, error) {
+	var metadata models.FileMetadataList
+	result := r.db.Unscoped().
+		Where("deleted_at IS NOT NULL AND trash_root_id = id AND deleted_at < ?", before).
+		Find(&metadata)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return metadata, nil
+}
+
+func (r *FileMetadataRepositoryImpl) RestoreFromTrash(rootID string) error {
+	var ids []string
+	result := r.db.Unscoped().Model(&models.FileMetadata{}).
+		Where("trash_root_id = ?", rootID).
+		Pluck("id", &ids)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return r.journaled(ids, func(db *gorm.DB) error {
+		err := db.Unscoped().Model(&models.FileMetadata{}).
+			Where("trash_root_id = ?", rootID).
+			Updates(map[string]interface{}{
+				"trash_root_id": "",
+				"deleted_at":    nil,
+			}).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return projectError.Errorf(projectError.ECONFLICT, "path of a restored item is already in use")
+		}
+		return err
+	})
+}
+
+func (r *FileMetadataRepositoryImpl) UpdateContentEncoding(id string, encoding string) error {
+	result := r.db.Unscoped().Model(&models.FileMetadata{}).
+		Where("id = ?", id).
+		UpdateColumn("content_encoding", encoding)
 	return result.Error
 }
+
+func (r *FileMetadataRepositoryImpl) UpdateContentEncodingByDigest(digest string, encoding string) error {
+	result := r.db.Unscoped().Model(&models.FileMetadata{}).
+		Where("content_digest = ?", digest).
+		UpdateColumn("content_encoding", encoding)
+	return result.Error
+}
+
+func (r *FileMetadataRepositoryImpl) Purge(id string) error {
+	return r.journaled([]string{id}, func(db *gorm.DB) error {
+		return db.Unscoped().Delete(&models.FileMetadata{}, "id = ?", id).Error
+	})
+}
+
+func (r *FileMetadataRepositoryImpl) GetChangesSince(sequence uint64, limit int) (models.ChangeEntryList, error) {
+	var entries models.ChangeEntryList
+	result := r.db.Where("sequence > ?", sequence).Order("sequence").Limit(limit).Find(&entries)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return entries, nil
+}
+
+// GetSettledSequence devolve a maior sequência gravada antes de before, ou 0
+// se não houver nenhuma.
+func (r *FileMetadataRepositoryImpl) GetSettledSequence(before time.Time) (uint64, error) {
+	var sequence uint64
+	result := r.db.Model(&models.ChangeEntry{}).
+		Where("created_at < ?", before).
+		Select("COALESCE(MAX(sequence), 0)").
+		Scan(&sequence)
+	return sequence, result.Error
+}
+
+func (r *FileMetadataRepositoryImpl) GetOldestSequence() (uint64, error) {
+	var sequence uint64
+	result := r.db.Model(&models.ChangeEntry{}).
+		Select("COALESCE(MIN(sequence), 0)").
+		Scan(&sequence)
+	return sequence, result.Error
+}
+
+// PruneChangesBefore apaga as entradas antigas do journal, mas sempre mantém a
+// mais recente para que cursores expirados continuem detectáveis.
+func (r *FileMetadataRepositoryImpl) PruneChangesBefore(before time.Time) (int64, error) {
+	result := r.db.
+		Where("created_at < ? AND sequence < (SELECT MAX(sequence) FROM change_entries)", before).
+		Delete(&models.ChangeEntry{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,325 @@
+package impl
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"myScalidraw/infra/database"
+	"myScalidraw/internal/domain/models"
+	"myScalidraw/pkg/projectError"
+)
+
+// newTestDB abre um SQLite em memória com o schema dos metadados e do feed de
+// mudanças que o Create alimenta. A query do List é SQL padrão, então o
+// comportamento é o mesmo do Postgres.
+func newTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.FileMetadata{}, &models.ChangeEntry{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return &database.DB{DB: db}
+}
+
+// createMetadata grava os itens; quem não tem caminho ganha um a partir do ID,
+// já que o caminho é único.
+func createMetadata(t *testing.T, repo *FileMetadataRepositoryImpl, items ...*models.FileMetadata) {
+	t.Helper()
+	for _, item := range items {
+		if item.Path == "" {
+			item.Path = "/" + item.ID
+		}
+		if err := repo.Create(item); err != nil {
+			t.Fatalf("create %s: %v", item.ID, err)
+		}
+	}
+}
+
+func metadataIDs(list models.FileMetadataList) []string {
+	ids := make([]string, len(list))
+	for i, item := range list {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+// listAll percorre as páginas como um cliente faria, seguindo o cursor.
+func listAll(t *testing.T, repo *FileMetadataRepositoryImpl, query models.FileQuery, pageSize int) []string {
+	t.Helper()
+	var ids []string
+	query.Limit = pageSize
+	for page := 0; page < 100; page++ {
+		list, err := repo.List(&query)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		ids = append(ids, metadataIDs(list)...)
+		if len(list) < pageSize {
+			return ids
+		}
+
+		cursor := models.NewFileCursor(&query, list[len(list)-1])
+		decoded, err := models.DecodeFileCursor(cursor.Encode())
+		if err != nil {
+			t.Fatalf("decode cursor: %v", err)
+		}
+		query.After = decoded
+	}
+	t.Fatal("pagination did not end")
+	return nil
+}
+
+func TestListPaginatesTies(t *testing.T) {
+	repo := NewFileMetadataRepository(newTestDB(t))
+	modified := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	createMetadata(t, repo,
+		&models.FileMetadata{ID: "e", Name: "same", Size: 10, LastModified: modified},
+		&models.FileMetadata{ID: "b", Name: "same", Size: 10, LastModified: modified},
+		&models.FileMetadata{ID: "d", Name: "other", Size: 20, LastModified: modified.Add(time.Hour)},
+		&models.FileMetadata{ID: "a", Name: "same", Size: 10, LastModified: modified},
+		&models.FileMetadata{ID: "c", Name: "other", Size: 5, LastModified: modified.Add(-time.Hour)},
+	)
+
+	tests := []struct {
+		name  string
+		query models.FileQuery
+		want  []string
+	}{
+		{"name", models.FileQuery{SortBy: models.SortByName}, []string{"c", "d", "a", "b", "e"}},
+		{"name descending", models.FileQuery{SortBy: models.SortByName, Descending: true}, []string{"e", "b", "a", "d", "c"}},
+		{"size", models.FileQuery{SortBy: models.SortBySize}, []string{"c", "a", "b", "e", "d"}},
+		{"size descending", models.FileQuery{SortBy: models.SortBySize, Descending: true}, []string{"d", "e", "b", "a", "c"}},
+		{"last modified", models.FileQuery{SortBy: models.SortByLastModified}, []string{"c", "a", "b", "e", "d"}},
+	}
+
+	for _, test := range tests {
+		for _, pageSize := range []int{1, 2, 5} {
+			t.Run(fmt.Sprintf("%s/page %d", test.name, pageSize), func(t *testing.T) {
+				if got := listAll(t, repo, test.query, pageSize); !reflect.DeepEqual(got, test.want) {
+					t.Errorf("ids = %v, want %v", got, test.want)
+				}
+			})
+		}
+	}
+}
+
+func TestListCursorSurvivesChanges(t *testing.T) {
+	repo := NewFileMetadataRepository(newTestDB(t))
+	createMetadata(t, repo,
+		&models.FileMetadata{ID: "a", Name: "a"},
+		&models.FileMetadata{ID: "b", Name: "b"},
+		&models.FileMetadata{ID: "c", Name: "c"},
+	)
+
+	query := &models.FileQuery{SortBy: models.SortByName, Limit: 2}
+	first, err := repo.List(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Um item inserido antes do cursor não desloca a página seguinte.
+	createMetadata(t, repo, &models.FileMetadata{ID: "0", Name: "0"})
+
+	query.After = models.NewFileCursor(query, first[len(first)-1])
+	second, err := repo.List(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := metadataIDs(second); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("second page = %v, want [c]", got)
+	}
+}
+
+func TestListNamePrefix(t *testing.T) {
+	repo := NewFileMetadataRepository(newTestDB(t))
+	createMetadata(t, repo,
+		&models.FileMetadata{ID: "1", Name: "50% off"},
+		&models.FileMetadata{ID: "2", Name: "500 items"},
+		&models.FileMetadata{ID: "3", Name: "a_b"},
+		&models.FileMetadata{ID: "4", Name: "axb"},
+		&models.FileMetadata{ID: "5", Name: "A_B maiúsculo"},
+		&models.FileMetadata{ID: "6", Name: `c:\temp`},
+		&models.FileMetadata{ID: "7", Name: "c:xtemp"},
+	)
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"50%", []string{"1"}},
+		{"50", []string{"1", "2"}},
+		{"a_", []string{"3", "5"}},
+		{"A_b", []string{"3", "5"}},
+		{"a%", []string{}},
+		{`c:\`, []string{"6"}},
+		{"%", []string{}},
+		{"_", []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.prefix, func(t *testing.T) {
+			list, err := repo.List(&models.FileQuery{SortBy: models.SortByName, NamePrefix: test.prefix})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Maiúsculas e minúsculas empatam de formas diferentes em cada
+			// collation; aqui só importa quem entra.
+			got := metadataIDs(list)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ids = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	repo := NewFileMetadataRepository(newTestDB(t))
+	since := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+	createMetadata(t, repo,
+		&models.FileMetadata{ID: "folder", Name: "folder", IsFolder: true, LastModified: since},
+		&models.FileMetadata{ID: "root", Name: "root", LastModified: since.Add(-time.Hour)},
+		&models.FileMetadata{ID: "child", Name: "child", ParentID: "folder", LastModified: since.Add(time.Hour)},
+		&models.FileMetadata{ID: "trashed", Name: "trashed"},
+	)
+	if err := repo.db.Delete(&models.FileMetadata{}, "id = ?", "trashed").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	root := ""
+	folders := true
+	tests := []struct {
+		name  string
+		query models.FileQuery
+		want  []string
+	}{
+		{"everything but the trash", models.FileQuery{}, []string{"child", "folder", "root"}},
+		{"root", models.FileQuery{ParentID: &root}, []string{"folder", "root"}},
+		{"folders", models.FileQuery{IsFolder: &folders}, []string{"folder"}},
+		{"modified since", models.FileQuery{ModifiedSince: &since}, []string{"child", "folder"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.query.SortBy = models.SortByName
+			list, err := repo.List(&test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := metadataIDs(list); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ids = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPathIsUniqueOutsideTrash(t *testing.T) {
+	repo := NewFileMetadataRepository(newTestDB(t))
+	createMetadata(t, repo, &models.FileMetadata{ID: "a", Name: "a", Path: "/a"})
+
+	err := repo.Create(&models.FileMetadata{ID: "b", Name: "a", Path: "/a"})
+	if projectError.ErrorCode(err) != projectError.ECONFLICT {
+		t.Fatalf("second item at /a: err = %v, want a conflict", err)
+	}
+
+	renamed := &models.FileMetadata{ID: "c", Name: "c", Path: "/c"}
+	createMetadata(t, repo, renamed)
+	renamed.Path = "/a"
+	if err := repo.Update(renamed); projectError.ErrorCode(err) != projectError.ECONFLICT {
+		t.Fatalf("rename onto /a: err = %v, want a conflict", err)
+	}
+
+	// Itens na lixeira não ocupam o caminho.
+	if err := repo.MoveToTrash([]string{"a"}, "a"); err != nil {
+		t.Fatal(err)
+	}
+	createMetadata(t, repo, &models.FileMetadata{ID: "d", Name: "a", Path: "/a"})
+
+	found, err := repo.GetByPath("/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ID != "d" {
+		t.Errorf("path resolved to %s, want d", found.ID)
+	}
+}
+
+func TestGetSubtree(t *testing.T) {
+	repo := NewFileMetadataRepository(newTestDB(t))
+	createMetadata(t, repo,
+		&models.FileMetadata{ID: "a", Name: "a", IsFolder: true},
+		&models.FileMetadata{ID: "b", Name: "b", IsFolder: true, ParentID: "a"},
+		&models.FileMetadata{ID: "c", Name: "c", ParentID: "b"},
+		&models.FileMetadata{ID: "empty", Name: "empty", IsFolder: true},
+		&models.FileMetadata{ID: "orphan", Name: "orphan", ParentID: "gone"},
+		&models.FileMetadata{ID: "trashed", Name: "trashed", IsFolder: true},
+		&models.FileMetadata{ID: "under-trash", Name: "under-trash", ParentID: "trashed"},
+		&models.FileMetadata{ID: "x", Name: "x", IsFolder: true, ParentID: "y"},
+		&models.FileMetadata{ID: "y", Name: "y", IsFolder: true, ParentID: "x"},
+	)
+	if err := repo.db.Delete(&models.FileMetadata{}, "id = ?", "trashed").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		rootID string
+		depth  int
+		want   []string
+	}{
+		{"root, one level", "", 1, []string{"a", "empty", "orphan", "under-trash"}},
+		{"root, everything", "", 0, []string{"a", "b", "c", "empty", "orphan", "under-trash"}},
+		{"folder, one level", "a", 1, []string{"b"}},
+		{"folder, everything", "a", 0, []string{"b", "c"}},
+		{"cycle", "x", 0, []string{"x", "y"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			list, err := repo.GetSubtree(test.rootID, test.depth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := metadataIDs(list)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ids = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	list, err := repo.GetSubtree("", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := list.ToTree("", 1)
+	hasChildren := map[string]bool{}
+	for _, item := range tree {
+		hasChildren[item.ID] = item.HasChildren
+		if item.Children != nil {
+			t.Errorf("%s expanded past the depth", item.ID)
+		}
+	}
+	if !hasChildren["a"] || hasChildren["empty"] {
+		t.Errorf("has children = %v, want only a", hasChildren)
+	}
+}
@@ -2,6 +2,7 @@ package impl
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -10,20 +11,38 @@ import (
 	"myScalidraw/infra/storage"
 	"myScalidraw/internal/domain/models"
 	"myScalidraw/internal/domain/repository"
+	"myScalidraw/pkg/projectError"
+	"myScalidraw/pkg/uuid"
+
+	"gorm.io/gorm"
 )
 
 type FileRepositoryMinioImpl struct {
 	fileSystem   []models.FileItem
-	minioClient  *storage.MinIO
+	store        storage.ObjectStore
 	metadataRepo repository.FileMetadataRepository
+	revisionRepo repository.FileRevisionRepository
+	blobRepo     repository.BlobRepository
+	outboxRepo   repository.OutboxRepository
 	db           *database.DB
+
+	// reservations fica sempre fora da transação: as reservas de staging
+	// precisam estar confirmadas antes do upload.
+	reservations repository.OutboxRepository
+
+	// pending guarda as operações de storage enfileiradas dentro de atomically.
+	pending models.OutboxEntryList
 }
 
-func NewFileRepositoryMinio(minioClient *storage.MinIO, db *database.DB, metadataRepo repository.FileMetadataRepository) *FileRepositoryMinioImpl {
+func NewFileRepositoryMinio(store storage.ObjectStore, db *database.DB, metadataRepo repository.FileMetadataRepository, revisionRepo repository.FileRevisionRepository, blobRepo repository.BlobRepository, outboxRepo repository.OutboxRepository) *FileRepositoryMinioImpl {
 	repo := &FileRepositoryMinioImpl{
-		minioClient:  minioClient,
+		store:        store,
 		metadataRepo: metadataRepo,
+		revisionRepo: revisionRepo,
+		blobRepo:     blobRepo,
+		outboxRepo:   outboxRepo,
 		db:           db,
+		reservations: outboxRepo,
 	}
 
 	repo.loadFileSystem()
@@ -119,43 +138,158 @@ func (r *FileRepositoryMinioImpl) buildItemPath(metadata *models.FileMetadata) s
 	return result
 }
 
-func (r *FileRepositoryMinioImpl) SaveFile(id string, content string) error {
-	metadata, err := r.metadataRepo.GetByID(id)
+func (r *FileRepositoryMinioImpl) SaveFile(id string, content string) (*models.FileRevision, error) {
+	return r.saveFile(id, content, nil)
+}
+
+// SaveFileIfMatch só grava se o arquivo ainda estiver na revisão informada.
+// A comparação acontece com a linha travada, então duas gravações baseadas na
+// mesma revisão nunca são aceitas juntas.
+func (r *FileRepositoryMinioImpl) SaveFileIfMatch(id string, content string, revision int) (*models.FileRevision, error) {
+	return r.saveFile(id, content, &revision)
+}
+
+func (r *FileRepositoryMinioImpl) saveFile(id string, content string, expectedRevision *int) (*models.FileRevision, error) {
+	var saved *models.FileRevision
+	err := r.atomically(func(tx *FileRepositoryMinioImpl) error {
+		metadata, err := tx.metadataRepo.GetByIDForUpdate(id)
+		if err != nil {
+			return projectError.Errorf(projectError.ENOTFOUND, "file not found: %s", id)
+		}
+
+		if expectedRevision != nil && metadata.Revision != *expectedRevision {
+			return projectError.Errorf(projectError.EPRECONDITION, "file %s is at revision %d, not %d", id, metadata.Revision, *expectedRevision)
+		}
+
+		saved, err = tx.saveContent(metadata, []byte(content), 0)
+		return err
+	})
+	return saved, err
+}
+
+// CreateFile grava os metadados, o conteúdo inicial e o marcador de pasta
+// como uma única operação.
+func (r *FileRepositoryMinioImpl) CreateFile(metadata *models.FileMetadata, content []byte) error {
+	return r.atomically(func(tx *FileRepositoryMinioImpl) error {
+		if err := tx.metadataRepo.Create(metadata); err != nil {
+			return fmt.Errorf("error creating metadata: %w", err)
+		}
+
+		if metadata.IsFolder {
+			return tx.enqueue(&models.OutboxEntry{
+				Operation: models.OutboxCreateFolder,
+				FileID:    metadata.ID,
+				ObjectKey: storage.FolderKey(metadata.Path),
+			})
+		}
+
+		if len(content) > 0 {
+			if _, err := tx.saveContent(metadata, content, 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+const sceneContentType = "application/json"
+
+func objectKey(id string) string {
+	return id + ".json"
+}
+
+func (r *FileRepositoryMinioImpl) putScene(id string, content []byte) error {
+	if len(content) == 0 {
+		return fmt.Errorf("file content cannot be empty")
+	}
+
+	_, err := r.store.Put(objectKey(id), content, storage.PutOptions{ContentType: sceneContentType})
+	return err
+}
+
+func (r *FileRepositoryMinioImpl) saveContent(metadata *models.FileMetadata, content []byte, restoredFrom int) (*models.FileRevision, error) {
+	blob, err := r.putBlob(metadata.ID, content)
 	if err != nil {
-		return fmt.Errorf("file not found: %s", id)
+		return nil, fmt.Errorf("error saving file to storage: %w", err)
+	}
+
+	return r.addRevision(metadata, blob, restoredFrom)
+}
+
+// addRevision registra uma nova revisão imutável apontando para um blob já
+// gravado e move o conteúdo atual do arquivo para ela.
+func (r *FileRepositoryMinioImpl) addRevision(metadata *models.FileMetadata, blob *models.Blob, restoredFrom int) (*models.FileRevision, error) {
+	revisionID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, fmt.Errorf("error generating revision ID: %w", err)
 	}
 
 	now := time.Now()
-	metadata.LastModified = now
-	metadata.UpdatedAt = now
+	revision := &models.FileRevision{
+		ID:           revisionID,
+		FileID:       metadata.ID,
+		Revision:     metadata.Revision + 1,
+		Digest:       blob.Digest,
+		Size:         blob.Size,
+		RestoredFrom: restoredFrom,
+		CreatedAt:    now,
+	}
 
-	metadata.Size = int64(len(content))
+	err = r.blobRepo.Acquire(&models.Blob{
+		Digest:   blob.Digest,
+		Size:     blob.Size,
+		Encoding: blob.Encoding,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error referencing blob: %w", err)
+	}
 
-	err = r.metadataRepo.Update(metadata)
+	err = r.revisionRepo.Create(revision)
 	if err != nil {
-		return fmt.Errorf("error updating metadata: %w", err)
+		return nil, fmt.Errorf("error creating revision: %w", err)
 	}
 
-	_, err = r.minioClient.UploadFile(id, []byte(content))
+	metadata.LastModified = now
+	metadata.UpdatedAt = now
+	metadata.Size = revision.Size
+	metadata.Revision = revision.Revision
+	metadata.ContentDigest = blob.Digest
+	metadata.ContentEncoding = blob.Encoding
+
+	err = r.metadataRepo.Update(metadata)
 	if err != nil {
-		return fmt.Errorf("error saving file to MinIO: %w", err)
+		return nil, fmt.Errorf("error updating metadata: %w", err)
 	}
 
-	return nil
+	return revision, nil
 }
 
+// sampleFileID é o desenho de exemplo que o GetFileContent recria a partir do
+// disco quando o objeto dele não existe no storage.
+const sampleFileID = "exemplo-salve"
+
 func (r *FileRepositoryMinioImpl) GetFileContent(id string) (string, error) {
-	content, err := r.minioClient.GetFile(id)
+	metadata, err := r.metadataRepo.GetByID(id)
+	if err == nil && metadata.ContentDigest != "" {
+		content, blobErr := r.getBlob(metadata.ContentDigest)
+		if blobErr != nil {
+			return "", fmt.Errorf("error fetching file: %w", blobErr)
+		}
+		return string(content), nil
+	}
+
+	// Arquivos gravados antes dos blobs continuam em <id>.json.
+	content, _, err := r.store.Get(objectKey(id))
 	if err != nil {
-		if id == "exemplo-salve" {
+		if id == sampleFileID {
 			localContent, localErr := loadLocalExcalidrawFile()
 			if localErr != nil {
 				return "", fmt.Errorf("error loading file: %w", err)
 			}
 
-			_, uploadErr := r.minioClient.UploadFile(id, []byte(localContent))
+			uploadErr := r.putScene(id, []byte(localContent))
 			if uploadErr != nil {
-				return "", fmt.Errorf("error uploading file to MinIO: %w", uploadErr)
+				return "", fmt.Errorf("error uploading file to storage: %w", uploadErr)
 			}
 
 			return localContent, nil
@@ -167,40 +301,208 @@ func (r *FileRepositoryMinioImpl) GetFileContent(id string) (string, error) {
 }
 
 func (r *FileRepositoryMinioImpl) UploadFile(id string, content []byte) error {
-	_, err := r.minioClient.UploadFile(id, content)
-	if err != nil {
-		return fmt.Errorf("error uploading file: %w", err)
-	}
-	return nil
+	return r.atomically(func(tx *FileRepositoryMinioImpl) error {
+		metadata, err := tx.metadataRepo.GetByIDForUpdate(id)
+		if err != nil {
+			return fmt.Errorf("file not found: %s", id)
+		}
+
+		_, err = tx.saveContent(metadata, content, 0)
+		if err != nil {
+			return fmt.Errorf("error uploading file: %w", err)
+		}
+		return nil
+	})
 }
 
 func (r *FileRepositoryMinioImpl) CreateFolder(folderPath string) error {
-	return r.minioClient.CreateFolder(folderPath)
+	if folderPath == "" {
+		return fmt.Errorf("folder path cannot be empty")
+	}
+
+	_, err := r.store.Put(storage.FolderKey(folderPath), []byte{}, storage.PutOptions{ContentType: storage.FolderContentType})
+	return err
 }
 
+// DeleteFile move o item e toda a sua subárvore para a lixeira. Os objetos no
+// storage só são removidos quando a lixeira é esvaziada.
 func (r *FileRepositoryMinioImpl) DeleteFile(id string) error {
+	return r.atomically(func(tx *FileRepositoryMinioImpl) error {
+		metadata, err := tx.metadataRepo.GetByIDForUpdate(id)
+		if err != nil {
+			return projectError.Errorf(projectError.ENOTFOUND, "file not found: %s", id)
+		}
 
-	metadata, err := r.metadataRepo.GetByID(id)
+		ids := []string{metadata.ID}
+		if metadata.IsFolder {
+			descendants, descErr := tx.collectDescendantIDs(metadata.ID)
+			if descErr != nil {
+				return fmt.Errorf("error listing children of %s: %w", id, descErr)
+			}
+			ids = append(ids, descendants...)
+		}
+
+		err = tx.metadataRepo.MoveToTrash(ids, metadata.ID)
+		if err != nil {
+			return fmt.Errorf("error moving file to trash: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *FileRepositoryMinioImpl) collectDescendantIDs(id string) ([]string, error) {
+	children, err := r.metadataRepo.GetByParentID(id)
 	if err != nil {
-		return fmt.Errorf("file not found: %s", id)
+		return nil, err
+	}
+
+	var ids []string
+	for _, child := range children {
+		ids = append(ids, child.ID)
+		if child.IsFolder {
+			grandChildren, err := r.collectDescendantIDs(child.ID)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, grandChildren...)
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *FileRepositoryMinioImpl) GetTrash() (models.FileMetadataList, error) {
+	return r.metadataRepo.GetTrash()
+}
+
+func (r *FileRepositoryMinioImpl) RestoreFromTrash(id string) (*models.FileMetadata, error) {
+	var restored *models.FileMetadata
+	err := r.atomically(func(tx *FileRepositoryMinioImpl) error {
+		var err error
+		restored, err = tx.restoreFromTrash(id)
+		return err
+	})
+	return restored, err
+}
+
+func (r *FileRepositoryMinioImpl) restoreFromTrash(id string) (*models.FileMetadata, error) {
+	metadata, err := r.metadataRepo.GetTrashedByID(id)
+	if err != nil || metadata.TrashRootID != metadata.ID {
+		return nil, projectError.Errorf(projectError.ENOTFOUND, "trash item not found: %s", id)
+	}
+
+	err = r.metadataRepo.RestoreFromTrash(metadata.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error restoring from trash: %w", err)
+	}
+
+	newPath := "/" + metadata.Name
+	if metadata.ParentID != "" {
+		parent, parentErr := r.metadataRepo.GetByID(metadata.ParentID)
+		if parentErr == nil {
+			newPath = parent.Path + "/" + metadata.Name
+		} else {
+			metadata.ParentID = ""
+		}
+	}
+
+	oldPath := metadata.Path
+	metadata.DeletedAt = gorm.DeletedAt{}
+	metadata.TrashRootID = ""
+	metadata.StoragePath = newPath
+	metadata.Path = newPath
+	metadata.UpdatedAt = time.Now()
+
+	err = r.metadataRepo.Update(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error updating metadata: %w", err)
 	}
 
 	if metadata.IsFolder {
-		children, childErr := r.metadataRepo.GetByParentID(id)
-		if childErr == nil && len(children) > 0 {
-			for _, child := range children {
-				if deleteErr := r.DeleteFile(child.ID); deleteErr != nil {
-					return fmt.Errorf("error deleting child file %s: %w", child.ID, deleteErr)
+		if err := r.updateFolderChildren(metadata, oldPath, &models.FileMetadataList{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return metadata, nil
+}
+
+func (r *FileRepositoryMinioImpl) PurgeFromTrash(id string) error {
+	return r.atomically(func(tx *FileRepositoryMinioImpl) error {
+		return tx.purgeFromTrash(id)
+	})
+}
+
+func (r *FileRepositoryMinioImpl) purgeFromTrash(id string) error {
+	metadata, err := r.metadataRepo.GetTrashedByID(id)
+	if err != nil || metadata.TrashRootID != metadata.ID {
+		return projectError.Errorf(projectError.ENOTFOUND, "trash item not found: %s", id)
+	}
+
+	items, err := r.metadataRepo.GetTrashedByRootID(metadata.ID)
+	if err != nil {
+		return fmt.Errorf("error listing trash item %s: %w", id, err)
+	}
+
+	for _, item := range items {
+		if purgeErr := r.purgeItem(item); purgeErr != nil {
+			return fmt.Errorf("error purging %s: %w", item.ID, purgeErr)
+		}
+	}
+
+	return nil
+}
+
+func (r *FileRepositoryMinioImpl) purgeItem(metadata *models.FileMetadata) error {
+	if metadata.IsFolder {
+		err := r.enqueue(&models.OutboxEntry{
+			Operation: models.OutboxDelete,
+			FileID:    metadata.ID,
+			ObjectKey: storage.FolderKey(metadata.Path),
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		revisions, err := r.revisionRepo.GetByFileID(metadata.ID)
+		if err != nil {
+			return fmt.Errorf("error fetching revisions: %w", err)
+		}
+
+		for _, revision := range revisions {
+			if revision.Digest != "" {
+				if releaseErr := r.blobRepo.Release(revision.Digest); releaseErr != nil {
+					return fmt.Errorf("error releasing blob: %w", releaseErr)
 				}
+				continue
+			}
+
+			err := r.enqueue(&models.OutboxEntry{
+				Operation: models.OutboxDelete,
+				FileID:    metadata.ID,
+				ObjectKey: objectKey(revision.StoragePath),
+			})
+			if err != nil {
+				return fmt.Errorf("error deleting revision from storage: %w", err)
 			}
 		}
-	} else {
-		if minioErr := r.minioClient.DeleteFile(id); minioErr != nil {
-			return fmt.Errorf("error deleting file from MinIO: %w", minioErr)
+
+		if err := r.revisionRepo.DeleteByFileID(metadata.ID); err != nil {
+			return fmt.Errorf("error deleting revisions: %w", err)
+		}
+
+		err = r.enqueue(&models.OutboxEntry{
+			Operation: models.OutboxDelete,
+			FileID:    metadata.ID,
+			ObjectKey: objectKey(metadata.ID),
+		})
+		if err != nil {
+			return fmt.Errorf("error deleting file from storage: %w", err)
 		}
 	}
 
-	err = r.metadataRepo.Delete(id)
+	err := r.metadataRepo.Purge(metadata.ID)
 	if err != nil {
 		return fmt.Errorf("error deleting metadata: %w", err)
 	}
@@ -208,11 +510,22 @@ func (r *FileRepositoryMinioImpl) DeleteFile(id string) error {
 	return nil
 }
 
-func (r *FileRepositoryMinioImpl) RenameFile(id string, newName string) error {
+// RenameFile devolve o item renomeado seguido dos descendentes cujo caminho
+// mudou junto, pais antes dos filhos.
+func (r *FileRepositoryMinioImpl) RenameFile(id string, newName string) (models.FileMetadataList, error) {
+	var changed models.FileMetadataList
+	err := r.atomically(func(tx *FileRepositoryMinioImpl) error {
+		changed = nil
+		return tx.renameFile(id, newName, &changed)
+	})
+	return changed, err
+}
 
-	metadata, err := r.metadataRepo.GetByID(id)
+func (r *FileRepositoryMinioImpl) renameFile(id string, newName string, changed *models.FileMetadataList) error {
+
+	metadata, err := r.metadataRepo.GetByIDForUpdate(id)
 	if err != nil {
-		return fmt.Errorf("file not found: %s", id)
+		return projectError.Errorf(projectError.ENOTFOUND, "file not found: %s", id)
 	}
 
 	if !metadata.IsFolder && !strings.HasSuffix(newName, ".excalidraw") {
@@ -234,6 +547,7 @@ func (r *FileRepositoryMinioImpl) RenameFile(id string, newName string) error {
 		newPath = "/" + newName
 	}
 
+	oldPath := metadata.Path
 	metadata.StoragePath = newPath
 	metadata.Path = newPath
 
@@ -241,36 +555,412 @@ func (r *FileRepositoryMinioImpl) RenameFile(id string, newName string) error {
 	if err != nil {
 		return fmt.Errorf("error updating metadata: %w", err)
 	}
+	*changed = append(*changed, metadata)
 
 	if metadata.IsFolder {
-		children, childErr := r.metadataRepo.GetByParentID(id)
-		if childErr == nil {
-			for _, child := range children {
-				r.updateChildPaths(child, metadata.StoragePath)
+		return r.updateFolderChildren(metadata, oldPath, changed)
+	}
+
+	return nil
+}
+
+// MoveFile devolve, como o RenameFile, o item movido e os descendentes.
+func (r *FileRepositoryMinioImpl) MoveFile(id string, parentID string) (models.FileMetadataList, error) {
+	var changed models.FileMetadataList
+	err := r.atomically(func(tx *FileRepositoryMinioImpl) error {
+		changed = nil
+		return tx.moveFile(id, parentID, &changed)
+	})
+	return changed, err
+}
+
+func (r *FileRepositoryMinioImpl) moveFile(id string, parentID string, changed *models.FileMetadataList) error {
+
+	metadata, err := r.metadataRepo.GetByIDForUpdate(id)
+	if err != nil {
+		return projectError.Errorf(projectError.ENOTFOUND, "file not found: %s", id)
+	}
+
+	newPath := "/" + metadata.Name
+	if parentID != "" {
+		if parentID == id {
+			return projectError.Errorf(projectError.ECONFLICT, "cannot move %s into itself", id)
+		}
+
+		parent, parentErr := r.metadataRepo.GetByID(parentID)
+		if parentErr != nil {
+			return projectError.Errorf(projectError.ENOTFOUND, "target folder not found: %s", parentID)
+		}
+
+		if !parent.IsFolder {
+			return projectError.Errorf(projectError.EINVALID, "target is not a folder: %s", parentID)
+		}
+
+		if metadata.IsFolder {
+			isDescendant, ancestorErr := r.isDescendantOf(parent, id)
+			if ancestorErr != nil {
+				return fmt.Errorf("error checking target ancestors: %w", ancestorErr)
+			}
+			if isDescendant {
+				return projectError.Errorf(projectError.ECONFLICT, "cannot move folder %s into its own descendant", id)
 			}
 		}
+
+		newPath = parent.Path + "/" + metadata.Name
+	}
+
+	oldPath := metadata.Path
+	metadata.ParentID = parentID
+	metadata.StoragePath = newPath
+	metadata.Path = newPath
+	metadata.UpdatedAt = time.Now()
+
+	err = r.metadataRepo.Update(metadata)
+	if err != nil {
+		return fmt.Errorf("error updating metadata: %w", err)
+	}
+	*changed = append(*changed, metadata)
+
+	if metadata.IsFolder {
+		return r.updateFolderChildren(metadata, oldPath, changed)
+	}
+
+	return nil
+}
+
+// CopyFile devolve todos os itens criados, começando pela cópia de id e com
+// os pais antes dos filhos.
+func (r *FileRepositoryMinioImpl) CopyFile(id string, parentID string, newName string) (models.FileMetadataList, error) {
+	var created models.FileMetadataList
+	err := r.atomically(func(tx *FileRepositoryMinioImpl) error {
+		created = nil
+		return tx.copyFile(id, parentID, newName, &created)
+	})
+	return created, err
+}
+
+// copyFile valida e copia dentro da mesma transação. Origem e destino ficam
+// travados até o commit, para que um move ou uma ida para a lixeira
+// concorrentes não deixem a cópia num lugar que já não existe.
+func (r *FileRepositoryMinioImpl) copyFile(id string, parentID string, newName string, created *models.FileMetadataList) error {
+	metadata, err := r.metadataRepo.GetByIDForUpdate(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return projectError.Errorf(projectError.ENOTFOUND, "file not found: %s", id)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading file %s: %w", id, err)
+	}
+
+	parentPath := ""
+	if parentID != "" {
+		parent, parentErr := r.metadataRepo.GetByIDForUpdate(parentID)
+		if errors.Is(parentErr, gorm.ErrRecordNotFound) {
+			return projectError.Errorf(projectError.ENOTFOUND, "target folder not found: %s", parentID)
+		}
+		if parentErr != nil {
+			return fmt.Errorf("error reading target folder %s: %w", parentID, parentErr)
+		}
+
+		if !parent.IsFolder {
+			return projectError.Errorf(projectError.EINVALID, "target is not a folder: %s", parentID)
+		}
+
+		if metadata.IsFolder {
+			isDescendant, ancestorErr := r.isDescendantOf(parent, id)
+			if ancestorErr != nil {
+				return fmt.Errorf("error checking target ancestors: %w", ancestorErr)
+			}
+			if parentID == id || isDescendant {
+				return projectError.Errorf(projectError.ECONFLICT, "cannot copy folder %s into itself", id)
+			}
+		}
+
+		parentPath = parent.Path
+	}
+
+	if newName == "" {
+		if parentID == metadata.ParentID {
+			newName, err = r.freeCopyName(metadata, parentPath)
+			if err != nil {
+				return err
+			}
+		} else {
+			newName = metadata.Name
+		}
+	} else if !metadata.IsFolder && !strings.HasSuffix(newName, ".excalidraw") {
+		newName = strings.TrimSuffix(newName, ".json") + ".excalidraw"
+	}
+
+	return r.copyTree(metadata, parentID, parentPath, newName, created)
+}
+
+func copyName(metadata *models.FileMetadata, n int) string {
+	suffix := " (copy)"
+	if n > 1 {
+		suffix = fmt.Sprintf(" (copy %d)", n)
+	}
+	if metadata.IsFolder {
+		return metadata.Name + suffix
+	}
+	return strings.TrimSuffix(metadata.Name, ".excalidraw") + suffix + ".excalidraw"
+}
+
+// freeCopyName escolhe o primeiro nome de cópia livre na pasta, já que o
+// caminho é único: "a (copy)", depois "a (copy 2)" e assim por diante.
+func (r *FileRepositoryMinioImpl) freeCopyName(metadata *models.FileMetadata, parentPath string) (string, error) {
+	for n := 1; ; n++ {
+		name := copyName(metadata, n)
+		_, err := r.metadataRepo.GetByPath(parentPath + "/" + name)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return name, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("error checking copy name %s: %w", name, err)
+		}
+	}
+}
+
+// copyTree cria novos metadados para o item e seus descendentes. As cópias
+// apontam para os mesmos blobs do original, então o conteúdo não é duplicado.
+func (r *FileRepositoryMinioImpl) copyTree(source *models.FileMetadata, parentID string, parentPath string, name string, created *models.FileMetadataList) error {
+	newID, err := uuid.GenerateUUID()
+	if err != nil {
+		return fmt.Errorf("error generating UUID: %w", err)
+	}
+
+	now := time.Now()
+	path := parentPath + "/" + name
+	copied := &models.FileMetadata{
+		ID:           newID,
+		Name:         name,
+		IsFolder:     source.IsFolder,
+		ParentID:     parentID,
+		StoragePath:  path,
+		Path:         path,
+		ContentType:  source.ContentType,
+		Size:         source.Size,
+		LastModified: now,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if source.IsFolder {
+		if err := r.metadataRepo.Create(copied); err != nil {
+			return fmt.Errorf("error creating metadata: %w", err)
+		}
+		*created = append(*created, copied)
+
+		err := r.enqueue(&models.OutboxEntry{
+			Operation: models.OutboxCreateFolder,
+			FileID:    copied.ID,
+			ObjectKey: storage.FolderKey(copied.Path),
+		})
+		if err != nil {
+			return fmt.Errorf("error creating folder in storage: %w", err)
+		}
+
+		children, err := r.metadataRepo.GetByParentID(source.ID)
+		if err != nil {
+			return fmt.Errorf("error listing children of %s: %w", source.ID, err)
+		}
+
+		for _, child := range children {
+			if err := r.copyTree(child, copied.ID, copied.Path, child.Name, created); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	// O blob reaproveitado é travado como no putBlob, para que o
+	// CollectGarbage não o apague entre a leitura e o commit da cópia.
+	var blob *models.Blob
+	if source.ContentDigest != "" {
+		blob, err = r.blobRepo.GetByDigestForUpdate(source.ContentDigest)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("error fetching blob %s: %w", source.ContentDigest, err)
+		}
+	}
+	if blob == nil || blob.RefCount <= 0 {
+		content, err := r.GetFileContent(source.ID)
+		if err != nil {
+			return fmt.Errorf("error reading file %s: %w", source.ID, err)
+		}
+
+		blob, err = r.putBlob(copied.ID, []byte(content))
+		if err != nil {
+			return fmt.Errorf("error copying file in storage: %w", err)
+		}
+	}
+
+	if err := r.metadataRepo.Create(copied); err != nil {
+		return fmt.Errorf("error creating metadata: %w", err)
+	}
+	*created = append(*created, copied)
+
+	if _, err := r.addRevision(copied, blob, 0); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-func (r *FileRepositoryMinioImpl) updateChildPaths(child *models.FileMetadata, parentPath string) {
+// isDescendantOf sobe a partir de item até a raiz procurando ancestorID.
+func (r *FileRepositoryMinioImpl) isDescendantOf(item *models.FileMetadata, ancestorID string) (bool, error) {
+	visited := map[string]bool{}
+	current := item
 
+	for current.ParentID != "" {
+		if current.ParentID == ancestorID {
+			return true, nil
+		}
+		if visited[current.ParentID] {
+			return false, fmt.Errorf("cycle detected at %s", current.ParentID)
+		}
+		visited[current.ParentID] = true
+
+		// Um pai que não existe mais encerra a cadeia; qualquer outro erro
+		// não pode virar "não é descendente", ou o move criaria um ciclo.
+		parent, err := r.metadataRepo.GetByID(current.ParentID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("error reading ancestor %s: %w", current.ParentID, err)
+		}
+		current = parent
+	}
+
+	return false, nil
+}
+
+func (r *FileRepositoryMinioImpl) updateChildPaths(child *models.FileMetadata, parentPath string, changed *models.FileMetadataList) error {
+
+	oldPath := child.Path
 	newPath := parentPath + "/" + child.Name
 	child.StoragePath = newPath
 	child.Path = newPath
 	child.UpdatedAt = time.Now()
 
-	r.metadataRepo.Update(child)
+	if err := r.metadataRepo.Update(child); err != nil {
+		return fmt.Errorf("error updating path of %s: %w", child.ID, err)
+	}
+	*changed = append(*changed, child)
 
 	if child.IsFolder {
-		grandChildren, err := r.metadataRepo.GetByParentID(child.ID)
-		if err == nil {
-			for _, grandChild := range grandChildren {
-				r.updateChildPaths(grandChild, newPath)
-			}
+		return r.updateFolderChildren(child, oldPath, changed)
+	}
+	return nil
+}
+
+// updateFolderChildren move o marcador de uma pasta cujo caminho mudou e
+// recalcula o caminho de todos os seus descendentes.
+func (r *FileRepositoryMinioImpl) updateFolderChildren(folder *models.FileMetadata, oldPath string, changed *models.FileMetadataList) error {
+	if oldPath != folder.Path {
+		err := r.enqueue(&models.OutboxEntry{
+			Operation: models.OutboxDelete,
+			FileID:    folder.ID,
+			ObjectKey: storage.FolderKey(oldPath),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = r.enqueue(&models.OutboxEntry{
+			Operation: models.OutboxCreateFolder,
+			FileID:    folder.ID,
+			ObjectKey: storage.FolderKey(folder.Path),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	children, err := r.metadataRepo.GetByParentID(folder.ID)
+	if err != nil {
+		return fmt.Errorf("error listing children of %s: %w", folder.ID, err)
+	}
+
+	for _, child := range children {
+		if err := r.updateChildPaths(child, folder.Path, changed); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+func (r *FileRepositoryMinioImpl) GetRevisions(id string) (models.FileRevisionList, error) {
+	_, err := r.metadataRepo.GetByID(id)
+	if err != nil {
+		return nil, projectError.Errorf(projectError.ENOTFOUND, "file not found: %s", id)
+	}
+
+	revisions, err := r.revisionRepo.GetByFileID(id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+func (r *FileRepositoryMinioImpl) GetRevisionContent(id string, revision int) (string, error) {
+	fileRevision, err := r.revisionRepo.GetByFileIDAndRevision(id, revision)
+	if err != nil {
+		return "", projectError.Errorf(projectError.ENOTFOUND, "revision %d not found for file %s", revision, id)
+	}
+
+	if fileRevision.Digest != "" {
+		content, err := r.getBlob(fileRevision.Digest)
+		if err != nil {
+			return "", fmt.Errorf("error fetching revision: %w", err)
+		}
+		return string(content), nil
+	}
+
+	content, _, err := r.store.Get(objectKey(fileRevision.StoragePath))
+	if err != nil {
+		return "", fmt.Errorf("error fetching revision: %w", err)
+	}
+
+	return string(content), nil
+}
+
+func (r *FileRepositoryMinioImpl) RestoreRevision(id string, revision int) (*models.FileRevision, error) {
+	var restored *models.FileRevision
+	err := r.atomically(func(tx *FileRepositoryMinioImpl) error {
+		var err error
+		restored, err = tx.restoreRevision(id, revision)
+		return err
+	})
+	return restored, err
+}
+
+func (r *FileRepositoryMinioImpl) restoreRevision(id string, revision int) (*models.FileRevision, error) {
+	metadata, err := r.metadataRepo.GetByIDForUpdate(id)
+	if err != nil {
+		return nil, projectError.Errorf(projectError.ENOTFOUND, "file not found: %s", id)
+	}
+
+	fileRevision, err := r.revisionRepo.GetByFileIDAndRevision(id, revision)
+	if err != nil {
+		return nil, projectError.Errorf(projectError.ENOTFOUND, "revision %d not found for file %s", revision, id)
+	}
+
+	if fileRevision.Digest != "" {
+		blob, blobErr := r.blobRepo.GetByDigest(fileRevision.Digest)
+		if blobErr != nil {
+			return nil, fmt.Errorf("error fetching blob %s: %w", fileRevision.Digest, blobErr)
+		}
+		return r.addRevision(metadata, blob, revision)
+	}
+
+	content, err := r.GetRevisionContent(id, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.saveContent(metadata, []byte(content), revision)
 }
 
 func loadLocalExcalidrawFile() (string, error) {
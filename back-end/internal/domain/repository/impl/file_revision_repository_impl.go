@@ -0,0 +1,53 @@
+package impl
+
+import (
+	"myScalidraw/infra/database"
+	"myScalidraw/internal/domain/models"
+)
+
+type FileRevisionRepositoryImpl struct {
+	db *database.DB
+}
+
+func NewFileRevisionRepository(db *database.DB) *FileRevisionRepositoryImpl {
+	return &FileRevisionRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *FileRevisionRepositoryImpl) GetAll() (models.FileRevisionList, error) {
+	var revisions models.FileRevisionList
+	result := r.db.Find(&revisions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return revisions, nil
+}
+
+func (r *FileRevisionRepositoryImpl) GetByFileID(fileID string) (models.FileRevisionList, error) {
+	var revisions models.FileRevisionList
+	result := r.db.Order("revision desc").Find(&revisions, "file_id = ?", fileID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return revisions, nil
+}
+
+func (r *FileRevisionRepositoryImpl) GetByFileIDAndRevision(fileID string, revision int) (*models.FileRevision, error) {
+	var fileRevision models.FileRevision
+	result := r.db.First(&fileRevision, "file_id = ? AND revision = ?", fileID, revision)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &fileRevision, nil
+}
+
+func (r *FileRevisionRepositoryImpl) Create(revision *models.FileRevision) error {
+	result := r.db.Create(revision)
+	return result.Error
+}
+
+func (r *FileRevisionRepositoryImpl) DeleteByFileID(fileID string) error {
+	result := r.db.Delete(&models.FileRevision{}, "file_id = ?", fileID)
+	return result.Error
+}
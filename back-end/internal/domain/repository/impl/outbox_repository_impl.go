@@ -0,0 +1,100 @@
+package impl
+
+import (
+	"time"
+
+	"myScalidraw/infra/database"
+	"myScalidraw/internal/domain/models"
+
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepositoryImpl struct {
+	db *database.DB
+}
+
+func NewOutboxRepository(db *database.DB) *OutboxRepositoryImpl {
+	return &OutboxRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *OutboxRepositoryImpl) Create(entry *models.OutboxEntry) error {
+	result := r.db.Create(entry)
+	return result.Error
+}
+
+func (r *OutboxRepositoryImpl) GetPendingBefore(before time.Time) (models.OutboxEntryList, error) {
+	var entries models.OutboxEntryList
+	result := r.db.Where("created_at < ?", before).Order("created_at").Find(&entries)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return entries, nil
+}
+
+func (r *OutboxRepositoryImpl) GetPendingByObjectKey(objectKey string) (*models.OutboxEntry, error) {
+	var entry models.OutboxEntry
+	result := r.db.Where("object_key = ? AND operation = ?", objectKey, models.OutboxPromote).First(&entry)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &entry, nil
+}
+
+func (r *OutboxRepositoryImpl) GetPendingByStagingKey(stagingKey string) (*models.OutboxEntry, error) {
+	var entry models.OutboxEntry
+	result := r.db.First(&entry, "staging_key = ?", stagingKey)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &entry, nil
+}
+
+func (r *OutboxRepositoryImpl) Delete(id string) error {
+	result := r.db.Delete(&models.OutboxEntry{}, "id = ?", id)
+	return result.Error
+}
+
+func (r *OutboxRepositoryImpl) ReserveStaged(staged *models.StagedObject) error {
+	result := r.db.Create(staged)
+	return result.Error
+}
+
+// LockStaged trava a reserva até o fim da transação corrente.
+func (r *OutboxRepositoryImpl) LockStaged(key string) error {
+	var staged models.StagedObject
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&staged, "key = ?", key)
+	return result.Error
+}
+
+// ClaimStaged trava a reserva só se nenhuma outra transação a tiver travado;
+// caso contrário devolve gorm.ErrRecordNotFound, como se ela não existisse.
+func (r *OutboxRepositoryImpl) ClaimStaged(key string) (*models.StagedObject, error) {
+	var staged models.StagedObject
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).First(&staged, "key = ?", key)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &staged, nil
+}
+
+func (r *OutboxRepositoryImpl) HasStaged(key string) (bool, error) {
+	var count int64
+	result := r.db.Model(&models.StagedObject{}).Where("key = ?", key).Count(&count)
+	return count > 0, result.Error
+}
+
+func (r *OutboxRepositoryImpl) GetAllStaged() (models.StagedObjectList, error) {
+	var staged models.StagedObjectList
+	result := r.db.Order("created_at").Find(&staged)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return staged, nil
+}
+
+func (r *OutboxRepositoryImpl) DeleteStaged(key string) error {
+	result := r.db.Delete(&models.StagedObject{}, "key = ?", key)
+	return result.Error
+}
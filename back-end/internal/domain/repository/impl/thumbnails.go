@@ -0,0 +1,57 @@
+package impl
+
+import (
+	"errors"
+	"fmt"
+
+	"myScalidraw/infra/storage"
+	"myScalidraw/pkg/projectError"
+)
+
+// A miniatura de uma cena fica ao lado do blob, em blobs/<digest>.thumb.png.
+// Como só depende do conteúdo, ela não passa pelo staging nem conta
+// referências: vive enquanto o blob existir e é apagada junto com ele pelo
+// CollectGarbage.
+
+func thumbnailKey(digest string) string {
+	return blobKey(digest) + ".thumb.png"
+}
+
+func (r *FileRepositoryMinioImpl) GetThumbnail(digest string) ([]byte, error) {
+	content, _, err := r.store.Get(thumbnailKey(digest))
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return nil, projectError.Errorf(projectError.ENOTFOUND, "thumbnail not found: %s", digest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching thumbnail %s: %w", digest, err)
+	}
+	return content, nil
+}
+
+func (r *FileRepositoryMinioImpl) HasThumbnail(digest string) (bool, error) {
+	_, err := r.store.Stat(thumbnailKey(digest))
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error checking thumbnail %s: %w", digest, err)
+	}
+	return true, nil
+}
+
+func (r *FileRepositoryMinioImpl) SaveThumbnail(digest string, content []byte) error {
+	_, err := r.store.Put(thumbnailKey(digest), content, storage.PutOptions{ContentType: "image/png"})
+	if err != nil {
+		return fmt.Errorf("error storing thumbnail %s: %w", digest, err)
+	}
+	return nil
+}
+
+// GetBlobContent lê o conteúdo pelo digest, para gerar miniaturas de cenas
+// gravadas antes delas existirem.
+func (r *FileRepositoryMinioImpl) GetBlobContent(digest string) ([]byte, error) {
+	if _, err := r.blobRepo.GetByDigest(digest); err != nil {
+		return nil, projectError.Errorf(projectError.ENOTFOUND, "blob not found: %s", digest)
+	}
+	return r.getBlob(digest)
+}
@@ -0,0 +1,289 @@
+package impl
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"myScalidraw/infra/database"
+	"myScalidraw/infra/storage"
+	"myScalidraw/internal/domain/models"
+	"myScalidraw/pkg/uuid"
+
+	"gorm.io/gorm"
+)
+
+// Operações que tocam o Postgres e o storage seguem sempre a mesma ordem: o
+// conteúdo novo é gravado numa chave de staging, os metadados são gravados
+// numa transação junto com uma entrada no outbox e, depois do commit, o
+// objeto é promovido para a chave final. Antes do upload a chave é reservada
+// num registro confirmado à parte, que a transação trava até o commit. Se o
+// processo cair no meio do caminho, o RecoverPendingOperations conclui as
+// entradas que ficaram no outbox e apaga os objetos de staging cuja reserva
+// não está mais travada, sem depender de quanto tempo a transação leva.
+
+const stagingPrefix = "staging/"
+
+func stagingKey(id string) string {
+	return stagingPrefix + id
+}
+
+// atomically executa fn com repositórios ligados a uma única transação e, se
+// ela for confirmada, conclui as operações de storage enfileiradas por fn.
+func (r *FileRepositoryMinioImpl) atomically(fn func(tx *FileRepositoryMinioImpl) error) error {
+	var txRepo *FileRepositoryMinioImpl
+
+	err := r.db.Transaction(func(db *gorm.DB) error {
+		txRepo = r.withTx(&database.DB{DB: db})
+		return fn(txRepo)
+	})
+	if err != nil {
+		if txRepo != nil {
+			txRepo.discardStaged()
+		}
+		return err
+	}
+
+	for _, entry := range txRepo.pending {
+		if err := r.completeOperation(entry); err != nil {
+			log.Printf("Error completing %s of %s, it will be retried: %v", entry.Operation, entry.ObjectKey, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *FileRepositoryMinioImpl) withTx(db *database.DB) *FileRepositoryMinioImpl {
+	return &FileRepositoryMinioImpl{
+		fileSystem:   r.fileSystem,
+		store:        r.store,
+		metadataRepo: NewFileMetadataRepository(db),
+		revisionRepo: NewFileRevisionRepository(db),
+		blobRepo:     NewBlobRepository(db),
+		outboxRepo:   NewOutboxRepository(db),
+		reservations: r.reservations,
+		db:           db,
+	}
+}
+
+// enqueue registra uma operação de storage no outbox da transação corrente.
+func (r *FileRepositoryMinioImpl) enqueue(entry *models.OutboxEntry) error {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return fmt.Errorf("error generating outbox ID: %w", err)
+	}
+
+	entry.ID = id
+	entry.CreatedAt = time.Now()
+
+	// Entra na lista antes do Create para que o staging seja descartado
+	// mesmo que a própria entrada não chegue a ser gravada.
+	r.pending = append(r.pending, entry)
+
+	if err := r.outboxRepo.Create(entry); err != nil {
+		return fmt.Errorf("error recording %s of %s: %w", entry.Operation, entry.ObjectKey, err)
+	}
+	return nil
+}
+
+// stageObject grava content numa chave de staging. Chamado dentro de
+// atomically: a reserva da chave é confirmada antes do upload e fica travada
+// pela transação, que a apaga junto com a criação da entrada do outbox.
+func (r *FileRepositoryMinioImpl) stageObject(fileID string, objectKey string, content []byte) (storage.ObjectInfo, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return storage.ObjectInfo{}, fmt.Errorf("error generating staging key: %w", err)
+	}
+	key := stagingKey(id)
+
+	err = r.reservations.ReserveStaged(&models.StagedObject{Key: key, FileID: fileID, CreatedAt: time.Now()})
+	if err != nil {
+		return storage.ObjectInfo{}, fmt.Errorf("error reserving %s: %w", key, err)
+	}
+	// Entre a reserva e a trava o worker pode ter levado a reserva, já que
+	// ainda não havia objeto; a gravação falha e o cliente tenta de novo.
+	if err := r.outboxRepo.LockStaged(key); err != nil {
+		return storage.ObjectInfo{}, fmt.Errorf("error locking reservation %s: %w", key, err)
+	}
+
+	info, err := r.store.Put(key, content, storage.PutOptions{ContentType: sceneContentType})
+	if err != nil {
+		return storage.ObjectInfo{}, fmt.Errorf("error staging %s: %w", objectKey, err)
+	}
+
+	err = r.enqueue(&models.OutboxEntry{
+		Operation:  models.OutboxPromote,
+		FileID:     fileID,
+		StagingKey: key,
+		ObjectKey:  objectKey,
+	})
+	if err != nil {
+		return info, err
+	}
+
+	if err := r.outboxRepo.DeleteStaged(key); err != nil {
+		return info, fmt.Errorf("error releasing reservation %s: %w", key, err)
+	}
+	return info, nil
+}
+
+// discardStaged roda depois do rollback, quando as reservas já voltaram a
+// existir e não estão mais travadas.
+func (r *FileRepositoryMinioImpl) discardStaged() {
+	for _, entry := range r.pending {
+		if entry.StagingKey == "" {
+			continue
+		}
+		if err := r.store.Delete(entry.StagingKey); err != nil {
+			log.Printf("Error discarding staged object %s: %v", entry.StagingKey, err)
+			continue
+		}
+		if err := r.reservations.DeleteStaged(entry.StagingKey); err != nil {
+			log.Printf("Error releasing reservation %s: %v", entry.StagingKey, err)
+		}
+	}
+}
+
+// completeOperation aplica a operação no storage e remove a entrada do outbox.
+// Todas as operações podem ser repetidas sem efeito colateral.
+func (r *FileRepositoryMinioImpl) completeOperation(entry *models.OutboxEntry) error {
+	switch entry.Operation {
+	case models.OutboxPromote:
+		err := r.store.Copy(entry.StagingKey, entry.ObjectKey)
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			if _, statErr := r.store.Stat(entry.ObjectKey); statErr != nil {
+				log.Printf("Staged object %s for %s is gone, content of %s was lost", entry.StagingKey, entry.ObjectKey, entry.FileID)
+			}
+		} else if err != nil {
+			return err
+		}
+
+		if err := r.store.Delete(entry.StagingKey); err != nil {
+			return err
+		}
+
+	case models.OutboxDelete:
+		if err := r.store.Delete(entry.ObjectKey); err != nil {
+			return err
+		}
+
+	case models.OutboxCreateFolder:
+		_, err := r.store.Put(entry.ObjectKey, []byte{}, storage.PutOptions{ContentType: storage.FolderContentType})
+		if err != nil {
+			return err
+		}
+
+	default:
+		log.Printf("Unknown outbox operation %s for %s", entry.Operation, entry.ObjectKey)
+	}
+
+	return r.outboxRepo.Delete(entry.ID)
+}
+
+// RecoverPendingOperations conclui as entradas do outbox criadas antes de
+// before e apaga os objetos de staging de transações desfeitas: os que têm
+// reserva sem trava e os que não têm nem reserva nem entrada no outbox.
+func (r *FileRepositoryMinioImpl) RecoverPendingOperations(before time.Time) (int, error) {
+	entries, err := r.outboxRepo.GetPendingBefore(before)
+	if err != nil {
+		return 0, fmt.Errorf("error listing pending operations: %w", err)
+	}
+
+	recovered := 0
+	for _, entry := range entries {
+		if err := r.completeOperation(entry); err != nil {
+			log.Printf("Error completing %s of %s: %v", entry.Operation, entry.ObjectKey, err)
+			continue
+		}
+		recovered++
+	}
+
+	staged, err := r.store.List(stagingPrefix)
+	if err != nil {
+		return recovered, fmt.Errorf("error listing staged objects: %w", err)
+	}
+
+	keys := map[string]bool{}
+	for _, object := range staged {
+		keys[object.Key] = true
+	}
+
+	// Reservas sem objeto sobram de quedas entre a reserva e o upload.
+	reservations, err := r.reservations.GetAllStaged()
+	if err != nil {
+		return recovered, fmt.Errorf("error listing staging reservations: %w", err)
+	}
+	for _, reservation := range reservations {
+		keys[reservation.Key] = true
+	}
+
+	for key := range keys {
+		reclaimed, err := r.reclaimStaged(key)
+		if err != nil {
+			log.Printf("Error reclaiming staged object %s: %v", key, err)
+			continue
+		}
+		if reclaimed {
+			recovered++
+		}
+	}
+
+	return recovered, nil
+}
+
+// reclaimStaged apaga o objeto de staging e a reserva dele se nenhuma
+// transação em andamento for dona da chave.
+func (r *FileRepositoryMinioImpl) reclaimStaged(key string) (bool, error) {
+	reclaimed := false
+	err := r.db.Transaction(func(db *gorm.DB) error {
+		outboxRepo := NewOutboxRepository(&database.DB{DB: db})
+
+		_, err := outboxRepo.ClaimStaged(key)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err != nil {
+			// Sem trava conseguida: ou outra transação é dona da reserva, ou
+			// ela já foi trocada por uma entrada do outbox. O commit faz as
+			// duas coisas de uma vez, então a ordem das consultas importa.
+			owned, err := outboxRepo.HasStaged(key)
+			if err != nil || owned {
+				return err
+			}
+			_, err = outboxRepo.GetPendingByStagingKey(key)
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		if err := r.store.Delete(key); err != nil {
+			return err
+		}
+		if err := outboxRepo.DeleteStaged(key); err != nil {
+			return err
+		}
+		reclaimed = true
+		return nil
+	})
+	return reclaimed, err
+}
+
+// readStaged cobre o intervalo entre o commit e a promoção do objeto.
+func (r *FileRepositoryMinioImpl) readStaged(objectKey string) ([]byte, bool) {
+	entry, err := r.outboxRepo.GetPendingByObjectKey(objectKey)
+	if err != nil {
+		return nil, false
+	}
+
+	content, _, err := r.store.Get(entry.StagingKey)
+	if err != nil {
+		return nil, false
+	}
+	return content, true
+}
+
+func isStagingKey(key string) bool {
+	return strings.HasPrefix(key, stagingPrefix)
+}
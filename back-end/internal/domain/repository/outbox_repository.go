@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"time"
+
+	"myScalidraw/internal/domain/models"
+)
+
+type OutboxRepository interface {
+	Create(entry *models.OutboxEntry) error
+
+	GetPendingBefore(before time.Time) (models.OutboxEntryList, error)
+
+	GetPendingByObjectKey(objectKey string) (*models.OutboxEntry, error)
+
+	GetPendingByStagingKey(stagingKey string) (*models.OutboxEntry, error)
+
+	Delete(id string) error
+
+	ReserveStaged(staged *models.StagedObject) error
+
+	LockStaged(key string) error
+
+	ClaimStaged(key string) (*models.StagedObject, error)
+
+	HasStaged(key string) (bool, error)
+
+	GetAllStaged() (models.StagedObjectList, error)
+
+	DeleteStaged(key string) error
+}
@@ -0,0 +1,188 @@
+package scene
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"myScalidraw/pkg/excalidraw"
+)
+
+var diffProperties = []string{
+	"type",
+	"text",
+	"x",
+	"y",
+	"width",
+	"height",
+	"angle",
+	"points",
+	"strokeColor",
+	"backgroundColor",
+	"fillStyle",
+	"strokeWidth",
+	"strokeStyle",
+	"opacity",
+	"fontSize",
+	"fontFamily",
+	"groupIds",
+	"frameId",
+	"containerId",
+	"link",
+	"locked",
+}
+
+// elementFields indexa os campos do Element pelo nome no JSON.
+var elementFields = func() map[string]int {
+	fields := map[string]int{}
+	elementType := reflect.TypeOf(excalidraw.Element{})
+	for i := 0; i < elementType.NumField(); i++ {
+		name, _, _ := strings.Cut(elementType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+}()
+
+func property(element *excalidraw.Element, name string) interface{} {
+	return reflect.ValueOf(element).Elem().Field(elementFields[name]).Interface()
+}
+
+type PropertyChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+type ElementChange struct {
+	ID          string                    `json:"id"`
+	Type        string                    `json:"type"`
+	FromVersion int64                     `json:"fromVersion,omitempty"`
+	ToVersion   int64                     `json:"toVersion,omitempty"`
+	Changes     map[string]PropertyChange `json:"changes,omitempty"`
+	Element     *excalidraw.Element       `json:"element,omitempty"`
+}
+
+type Diff struct {
+	Added    []ElementChange           `json:"added"`
+	Removed  []ElementChange           `json:"removed"`
+	Modified []ElementChange           `json:"modified"`
+	AppState map[string]PropertyChange `json:"appState"`
+}
+
+func (d *Diff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0 && len(d.AppState) == 0
+}
+
+// Compare casa elementos pelo id. Elementos marcados com isDeleted contam como
+// ausentes, do mesmo jeito que o editor os trata.
+func Compare(from, to *excalidraw.Scene) *Diff {
+	diff := &Diff{
+		Added:    []ElementChange{},
+		Removed:  []ElementChange{},
+		Modified: []ElementChange{},
+		AppState: compareMaps(from.AppState, to.AppState),
+	}
+
+	fromElements := liveElements(from)
+	toElements := liveElements(to)
+
+	for id, element := range toElements {
+		if _, ok := fromElements[id]; !ok {
+			diff.Added = append(diff.Added, ElementChange{
+				ID:        id,
+				Type:      element.Type,
+				ToVersion: element.Version,
+				Element:   element,
+			})
+		}
+	}
+
+	for id, element := range fromElements {
+		newElement, ok := toElements[id]
+		if !ok {
+			diff.Removed = append(diff.Removed, ElementChange{
+				ID:          id,
+				Type:        element.Type,
+				FromVersion: element.Version,
+				Element:     element,
+			})
+			continue
+		}
+
+		if change, modified := compareElements(element, newElement); modified {
+			diff.Modified = append(diff.Modified, change)
+		}
+	}
+
+	sortChanges(diff.Added)
+	sortChanges(diff.Removed)
+	sortChanges(diff.Modified)
+
+	return diff
+}
+
+func compareElements(from, to *excalidraw.Element) (ElementChange, bool) {
+	changes := map[string]PropertyChange{}
+	for _, name := range diffProperties {
+		fromValue, toValue := property(from, name), property(to, name)
+		if !reflect.DeepEqual(fromValue, toValue) {
+			changes[name] = PropertyChange{From: fromValue, To: toValue}
+		}
+	}
+
+	if sameVersion(from, to) && len(changes) == 0 {
+		return ElementChange{}, false
+	}
+
+	return ElementChange{
+		ID:          to.ID,
+		Type:        to.Type,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Changes:     changes,
+	}, true
+}
+
+func compareMaps(from, to excalidraw.AppState) map[string]PropertyChange {
+	changes := map[string]PropertyChange{}
+
+	for key, value := range from {
+		if !sameJSON(value, to[key]) {
+			changes[key] = PropertyChange{From: value, To: jsonValue(to[key])}
+		}
+	}
+	for key, value := range to {
+		if _, ok := from[key]; !ok {
+			changes[key] = PropertyChange{From: nil, To: value}
+		}
+	}
+
+	return changes
+}
+
+// jsonValue evita que uma chave ausente vire um RawMessage nil, que não é
+// igual a nil dentro de uma interface.
+func jsonValue(value json.RawMessage) interface{} {
+	if value == nil {
+		return nil
+	}
+	return value
+}
+
+func liveElements(scene *excalidraw.Scene) map[string]*excalidraw.Element {
+	elements := make(map[string]*excalidraw.Element, len(scene.Elements))
+	for id, element := range scene.ElementsByID() {
+		if !element.IsDeleted {
+			elements[id] = element
+		}
+	}
+	return elements
+}
+
+func sortChanges(changes []ElementChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ID < changes[j].ID
+	})
+}
@@ -0,0 +1,111 @@
+package scene
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func changeIDs(changes []ElementChange) []string {
+	result := make([]string, len(changes))
+	for i, change := range changes {
+		result[i] = change.ID
+	}
+	return result
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     []string
+		to       []string
+		added    []string
+		removed  []string
+		modified []string
+	}{
+		{
+			name:     "unchanged",
+			from:     []string{element("a", 1, 10, 0)},
+			to:       []string{element("a", 1, 10, 0)},
+			added:    []string{},
+			removed:  []string{},
+			modified: []string{},
+		},
+		{
+			name:     "added and removed",
+			from:     []string{element("a", 1, 10, 0)},
+			to:       []string{element("b", 1, 10, 0)},
+			added:    []string{"b"},
+			removed:  []string{"a"},
+			modified: []string{},
+		},
+		{
+			name:     "tombstone counts as removed",
+			from:     []string{element("a", 1, 10, 0)},
+			to:       []string{element("a", 2, 20, 0, `"isDeleted":true`)},
+			added:    []string{},
+			removed:  []string{"a"},
+			modified: []string{},
+		},
+		{
+			name:     "restored tombstone counts as added",
+			from:     []string{element("a", 2, 20, 0, `"isDeleted":true`)},
+			to:       []string{element("a", 3, 30, 0)},
+			added:    []string{"a"},
+			removed:  []string{},
+			modified: []string{},
+		},
+		{
+			name:     "modified, sorted by id",
+			from:     []string{element("b", 1, 10, 0), element("a", 1, 10, 0)},
+			to:       []string{element("b", 2, 20, 1), element("a", 2, 20, 0)},
+			added:    []string{},
+			removed:  []string{},
+			modified: []string{"a", "b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff := Compare(sceneOf(t, test.from...), sceneOf(t, test.to...))
+
+			if got := changeIDs(diff.Added); !reflect.DeepEqual(got, test.added) {
+				t.Errorf("added = %v, want %v", got, test.added)
+			}
+			if got := changeIDs(diff.Removed); !reflect.DeepEqual(got, test.removed) {
+				t.Errorf("removed = %v, want %v", got, test.removed)
+			}
+			if got := changeIDs(diff.Modified); !reflect.DeepEqual(got, test.modified) {
+				t.Errorf("modified = %v, want %v", got, test.modified)
+			}
+		})
+	}
+}
+
+func TestCompareChanges(t *testing.T) {
+	from := sceneOf(t, element("a", 1, 10, 0))
+	to := sceneOf(t, element("a", 2, 20, 5))
+	from.AppState["viewBackgroundColor"] = json.RawMessage(`"#fff"`)
+	to.AppState["viewBackgroundColor"] = json.RawMessage(`"#000"`)
+
+	diff := Compare(from, to)
+
+	if len(diff.Modified) != 1 {
+		t.Fatalf("modified = %+v, want one change", diff.Modified)
+	}
+	change := diff.Modified[0]
+	if change.FromVersion != 1 || change.ToVersion != 2 {
+		t.Errorf("versions = %d -> %d, want 1 -> 2", change.FromVersion, change.ToVersion)
+	}
+	if len(change.Changes) != 1 || change.Changes["x"].To == nil {
+		t.Errorf("changes = %+v, want only x", change.Changes)
+	}
+
+	wantAppState := map[string]PropertyChange{"viewBackgroundColor": {From: json.RawMessage(`"#fff"`), To: json.RawMessage(`"#000"`)}}
+	if !reflect.DeepEqual(diff.AppState, wantAppState) {
+		t.Errorf("appState = %+v, want %+v", diff.AppState, wantAppState)
+	}
+	if diff.IsEmpty() {
+		t.Error("diff with changes reported as empty")
+	}
+}
@@ -0,0 +1,238 @@
+package scene
+
+import (
+	"sort"
+
+	"myScalidraw/pkg/excalidraw"
+)
+
+const (
+	WinnerServer = "server"
+	WinnerClient = "client"
+)
+
+// Conflict descreve um elemento alterado pelos dois lados desde a versão base.
+// Um elemento removido do array (sem tombstone) aparece com Removed.
+type Conflict struct {
+	ID            string `json:"id"`
+	Type          string `json:"type"`
+	Winner        string `json:"winner"`
+	ServerVersion int64  `json:"serverVersion"`
+	ClientVersion int64  `json:"clientVersion"`
+	ServerRemoved bool   `json:"serverRemoved,omitempty"`
+	ClientRemoved bool   `json:"clientRemoved,omitempty"`
+}
+
+type MergeResult struct {
+	Scene     *excalidraw.Scene `json:"-"`
+	Conflicts []Conflict        `json:"conflicts"`
+}
+
+// Merge combina duas edições feitas a partir da mesma base seguindo as regras
+// de reconciliação do Excalidraw: o lado que não mexeu no elemento cede ao
+// outro; se os dois mexeram, vence a maior version e, no empate, o menor
+// versionNonce. Tombstones (isDeleted) são elementos como quaisquer outros e
+// entram na mesma comparação.
+func Merge(base, server, client *excalidraw.Scene) *MergeResult {
+	result := &MergeResult{Conflicts: []Conflict{}}
+
+	baseElements := base.ElementsByID()
+	serverElements := server.ElementsByID()
+	clientElements := client.ElementsByID()
+
+	merged := make(map[string]*excalidraw.Element, len(serverElements)+len(clientElements))
+
+	for _, id := range mergeOrder(server, client) {
+		baseElement, inBase := baseElements[id]
+		serverElement, inServer := serverElements[id]
+		clientElement, inClient := clientElements[id]
+
+		serverChanged := !inBase || !inServer || !sameVersion(serverElement, baseElement)
+		clientChanged := !inBase || !inClient || !sameVersion(clientElement, baseElement)
+
+		switch {
+		case inServer && inClient:
+			if sameVersion(serverElement, clientElement) || !serverChanged {
+				merged[id] = clientElement
+				continue
+			}
+			if !clientChanged {
+				merged[id] = serverElement
+				continue
+			}
+
+			conflict := Conflict{
+				ID:            id,
+				Type:          clientElement.Type,
+				ServerVersion: serverElement.Version,
+				ClientVersion: clientElement.Version,
+			}
+			if keepServer(serverElement, clientElement) {
+				conflict.Winner = WinnerServer
+				merged[id] = serverElement
+			} else {
+				conflict.Winner = WinnerClient
+				merged[id] = clientElement
+			}
+			result.Conflicts = append(result.Conflicts, conflict)
+
+		case inClient:
+			// Removido no servidor. Se o cliente também não mexeu, a remoção vale.
+			if inBase && !clientChanged {
+				continue
+			}
+			merged[id] = clientElement
+			if inBase {
+				result.Conflicts = append(result.Conflicts, Conflict{
+					ID:            id,
+					Type:          clientElement.Type,
+					Winner:        WinnerClient,
+					ClientVersion: clientElement.Version,
+					ServerRemoved: true,
+				})
+			}
+
+		case inServer:
+			if inBase && !serverChanged {
+				continue
+			}
+			merged[id] = serverElement
+			if inBase {
+				result.Conflicts = append(result.Conflicts, Conflict{
+					ID:            id,
+					Type:          serverElement.Type,
+					Winner:        WinnerServer,
+					ServerVersion: serverElement.Version,
+					ClientRemoved: true,
+				})
+			}
+		}
+	}
+
+	elements := make([]excalidraw.Element, 0, len(merged))
+	for _, id := range mergeOrder(server, client) {
+		if element, ok := merged[id]; ok {
+			elements = append(elements, *element)
+		}
+	}
+	sortByFractionalIndex(elements)
+
+	// As chaves do topo que o modelo não conhece seguem as do cliente.
+	scene := *client
+	scene.Elements = elements
+	scene.AppState = mergeMaps(base.AppState, server.AppState, client.AppState)
+	scene.Files = mergeFiles(server.Files, client.Files)
+	result.Scene = &scene
+
+	return result
+}
+
+func sameVersion(a, b *excalidraw.Element) bool {
+	return a.Version == b.Version && a.VersionNonce == b.VersionNonce
+}
+
+// keepServer reproduz o shouldDiscardRemoteElement do Excalidraw, com o
+// servidor no papel do estado local.
+func keepServer(server, client *excalidraw.Element) bool {
+	if server.Version != client.Version {
+		return server.Version > client.Version
+	}
+	return server.VersionNonce <= client.VersionNonce
+}
+
+// mergeOrder segue a ordem do cliente e encaixa os elementos que só existem no
+// servidor logo depois do elemento que os precedia no servidor.
+func mergeOrder(server, client *excalidraw.Scene) []string {
+	var order []string
+	seen := map[string]bool{}
+
+	for _, element := range client.Elements {
+		if id := element.ID; id != "" && !seen[id] {
+			order = append(order, id)
+			seen[id] = true
+		}
+	}
+
+	previous := ""
+	for _, element := range server.Elements {
+		id := element.ID
+		if id == "" {
+			continue
+		}
+		if !seen[id] {
+			position := 0
+			if previous != "" {
+				for i, existing := range order {
+					if existing == previous {
+						position = i + 1
+						break
+					}
+				}
+			}
+			order = append(order[:position], append([]string{id}, order[position:]...)...)
+			seen[id] = true
+		}
+		previous = id
+	}
+
+	return order
+}
+
+// sortByFractionalIndex respeita o campo index das versões mais novas do
+// Excalidraw quando todos os elementos o possuem.
+func sortByFractionalIndex(elements []excalidraw.Element) {
+	for _, element := range elements {
+		if element.Index == nil || *element.Index == "" {
+			return
+		}
+	}
+
+	sort.SliceStable(elements, func(i, j int) bool {
+		return *elements[i].Index < *elements[j].Index
+	})
+}
+
+// mergeMaps aplica sobre o estado do servidor apenas as chaves que o cliente
+// alterou em relação à base.
+func mergeMaps(base, server, client excalidraw.AppState) excalidraw.AppState {
+	merged := make(excalidraw.AppState, len(server))
+	for key, value := range server {
+		merged[key] = value
+	}
+
+	keys := map[string]bool{}
+	for key := range base {
+		keys[key] = true
+	}
+	for key := range client {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		baseValue, inBase := base[key]
+		clientValue, inClient := client[key]
+		if inBase == inClient && sameJSON(baseValue, clientValue) {
+			continue
+		}
+
+		if inClient {
+			merged[key] = clientValue
+		} else {
+			delete(merged, key)
+		}
+	}
+
+	return merged
+}
+
+// Os arquivos embutidos são imutáveis por id, então basta unir os dois lados.
+func mergeFiles(server, client map[string]excalidraw.BinaryFile) map[string]excalidraw.BinaryFile {
+	merged := make(map[string]excalidraw.BinaryFile, len(server)+len(client))
+	for id, file := range server {
+		merged[id] = file
+	}
+	for id, file := range client {
+		merged[id] = file
+	}
+	return merged
+}
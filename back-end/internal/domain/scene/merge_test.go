@@ -0,0 +1,264 @@
+package scene
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"myScalidraw/pkg/excalidraw"
+)
+
+// element monta um elemento com x identificando de qual lado veio a versão:
+// 0 na base, 1 no servidor e 2 no cliente.
+func element(id string, version, nonce int, x int, extra ...string) string {
+	fields := append([]string{
+		fmt.Sprintf(`"id":%q`, id),
+		`"type":"rectangle"`,
+		fmt.Sprintf(`"version":%d`, version),
+		fmt.Sprintf(`"versionNonce":%d`, nonce),
+		fmt.Sprintf(`"x":%d`, x),
+	}, extra...)
+	return "{" + strings.Join(fields, ",") + "}"
+}
+
+func sceneOf(t *testing.T, elements ...string) *excalidraw.Scene {
+	t.Helper()
+	scene, err := excalidraw.Parse([]byte(`{"type":"excalidraw","elements":[` + strings.Join(elements, ",") + `]}`))
+	if err != nil {
+		t.Fatalf("parse scene: %v", err)
+	}
+	return scene
+}
+
+func ids(elements []excalidraw.Element) []string {
+	result := make([]string, len(elements))
+	for i, element := range elements {
+		result[i] = element.ID
+	}
+	return result
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      []string
+		server    []string
+		client    []string
+		want      map[string]int
+		deleted   []string
+		conflicts []Conflict
+	}{
+		{
+			name:   "only client edited",
+			base:   []string{element("a", 1, 10, 0)},
+			server: []string{element("a", 1, 10, 0)},
+			client: []string{element("a", 2, 20, 2)},
+			want:   map[string]int{"a": 2},
+		},
+		{
+			name:   "only server edited",
+			base:   []string{element("a", 1, 10, 0)},
+			server: []string{element("a", 2, 20, 1)},
+			client: []string{element("a", 1, 10, 0)},
+			want:   map[string]int{"a": 1},
+		},
+		{
+			name:   "same edit on both sides",
+			base:   []string{element("a", 1, 10, 0)},
+			server: []string{element("a", 2, 20, 1)},
+			client: []string{element("a", 2, 20, 1)},
+			want:   map[string]int{"a": 1},
+		},
+		{
+			name:      "concurrent edit, server has higher version",
+			base:      []string{element("a", 1, 10, 0)},
+			server:    []string{element("a", 3, 20, 1)},
+			client:    []string{element("a", 2, 30, 2)},
+			want:      map[string]int{"a": 1},
+			conflicts: []Conflict{{ID: "a", Type: "rectangle", Winner: WinnerServer, ServerVersion: 3, ClientVersion: 2}},
+		},
+		{
+			name:      "concurrent edit, client has higher version",
+			base:      []string{element("a", 1, 10, 0)},
+			server:    []string{element("a", 2, 20, 1)},
+			client:    []string{element("a", 4, 30, 2)},
+			want:      map[string]int{"a": 2},
+			conflicts: []Conflict{{ID: "a", Type: "rectangle", Winner: WinnerClient, ServerVersion: 2, ClientVersion: 4}},
+		},
+		{
+			name:      "version tie, server has lower nonce",
+			base:      []string{element("a", 1, 10, 0)},
+			server:    []string{element("a", 2, 5, 1)},
+			client:    []string{element("a", 2, 9, 2)},
+			want:      map[string]int{"a": 1},
+			conflicts: []Conflict{{ID: "a", Type: "rectangle", Winner: WinnerServer, ServerVersion: 2, ClientVersion: 2}},
+		},
+		{
+			name:      "version tie, client has lower nonce",
+			base:      []string{element("a", 1, 10, 0)},
+			server:    []string{element("a", 2, 9, 1)},
+			client:    []string{element("a", 2, 5, 2)},
+			want:      map[string]int{"a": 2},
+			conflicts: []Conflict{{ID: "a", Type: "rectangle", Winner: WinnerClient, ServerVersion: 2, ClientVersion: 2}},
+		},
+		{
+			name:   "removed on server, untouched by client",
+			base:   []string{element("a", 1, 10, 0), element("b", 1, 10, 0)},
+			server: []string{element("b", 1, 10, 0)},
+			client: []string{element("a", 1, 10, 0), element("b", 1, 10, 0)},
+			want:   map[string]int{"b": 0},
+		},
+		{
+			name:      "removed on server, edited by client",
+			base:      []string{element("a", 1, 10, 0)},
+			server:    []string{},
+			client:    []string{element("a", 2, 20, 2)},
+			want:      map[string]int{"a": 2},
+			conflicts: []Conflict{{ID: "a", Type: "rectangle", Winner: WinnerClient, ClientVersion: 2, ServerRemoved: true}},
+		},
+		{
+			name:      "removed by client, edited on server",
+			base:      []string{element("a", 1, 10, 0)},
+			server:    []string{element("a", 2, 20, 1)},
+			client:    []string{},
+			want:      map[string]int{"a": 1},
+			conflicts: []Conflict{{ID: "a", Type: "rectangle", Winner: WinnerServer, ServerVersion: 2, ClientRemoved: true}},
+		},
+		{
+			name:      "tombstone on server beats an older client edit",
+			base:      []string{element("a", 1, 10, 0)},
+			server:    []string{element("a", 3, 20, 0, `"isDeleted":true`)},
+			client:    []string{element("a", 2, 30, 2)},
+			want:      map[string]int{"a": 0},
+			deleted:   []string{"a"},
+			conflicts: []Conflict{{ID: "a", Type: "rectangle", Winner: WinnerServer, ServerVersion: 3, ClientVersion: 2}},
+		},
+		{
+			name:      "client edit beats an older tombstone",
+			base:      []string{element("a", 1, 10, 0)},
+			server:    []string{element("a", 2, 20, 0, `"isDeleted":true`)},
+			client:    []string{element("a", 5, 30, 2)},
+			want:      map[string]int{"a": 2},
+			conflicts: []Conflict{{ID: "a", Type: "rectangle", Winner: WinnerClient, ServerVersion: 2, ClientVersion: 5}},
+		},
+		{
+			name:   "added on both sides",
+			base:   []string{},
+			server: []string{element("s", 1, 10, 1)},
+			client: []string{element("c", 1, 10, 2)},
+			want:   map[string]int{"s": 1, "c": 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Merge(sceneOf(t, test.base...), sceneOf(t, test.server...), sceneOf(t, test.client...))
+
+			got := map[string]int{}
+			var deleted []string
+			for _, element := range result.Scene.Elements {
+				got[element.ID] = int(element.X)
+				if element.IsDeleted {
+					deleted = append(deleted, element.ID)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("elements = %v, want %v", got, test.want)
+			}
+			if !reflect.DeepEqual(deleted, test.deleted) {
+				t.Errorf("deleted = %v, want %v", deleted, test.deleted)
+			}
+
+			want := test.conflicts
+			if want == nil {
+				want = []Conflict{}
+			}
+			if !reflect.DeepEqual(result.Conflicts, want) {
+				t.Errorf("conflicts = %+v, want %+v", result.Conflicts, want)
+			}
+		})
+	}
+}
+
+func TestMergeOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		base   []string
+		server []string
+		client []string
+		want   []string
+	}{
+		{
+			name:   "client reorder is kept",
+			base:   []string{element("a", 1, 10, 0), element("b", 1, 10, 0)},
+			server: []string{element("a", 1, 10, 0), element("b", 1, 10, 0)},
+			client: []string{element("b", 1, 10, 0), element("a", 1, 10, 0)},
+			want:   []string{"b", "a"},
+		},
+		{
+			name:   "server-only element follows its server predecessor",
+			base:   []string{element("a", 1, 10, 0), element("b", 1, 10, 0)},
+			server: []string{element("a", 1, 10, 0), element("c", 1, 10, 1), element("b", 1, 10, 0)},
+			client: []string{element("a", 1, 10, 0), element("b", 1, 10, 0)},
+			want:   []string{"a", "c", "b"},
+		},
+		{
+			name:   "server-only element at the bottom",
+			base:   []string{element("a", 1, 10, 0)},
+			server: []string{element("c", 1, 10, 1), element("a", 1, 10, 0)},
+			client: []string{element("a", 1, 10, 0)},
+			want:   []string{"c", "a"},
+		},
+		{
+			name: "fractional index wins over array order",
+			base: []string{
+				element("a", 1, 10, 0, `"index":"a0"`),
+				element("b", 1, 10, 0, `"index":"a1"`),
+			},
+			server: []string{
+				element("a", 1, 10, 0, `"index":"a0"`),
+				element("b", 1, 10, 0, `"index":"a1"`),
+			},
+			client: []string{
+				element("b", 2, 20, 2, `"index":"a2"`),
+				element("a", 1, 10, 0, `"index":"a0"`),
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "array order is used when an index is missing",
+			base: []string{element("a", 1, 10, 0, `"index":"a0"`)},
+			server: []string{
+				element("a", 1, 10, 0, `"index":"a0"`),
+				element("b", 1, 10, 1),
+			},
+			client: []string{element("a", 1, 10, 0, `"index":"a0"`)},
+			want:   []string{"a", "b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Merge(sceneOf(t, test.base...), sceneOf(t, test.server...), sceneOf(t, test.client...))
+			if got := ids(result.Scene.Elements); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("order = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergeAppState(t *testing.T) {
+	// A base veio indentada do arquivo; a mesma cor compacta não é mudança.
+	base := &excalidraw.Scene{AppState: excalidraw.AppState{"viewBackgroundColor": json.RawMessage(`"#fff"`), "gridSize": json.RawMessage("20"), "zoom": json.RawMessage(`{ "value": 1 }`)}}
+	server := &excalidraw.Scene{AppState: excalidraw.AppState{"viewBackgroundColor": json.RawMessage(`"#fff"`), "gridSize": json.RawMessage("10"), "zoom": json.RawMessage(`{"value":2}`)}}
+	client := &excalidraw.Scene{AppState: excalidraw.AppState{"viewBackgroundColor": json.RawMessage(`"#000"`), "zoom": json.RawMessage(`{"value":1}`)}}
+
+	result := Merge(base, server, client)
+
+	want := excalidraw.AppState{"viewBackgroundColor": json.RawMessage(`"#000"`), "zoom": json.RawMessage(`{"value":2}`)}
+	if !reflect.DeepEqual(result.Scene.AppState, want) {
+		t.Errorf("appState = %v, want %v", result.Scene.AppState, want)
+	}
+}
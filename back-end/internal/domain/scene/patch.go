@@ -0,0 +1,100 @@
+package scene
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"myScalidraw/pkg/excalidraw"
+)
+
+// Patch é uma gravação parcial: só os elementos alterados, os ids removidos,
+// as chaves do appState que mudaram e os arquivos novos.
+type Patch struct {
+	Elements          []excalidraw.Element             `json:"elements"`
+	DeletedElementIDs []string                         `json:"deletedElementIds"`
+	AppState          excalidraw.AppState              `json:"appState"`
+	Files             map[string]excalidraw.BinaryFile `json:"files"`
+}
+
+func ParsePatch(content []byte) (*Patch, error) {
+	var patch Patch
+	if err := json.Unmarshal(content, &patch); err != nil {
+		return nil, fmt.Errorf("invalid patch JSON: %w", err)
+	}
+
+	for i, element := range patch.Elements {
+		if element.ID == "" {
+			return nil, fmt.Errorf("element at index %d has no id", i)
+		}
+	}
+
+	return &patch, nil
+}
+
+func (p *Patch) IsEmpty() bool {
+	return len(p.Elements) == 0 && len(p.DeletedElementIDs) == 0 && len(p.AppState) == 0 && len(p.Files) == 0
+}
+
+// Apply aplica o patch sobre a cena. Um elemento enviado que já está numa
+// versão mais nova na cena é descartado pelas mesmas regras do Merge e volta
+// como conflito. Ids removidos viram tombstones com a versão incrementada.
+// A cena não é validada aqui; quem grava o resultado valida.
+func (p *Patch) Apply(s *excalidraw.Scene) []Conflict {
+	conflicts := []Conflict{}
+
+	positions := make(map[string]int, len(s.Elements))
+	for i, element := range s.Elements {
+		positions[element.ID] = i
+	}
+
+	for i := range p.Elements {
+		element := &p.Elements[i]
+		position, exists := positions[element.ID]
+		if !exists {
+			positions[element.ID] = len(s.Elements)
+			s.Elements = append(s.Elements, *element)
+			continue
+		}
+
+		stored := &s.Elements[position]
+		if sameVersion(stored, element) {
+			s.Elements[position] = *element
+			continue
+		}
+		if keepServer(stored, element) {
+			conflicts = append(conflicts, Conflict{
+				ID:            element.ID,
+				Type:          stored.Type,
+				Winner:        WinnerServer,
+				ServerVersion: stored.Version,
+				ClientVersion: element.Version,
+			})
+			continue
+		}
+		s.Elements[position] = *element
+	}
+
+	for _, id := range p.DeletedElementIDs {
+		position, exists := positions[id]
+		if !exists || s.Elements[position].IsDeleted {
+			continue
+		}
+		s.Elements[position] = s.Elements[position].Tombstone()
+	}
+
+	if len(p.AppState) > 0 && s.AppState == nil {
+		s.AppState = excalidraw.AppState{}
+	}
+	for key, value := range p.AppState {
+		s.AppState[key] = value
+	}
+
+	if len(p.Files) > 0 && s.Files == nil {
+		s.Files = map[string]excalidraw.BinaryFile{}
+	}
+	for id, file := range p.Files {
+		s.Files[id] = file
+	}
+
+	return conflicts
+}
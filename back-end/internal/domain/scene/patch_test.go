@@ -0,0 +1,176 @@
+package scene
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"myScalidraw/pkg/excalidraw"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name      string
+		stored    []string
+		patch     string
+		want      map[string]int
+		versions  map[string]int64
+		deleted   []string
+		order     []string
+		conflicts []Conflict
+	}{
+		{
+			name:     "new element is appended",
+			stored:   []string{element("a", 1, 10, 0)},
+			patch:    `{"elements":[` + element("b", 1, 10, 2) + `]}`,
+			want:     map[string]int{"a": 0, "b": 2},
+			versions: map[string]int64{"a": 1, "b": 1},
+			order:    []string{"a", "b"},
+		},
+		{
+			name:     "newer element replaces the stored one",
+			stored:   []string{element("a", 1, 10, 0), element("b", 1, 10, 0)},
+			patch:    `{"elements":[` + element("a", 2, 20, 2) + `]}`,
+			want:     map[string]int{"a": 2, "b": 0},
+			versions: map[string]int64{"a": 2, "b": 1},
+			order:    []string{"a", "b"},
+		},
+		{
+			name:      "stale element is rejected",
+			stored:    []string{element("a", 3, 10, 1)},
+			patch:     `{"elements":[` + element("a", 2, 20, 2) + `]}`,
+			want:      map[string]int{"a": 1},
+			versions:  map[string]int64{"a": 3},
+			order:     []string{"a"},
+			conflicts: []Conflict{{ID: "a", Type: "rectangle", Winner: WinnerServer, ServerVersion: 3, ClientVersion: 2}},
+		},
+		{
+			name:      "version tie keeps the lower nonce",
+			stored:    []string{element("a", 2, 5, 1)},
+			patch:     `{"elements":[` + element("a", 2, 9, 2) + `]}`,
+			want:      map[string]int{"a": 1},
+			versions:  map[string]int64{"a": 2},
+			order:     []string{"a"},
+			conflicts: []Conflict{{ID: "a", Type: "rectangle", Winner: WinnerServer, ServerVersion: 2, ClientVersion: 2}},
+		},
+		{
+			name:     "deleted id becomes a tombstone",
+			stored:   []string{element("a", 1, 10, 0), element("b", 4, 10, 0)},
+			patch:    `{"deletedElementIds":["b"]}`,
+			want:     map[string]int{"a": 0, "b": 0},
+			versions: map[string]int64{"a": 1, "b": 5},
+			deleted:  []string{"b"},
+			order:    []string{"a", "b"},
+		},
+		{
+			name:     "deleting an existing tombstone keeps its version",
+			stored:   []string{element("a", 3, 10, 0, `"isDeleted":true`)},
+			patch:    `{"deletedElementIds":["a"]}`,
+			want:     map[string]int{"a": 0},
+			versions: map[string]int64{"a": 3},
+			deleted:  []string{"a"},
+			order:    []string{"a"},
+		},
+		{
+			name:     "deleting an unknown id is ignored",
+			stored:   []string{element("a", 1, 10, 0)},
+			patch:    `{"deletedElementIds":["missing"]}`,
+			want:     map[string]int{"a": 0},
+			versions: map[string]int64{"a": 1},
+			order:    []string{"a"},
+		},
+		{
+			name:     "element added and deleted in the same patch",
+			stored:   []string{},
+			patch:    `{"elements":[` + element("a", 1, 10, 2) + `],"deletedElementIds":["a"]}`,
+			want:     map[string]int{"a": 2},
+			versions: map[string]int64{"a": 2},
+			deleted:  []string{"a"},
+			order:    []string{"a"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stored := sceneOf(t, test.stored...)
+			patch, err := ParsePatch([]byte(test.patch))
+			if err != nil {
+				t.Fatalf("parse patch: %v", err)
+			}
+
+			conflicts := patch.Apply(stored)
+
+			got := map[string]int{}
+			versions := map[string]int64{}
+			var deleted []string
+			for _, element := range stored.Elements {
+				got[element.ID] = int(element.X)
+				versions[element.ID] = element.Version
+				if element.IsDeleted {
+					deleted = append(deleted, element.ID)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("elements = %v, want %v", got, test.want)
+			}
+			if !reflect.DeepEqual(versions, test.versions) {
+				t.Errorf("versions = %v, want %v", versions, test.versions)
+			}
+			if !reflect.DeepEqual(deleted, test.deleted) {
+				t.Errorf("deleted = %v, want %v", deleted, test.deleted)
+			}
+			if order := ids(stored.Elements); !reflect.DeepEqual(order, test.order) {
+				t.Errorf("order = %v, want %v", order, test.order)
+			}
+
+			want := test.conflicts
+			if want == nil {
+				want = []Conflict{}
+			}
+			if !reflect.DeepEqual(conflicts, want) {
+				t.Errorf("conflicts = %+v, want %+v", conflicts, want)
+			}
+		})
+	}
+}
+
+func TestApplyTombstoneWinsReconciliation(t *testing.T) {
+	stored := sceneOf(t, element("a", 4, 10, 0))
+	original := stored.Elements[0]
+
+	(&Patch{DeletedElementIDs: []string{"a"}}).Apply(stored)
+
+	tombstone := stored.Elements[0]
+	if original.IsDeleted {
+		t.Fatal("Apply changed the stored element in place")
+	}
+	if keepServer(&original, &tombstone) {
+		t.Error("tombstone loses to the element it deletes")
+	}
+}
+
+func TestApplyAppStateAndFiles(t *testing.T) {
+	stored := sceneOf(t)
+	stored.AppState["gridSize"] = json.RawMessage("20")
+	stored.Files = map[string]excalidraw.BinaryFile{"f1": {ID: "f1"}}
+
+	(&Patch{
+		AppState: excalidraw.AppState{"viewBackgroundColor": json.RawMessage(`"#000"`)},
+		Files:    map[string]excalidraw.BinaryFile{"f2": {ID: "f2"}},
+	}).Apply(stored)
+
+	wantAppState := excalidraw.AppState{"gridSize": json.RawMessage("20"), "viewBackgroundColor": json.RawMessage(`"#000"`)}
+	if !reflect.DeepEqual(stored.AppState, wantAppState) {
+		t.Errorf("appState = %v, want %v", stored.AppState, wantAppState)
+	}
+	wantFiles := map[string]excalidraw.BinaryFile{"f1": {ID: "f1"}, "f2": {ID: "f2"}}
+	if !reflect.DeepEqual(stored.Files, wantFiles) {
+		t.Errorf("files = %v, want %v", stored.Files, wantFiles)
+	}
+}
+
+func TestParsePatchRequiresIDs(t *testing.T) {
+	if _, err := ParsePatch([]byte(`{"elements":[{"type":"rectangle"}]}`)); err == nil {
+		t.Error("patch with an element without id was accepted")
+	}
+}
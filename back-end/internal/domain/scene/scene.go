@@ -0,0 +1,24 @@
+package scene
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// O pacote compara, mescla e aplica patches sobre o modelo de pkg/excalidraw.
+// Os elementos carregam o JSON de onde vieram, então o resultado gravado
+// mantém os campos que o modelo não conhece.
+
+// sameJSON compara dois valores ignorando a formatação, já que a mesma cena
+// pode vir indentada do arquivo e compacta do editor.
+func sameJSON(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	var compactA, compactB bytes.Buffer
+	if json.Compact(&compactA, a) != nil || json.Compact(&compactB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(compactA.Bytes(), compactB.Bytes())
+}
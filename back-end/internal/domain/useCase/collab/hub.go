@@ -0,0 +1,176 @@
+package collab
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"myScalidraw/internal/domain/useCase/file"
+	"myScalidraw/pkg/excalidraw"
+	"myScalidraw/pkg/projectError"
+	"myScalidraw/pkg/uuid"
+)
+
+const (
+	defaultSaveDebounce = 2 * time.Second
+	participantBuffer   = 256
+)
+
+// Hub mantém uma sala por arquivo aberto. A sala é criada com o conteúdo
+// gravado quando entra o primeiro participante e é gravada e descartada
+// quando sai o último.
+type Hub struct {
+	files        *file.FileUseCase
+	saveDebounce time.Duration
+
+	mu      sync.Mutex
+	rooms   map[string]*Room
+	opening map[string]*opening
+}
+
+// opening marca uma sala sendo aberta fora do lock do hub. Quem chega nesse
+// meio tempo espera done em vez de abrir outra sala para o mesmo arquivo.
+type opening struct {
+	done chan struct{}
+	err  error
+}
+
+func NewHub(files *file.FileUseCase) *Hub {
+	return &Hub{
+		files:        files,
+		saveDebounce: defaultSaveDebounce,
+		rooms:        map[string]*Room{},
+		opening:      map[string]*opening{},
+	}
+}
+
+func (h *Hub) Join(fileID string, username string) (*Room, *Participant, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	participant := &Participant{
+		ID:       id,
+		Username: username,
+		Send:     make(chan []byte, participantBuffer),
+	}
+
+	for {
+		h.mu.Lock()
+		if room, ok := h.rooms[fileID]; ok {
+			// Entra ainda com o lock do hub para que a sala não seja fechada
+			// entre ser encontrada e receber o participante.
+			room.join(participant)
+			h.mu.Unlock()
+			return room, participant, nil
+		}
+
+		if pending, ok := h.opening[fileID]; ok {
+			h.mu.Unlock()
+			<-pending.done
+			if pending.err != nil {
+				return nil, nil, pending.err
+			}
+			continue
+		}
+
+		pending := &opening{done: make(chan struct{})}
+		h.opening[fileID] = pending
+		h.mu.Unlock()
+
+		// A leitura do arquivo acontece sem o lock, para não segurar as
+		// entradas e saídas das outras salas.
+		room, err := h.openRoom(fileID)
+
+		h.mu.Lock()
+		delete(h.opening, fileID)
+		if err == nil {
+			h.rooms[fileID] = room
+		}
+		pending.err = err
+		close(pending.done)
+		h.mu.Unlock()
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+func (h *Hub) openRoom(fileID string) (*Room, error) {
+	item, err := h.files.GetFileByID(fileID)
+	if err != nil || item == nil {
+		return nil, projectError.Errorf(projectError.ENOTFOUND, "file not found: %s", fileID)
+	}
+	if item.IsFolder {
+		return nil, projectError.Errorf(projectError.EINVALID, "folders cannot be edited: %s", fileID)
+	}
+
+	content, err := h.files.GetFileContentAt(fileID, item.Revision)
+	if err != nil {
+		if item.Revision > 0 {
+			return nil, err
+		}
+		content = "{}"
+	}
+
+	current, err := excalidraw.Parse([]byte(content))
+	if err != nil {
+		return nil, &projectError.Error{
+			Code:      projectError.EINVALID,
+			Message:   "stored content is not a valid scene",
+			PrevError: err,
+		}
+	}
+
+	log.Printf("Opening collaboration room for %s at revision %d", fileID, item.Revision)
+	return &Room{
+		FileID:       fileID,
+		hub:          h,
+		scene:        current,
+		revision:     item.Revision,
+		participants: map[string]*Participant{},
+	}, nil
+}
+
+// Leave tira o participante da sala. O último a sair grava a sala antes de
+// ela sair do hub: quem entrar durante a gravação cai nesta mesma sala em vez
+// de abrir outra com o conteúdo antigo. Se a gravação falhar a sala continua
+// aberta, com as alterações, e o timer da sala tenta de novo até conseguir.
+func (h *Hub) Leave(room *Room, participant *Participant) {
+	if !room.Leave(participant) {
+		return
+	}
+
+	room.Flush()
+	h.closeIfIdle(room)
+}
+
+// closeIfIdle tira do hub a sala sem participantes e sem nada por gravar.
+func (h *Hub) closeIfIdle(room *Room) {
+	h.mu.Lock()
+	closed := h.rooms[room.FileID] == room && room.isIdle()
+	if closed {
+		delete(h.rooms, room.FileID)
+	}
+	h.mu.Unlock()
+
+	if closed {
+		log.Printf("Closed collaboration room for %s", room.FileID)
+	}
+}
+
+// Close grava todas as salas abertas; usado no desligamento do servidor.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	rooms := make([]*Room, 0, len(h.rooms))
+	for _, room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	h.mu.Unlock()
+
+	for _, room := range rooms {
+		room.Flush()
+	}
+}
@@ -0,0 +1,376 @@
+package collab
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"myScalidraw/internal/domain/scene"
+	"myScalidraw/pkg/excalidraw"
+)
+
+const (
+	MessageInit         = "init"
+	MessageSceneUpdate  = "scene-update"
+	MessagePointer      = "pointer"
+	MessageViewport     = "viewport"
+	MessageFollow       = "follow"
+	MessageUnfollow     = "unfollow"
+	MessageParticipants = "participants"
+	MessageSaved        = "saved"
+	MessageError        = "error"
+)
+
+// Message é usado nos dois sentidos. Pointer e viewport são repassados como
+// vieram em Payload, só com From preenchido pelo servidor.
+type Message struct {
+	Type         string                           `json:"type"`
+	From         string                           `json:"from,omitempty"`
+	Username     string                           `json:"username,omitempty"`
+	SocketID     string                           `json:"socketId,omitempty"`
+	Target       string                           `json:"target,omitempty"`
+	Revision     int                              `json:"revision,omitempty"`
+	Elements     []excalidraw.Element             `json:"elements,omitempty"`
+	AppState     excalidraw.AppState              `json:"appState,omitempty"`
+	Files        map[string]excalidraw.BinaryFile `json:"files,omitempty"`
+	Payload      json.RawMessage                  `json:"payload,omitempty"`
+	Participants []ParticipantInfo                `json:"participants,omitempty"`
+	Message      string                           `json:"message,omitempty"`
+}
+
+type ParticipantInfo struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// Participant é uma conexão numa sala. O handler escreve no socket tudo o que
+// chegar em Send; o canal é fechado quando o participante sai da sala.
+type Participant struct {
+	ID       string
+	Username string
+	Send     chan []byte
+
+	following string
+}
+
+// Room guarda a cena reconciliada de um arquivo enquanto houver alguém
+// editando. As alterações são gravadas depois de saveDebounce sem mudanças.
+type Room struct {
+	FileID string
+
+	hub          *Hub
+	mu           sync.Mutex
+	saveMu       sync.Mutex
+	scene        *excalidraw.Scene
+	revision     int
+	participants map[string]*Participant
+	dirty        bool
+	saveTimer    *time.Timer
+}
+
+func (r *Room) info() []ParticipantInfo {
+	participants := make([]ParticipantInfo, 0, len(r.participants))
+	for _, participant := range r.participants {
+		participants = append(participants, ParticipantInfo{ID: participant.ID, Username: participant.Username})
+	}
+	return participants
+}
+
+// send precisa ser chamado com o lock da sala. Um participante que não dá
+// conta de ler as mensagens é removido em vez de travar a sala.
+func (r *Room) send(participant *Participant, message Message) {
+	content, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error encoding %s message for room %s: %v", message.Type, r.FileID, err)
+		return
+	}
+
+	select {
+	case participant.Send <- content:
+	default:
+		log.Printf("Dropping slow participant %s from room %s", participant.ID, r.FileID)
+		r.remove(participant)
+	}
+}
+
+func (r *Room) broadcast(message Message, except string) {
+	for id, participant := range r.participants {
+		if id != except {
+			r.send(participant, message)
+		}
+	}
+}
+
+func (r *Room) remove(participant *Participant) bool {
+	if r.participants[participant.ID] != participant {
+		return false
+	}
+	delete(r.participants, participant.ID)
+	close(participant.Send)
+	return true
+}
+
+func (r *Room) join(participant *Participant) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.participants[participant.ID] = participant
+
+	r.send(participant, Message{
+		Type:         MessageInit,
+		SocketID:     participant.ID,
+		Revision:     r.revision,
+		Elements:     r.scene.Elements,
+		AppState:     r.scene.AppState,
+		Files:        r.scene.Files,
+		Participants: r.info(),
+	})
+	r.broadcast(Message{Type: MessageParticipants, Participants: r.info()}, participant.ID)
+}
+
+// Leave tira o participante da sala e informa quem ficou. Retorna true quando
+// a sala ficou vazia.
+func (r *Room) Leave(participant *Participant) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.remove(participant)
+	r.broadcast(Message{Type: MessageParticipants, Participants: r.info()}, "")
+	return len(r.participants) == 0
+}
+
+// Reject avisa o participante de uma mensagem que não pôde ser lida.
+func (r *Room) Reject(participant *Participant, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.participants[participant.ID] == participant {
+		r.send(participant, Message{Type: MessageError, Message: "invalid message: " + err.Error()})
+	}
+}
+
+// Handle processa uma mensagem recebida de um participante.
+func (r *Room) Handle(participant *Participant, message Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.participants[participant.ID] != participant {
+		return
+	}
+
+	switch message.Type {
+	case MessageSceneUpdate:
+		r.applyUpdate(participant, message)
+
+	case MessagePointer:
+		r.broadcast(Message{
+			Type:     MessagePointer,
+			From:     participant.ID,
+			Username: participant.Username,
+			Payload:  message.Payload,
+		}, participant.ID)
+
+	case MessageViewport:
+		for _, follower := range r.participants {
+			if follower.following == participant.ID {
+				r.send(follower, Message{
+					Type:     MessageViewport,
+					From:     participant.ID,
+					Username: participant.Username,
+					Payload:  message.Payload,
+				})
+			}
+		}
+
+	case MessageFollow:
+		if _, ok := r.participants[message.Target]; ok && message.Target != participant.ID {
+			participant.following = message.Target
+		}
+
+	case MessageUnfollow:
+		participant.following = ""
+
+	default:
+		r.send(participant, Message{Type: MessageError, Message: "unknown message type: " + message.Type})
+	}
+}
+
+// applyUpdate reconcilia os elementos recebidos com a cena da sala. Os que
+// vencerem vão para os outros participantes. Os que perderem ou forem
+// inválidos voltam para o remetente na versão da sala, para que ele convirja;
+// um elemento inválido que a sala não conhece volta como removido.
+func (r *Room) applyUpdate(participant *Participant, message Message) {
+	valid := make([]excalidraw.Element, 0, len(message.Elements))
+	var invalid []excalidraw.Element
+	var invalidErr error
+	for _, element := range message.Elements {
+		if err := element.ValidateDraft(); err != nil {
+			invalid = append(invalid, element)
+			if invalidErr == nil {
+				invalidErr = err
+			}
+			continue
+		}
+		valid = append(valid, element)
+	}
+
+	patch := &scene.Patch{Elements: valid, Files: message.Files}
+	conflicts := patch.Apply(r.scene)
+
+	rejected := make(map[string]bool, len(conflicts))
+	for _, conflict := range conflicts {
+		rejected[conflict.ID] = true
+	}
+
+	accepted := make([]excalidraw.Element, 0, len(valid))
+	for _, element := range valid {
+		if !rejected[element.ID] {
+			accepted = append(accepted, element)
+		}
+	}
+
+	if len(accepted) > 0 || len(message.Files) > 0 {
+		r.broadcast(Message{
+			Type:     MessageSceneUpdate,
+			From:     participant.ID,
+			Elements: accepted,
+			Files:    message.Files,
+		}, participant.ID)
+		r.markDirty()
+	}
+
+	if invalidErr != nil {
+		r.send(participant, Message{Type: MessageError, Message: invalidErr.Error()})
+	}
+
+	if len(conflicts) > 0 || len(invalid) > 0 {
+		current := r.scene.ElementsByID()
+		authoritative := make([]excalidraw.Element, 0, len(conflicts)+len(invalid))
+		for _, conflict := range conflicts {
+			authoritative = append(authoritative, *current[conflict.ID])
+		}
+		for _, element := range invalid {
+			if stored, ok := current[element.ID]; ok {
+				authoritative = append(authoritative, *stored)
+			} else if element.ID != "" {
+				authoritative = append(authoritative, element.Tombstone())
+			}
+		}
+		r.send(participant, Message{Type: MessageSceneUpdate, Elements: authoritative})
+	}
+}
+
+func (r *Room) markDirty() {
+	r.dirty = true
+	if r.saveTimer == nil {
+		r.saveTimer = time.AfterFunc(r.hub.saveDebounce, r.flushAndClose)
+		return
+	}
+	r.saveTimer.Reset(r.hub.saveDebounce)
+}
+
+// flushAndClose roda no timer. Uma sala que ficou vazia com a gravação
+// falhando só sai do hub quando uma dessas tentativas dá certo.
+func (r *Room) flushAndClose() {
+	r.Flush()
+	r.hub.closeIfIdle(r)
+}
+
+// Flush grava a cena da sala se houver alterações pendentes. A gravação
+// acontece fora do lock da sala para não segurar as mensagens dos outros.
+// Ela parte da revisão que a sala carregou ou gravou por último, então uma
+// gravação feita por fora nesse meio tempo é mesclada em vez de sobrescrita, e
+// o resultado volta para os participantes.
+func (r *Room) Flush() {
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+
+	r.mu.Lock()
+	if !r.dirty {
+		r.mu.Unlock()
+		return
+	}
+	sent := r.savable()
+	baseRevision := r.revision
+	r.dirty = false
+	r.mu.Unlock()
+
+	result, err := r.hub.files.SaveSceneMerging(r.FileID, sent, baseRevision)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Error saving scene of room %s: %v", r.FileID, err)
+		r.markDirty()
+		r.broadcast(Message{Type: MessageError, Message: "error saving drawing"}, "")
+		return
+	}
+
+	if result.Merged {
+		r.adopt(sent, result.Scene)
+	}
+
+	r.revision = result.Revision.Revision
+	r.broadcast(Message{Type: MessageSaved, Revision: result.Revision.Revision}, "")
+}
+
+// savable devolve a cena sem os elementos que ainda não passam na validação
+// das gravações, como uma seta com um ponto só. As edições ao vivo usam as
+// regras de rascunho, então esses elementos ficam na sala até estarem
+// completos.
+func (r *Room) savable() *excalidraw.Scene {
+	savable := *r.scene
+	savable.Elements = make([]excalidraw.Element, 0, len(r.scene.Elements))
+	for _, element := range r.scene.Elements {
+		if element.Validate() == nil {
+			savable.Elements = append(savable.Elements, element)
+		}
+	}
+	return &savable
+}
+
+// adopt troca a cena da sala pela que foi gravada depois de mesclada com uma
+// gravação feita por fora. O que os participantes mudaram enquanto a gravação
+// acontecia é mesclado por cima, e os elementos que mudaram são enviados a
+// todos. Os que sumiram viram tombstones para que os participantes também os
+// removam.
+func (r *Room) adopt(sent, saved *excalidraw.Scene) {
+	previous := r.scene.ElementsByID()
+	merged := scene.Merge(sent, saved, r.scene).Scene
+
+	current := merged.ElementsByID()
+	for _, element := range r.scene.Elements {
+		if _, ok := current[element.ID]; !ok && !element.IsDeleted {
+			merged.Elements = append(merged.Elements, element.Tombstone())
+		}
+	}
+
+	changed := []excalidraw.Element{}
+	for _, element := range merged.Elements {
+		old, ok := previous[element.ID]
+		if !ok || old.Version != element.Version || old.VersionNonce != element.VersionNonce {
+			changed = append(changed, element)
+		}
+	}
+
+	files := map[string]excalidraw.BinaryFile{}
+	for id, file := range merged.Files {
+		if _, ok := r.scene.Files[id]; !ok {
+			files[id] = file
+		}
+	}
+
+	r.scene = merged
+	if len(changed) > 0 || len(files) > 0 {
+		r.broadcast(Message{Type: MessageSceneUpdate, Elements: changed, Files: files}, "")
+	}
+}
+
+// isIdle indica uma sala sem participantes e sem alterações por gravar.
+func (r *Room) isIdle() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.participants) == 0 && !r.dirty
+}
@@ -0,0 +1,80 @@
+package file
+
+import (
+	"errors"
+
+	"myScalidraw/internal/domain/models"
+	"myScalidraw/pkg/excalidraw"
+	"myScalidraw/pkg/projectError"
+	"myScalidraw/pkg/render"
+)
+
+// RenderFile monta o desenho da revisão atual do arquivo para exportação e
+// devolve também a revisão, que serve de ETag.
+func (uc *FileUseCase) RenderFile(id string, options render.Options) (*render.Drawing, int, error) {
+	scene, revision, err := uc.loadScene(id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	drawing, err := render.Build(scene, options)
+	if err != nil {
+		return nil, 0, renderError(err, options)
+	}
+	return drawing, revision, nil
+}
+
+// RenderPages monta uma página por frame, ou uma só com a cena inteira quando
+// não há frames.
+func (uc *FileUseCase) RenderPages(id string, options render.Options) ([]*render.Drawing, int, error) {
+	scene, revision, err := uc.loadScene(id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pages, err := render.BuildPages(scene, options)
+	if err != nil {
+		return nil, 0, renderError(err, options)
+	}
+	return pages, revision, nil
+}
+
+func (uc *FileUseCase) loadScene(id string) (*excalidraw.Scene, int, error) {
+	metadata, err := uc.metadataRepo.GetByID(id)
+	if err != nil {
+		return nil, 0, projectError.Errorf(projectError.ENOTFOUND, "file not found: %s", id)
+	}
+	if metadata.IsFolder {
+		return nil, 0, projectError.Errorf(projectError.EINVALID, "cannot export a folder")
+	}
+
+	scene, err := uc.parseRevision(metadata)
+	if err != nil {
+		return nil, 0, err
+	}
+	return scene, metadata.Revision, nil
+}
+
+func (uc *FileUseCase) parseRevision(metadata *models.FileMetadata) (*excalidraw.Scene, error) {
+	content, err := uc.GetFileContentAt(metadata.ID, metadata.Revision)
+	if err != nil {
+		return nil, err
+	}
+
+	scene, err := excalidraw.Parse([]byte(content))
+	if err != nil {
+		return nil, &projectError.Error{
+			Code:      projectError.EINVALID,
+			Message:   "stored scene is invalid: " + err.Error(),
+			PrevError: err,
+		}
+	}
+	return scene, nil
+}
+
+func renderError(err error, options render.Options) error {
+	if errors.Is(err, render.ErrFrameNotFound) {
+		return projectError.Errorf(projectError.ENOTFOUND, "frame not found: %s", options.Frame)
+	}
+	return err
+}
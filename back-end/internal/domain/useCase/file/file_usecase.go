@@ -2,34 +2,132 @@ package file
 
 import (
 	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"myScalidraw/internal/domain/events"
 	"myScalidraw/internal/domain/models"
 	"myScalidraw/internal/domain/repository"
+	"myScalidraw/internal/domain/scene"
+	"myScalidraw/pkg/excalidraw"
+	"myScalidraw/pkg/projectError"
+
+	"gorm.io/gorm"
 )
 
 type FileUseCase struct {
 	fileRepo     repository.FileRepository
 	metadataRepo repository.FileMetadataRepository
+	events       *events.Broker
+
+	// pathMu serializa as gravações por caminho deste processo, para que duas
+	// criações concorrentes das mesmas pastas intermediárias não esbarrem uma
+	// na outra. A unicidade em si vem do índice único de caminho no banco, que
+	// vale para todas as rotas e devolve ECONFLICT.
+	pathMu sync.Mutex
+
+	thumbnails *thumbnailQueue
 }
 
-func NewFileUseCase(fileRepo repository.FileRepository, metadataRepo repository.FileMetadataRepository) *FileUseCase {
+func NewFileUseCase(fileRepo repository.FileRepository, metadataRepo repository.FileMetadataRepository, broker *events.Broker) *FileUseCase {
 	return &FileUseCase{
 		fileRepo:     fileRepo,
 		metadataRepo: metadataRepo,
+		events:       broker,
+		thumbnails:   newThumbnailQueue(),
 	}
 }
 
-func (uc *FileUseCase) GetFiles() []models.FileItem {
+func (uc *FileUseCase) SubscribeEvents() (<-chan events.Event, func()) {
+	return uc.events.Subscribe()
+}
 
-	metadata, err := uc.metadataRepo.GetAll()
+// publish avisa os inscritos sobre uma alteração já gravada no item id.
+func (uc *FileUseCase) publish(eventType string, id string) {
+	metadata, err := uc.metadataRepo.GetByID(id)
 	if err != nil {
+		return
+	}
+
+	item := metadata.ToFileItem()
+	uc.events.Publish(eventType, &item)
+}
+
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+// ListFiles devolve uma página da listagem e o cursor da próxima, vazio
+// quando não há mais itens.
+func (uc *FileUseCase) ListFiles(query *models.FileQuery) ([]models.FileItem, string, error) {
+	switch query.SortBy {
+	case "":
+		query.SortBy = models.SortByName
+	case models.SortByName, models.SortByLastModified, models.SortBySize:
+	default:
+		return nil, "", projectError.Errorf(projectError.EINVALID, "cannot sort by %s", query.SortBy)
+	}
+
+	if query.Limit < 0 || query.Limit > MaxListLimit {
+		return nil, "", projectError.Errorf(projectError.EINVALID, "limit must be between 1 and %d", MaxListLimit)
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultListLimit
+	}
+	if query.After != nil && !query.After.Matches(query) {
+		return nil, "", projectError.Errorf(projectError.EINVALID, "cursor was created for a different sort, order or filter")
+	}
+	if query.After != nil {
+		if _, err := query.After.SortValue(); err != nil {
+			return nil, "", projectError.Errorf(projectError.EINVALID, "invalid cursor value")
+		}
+	}
+
+	// Um item a mais diz se existe uma próxima página.
+	limit := query.Limit
+	query.Limit = limit + 1
 
-		return []models.FileItem{}
+	metadata, err := uc.metadataRepo.List(query)
+	if err != nil {
+		return nil, "", err
 	}
 
-	flatList := metadata.ToFlatList()
+	next := ""
+	if len(metadata) > limit {
+		metadata = metadata[:limit]
+		next = models.NewFileCursor(query, metadata[limit-1]).Encode()
+	}
 
-	return flatList
+	items := metadata.ToFlatList()
+	if items == nil {
+		items = []models.FileItem{}
+	}
+	return items, next, nil
+}
+
+// GetTree devolve a árvore abaixo da pasta rootID ("" para a raiz) até depth
+// níveis, com 0 sem limite.
+func (uc *FileUseCase) GetTree(rootID string, depth int) ([]models.FileItem, error) {
+	if rootID != "" {
+		root, err := uc.metadataRepo.GetByID(rootID)
+		if err != nil {
+			return nil, projectError.Errorf(projectError.ENOTFOUND, "folder not found: %s", rootID)
+		}
+		if !root.IsFolder {
+			return nil, projectError.Errorf(projectError.EINVALID, "%s is not a folder", rootID)
+		}
+	}
+
+	metadata, err := uc.metadataRepo.GetSubtree(rootID, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata.ToTree(rootID, depth), nil
 }
 
 func (uc *FileUseCase) GetFileByID(id string) (*models.FileItem, error) {
@@ -51,35 +149,289 @@ func (uc *FileUseCase) GetFileByID(id string) (*models.FileItem, error) {
 	return file, nil
 }
 
-func (uc *FileUseCase) SaveFile(id string, content string) error {
-	return uc.fileRepo.SaveFile(id, content)
+// validateScene recusa conteúdo que não é uma cena válida do Excalidraw antes
+// que ele vire uma revisão.
+func validateScene(content []byte) error {
+	if _, err := excalidraw.Parse(content); err != nil {
+		return invalidScene(err)
+	}
+	return nil
+}
+
+func invalidScene(err error) error {
+	return &projectError.Error{
+		Code:      projectError.EINVALID,
+		Message:   err.Error(),
+		PrevError: err,
+	}
+}
+
+func (uc *FileUseCase) SaveFile(id string, content string) (*models.FileRevision, error) {
+	if err := validateScene([]byte(content)); err != nil {
+		return nil, err
+	}
+
+	revision, err := uc.fileRepo.SaveFile(id, content)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.queueThumbnail(id)
+	uc.publish(events.FileSaved, id)
+	return revision, nil
+}
+
+func (uc *FileUseCase) SaveFileIfMatch(id string, content string, revision int) (*models.FileRevision, error) {
+	if err := validateScene([]byte(content)); err != nil {
+		return nil, err
+	}
+	return uc.saveIfMatch(id, content, revision)
+}
+
+// saveIfMatch é o SaveFileIfMatch para conteúdo que já foi validado.
+func (uc *FileUseCase) saveIfMatch(id string, content string, revision int) (*models.FileRevision, error) {
+	saved, err := uc.fileRepo.SaveFileIfMatch(id, content, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.queueThumbnail(id)
+	uc.publish(events.FileSaved, id)
+	return saved, nil
 }
 
 func (uc *FileUseCase) CreateFile(metadata *models.FileMetadata, content []byte) error {
-	err := uc.metadataRepo.Create(metadata)
+	if !metadata.IsFolder && len(content) > 0 {
+		if err := validateScene(content); err != nil {
+			return err
+		}
+	}
+
+	err := uc.fileRepo.CreateFile(metadata, content)
 	if err != nil {
 		return err
 	}
 
-	if metadata.IsFolder {
-		return uc.fileRepo.CreateFolder(metadata.Path)
+	if !metadata.IsFolder {
+		uc.queueThumbnail(metadata.ID)
 	}
+	uc.publish(events.FileCreated, metadata.ID)
+	return nil
+}
 
-	if len(content) > 0 {
-		return uc.fileRepo.UploadFile(metadata.ID, content)
+func (uc *FileUseCase) DeleteFile(id string) error {
+	metadata, err := uc.metadataRepo.GetByID(id)
+	if err != nil {
+		return uc.fileRepo.DeleteFile(id)
 	}
 
+	err = uc.fileRepo.DeleteFile(id)
+	if err != nil {
+		return err
+	}
+
+	item := metadata.ToFileItem()
+	uc.events.Publish(events.FileDeleted, &item)
 	return nil
 }
 
-func (uc *FileUseCase) DeleteFile(id string) error {
-	return uc.fileRepo.DeleteFile(id)
+func (uc *FileUseCase) GetTrash() (models.FileMetadataList, error) {
+	return uc.fileRepo.GetTrash()
+}
+
+// RestoreFromTrash devolve o item e os descendentes que foram para a lixeira
+// junto com ele, e publica um evento para cada um, pais antes dos filhos.
+func (uc *FileUseCase) RestoreFromTrash(id string) (*models.FileMetadata, error) {
+	items, err := uc.metadataRepo.GetTrashedByRootID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	restored, err := uc.fileRepo.RestoreFromTrash(id)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return strings.Count(items[i].Path, "/") < strings.Count(items[j].Path, "/")
+	})
+
+	item := restored.ToFileItem()
+	uc.events.Publish(events.FileCreated, &item)
+	for _, descendant := range items {
+		if descendant.ID != restored.ID {
+			uc.publish(events.FileCreated, descendant.ID)
+		}
+	}
+	return restored, nil
+}
+
+// PurgeFromTrash apaga de vez o item e os descendentes que estavam na lixeira
+// com ele, publicando a remoção de cada um.
+func (uc *FileUseCase) PurgeFromTrash(id string) error {
+	items, err := uc.metadataRepo.GetTrashedByRootID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.fileRepo.PurgeFromTrash(id); err != nil {
+		return err
+	}
+
+	for _, purged := range items {
+		item := purged.ToFileItem()
+		uc.events.Publish(events.FileDeleted, &item)
+	}
+	return nil
+}
+
+func (uc *FileUseCase) PurgeExpiredTrash(retention time.Duration) (int, error) {
+	expired, err := uc.metadataRepo.GetTrashExpiredBefore(time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, item := range expired {
+		if err := uc.PurgeFromTrash(item.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+func (uc *FileUseCase) CollectGarbage(gracePeriod time.Duration) (int, error) {
+	return uc.fileRepo.CollectGarbage(time.Now().Add(-gracePeriod))
+}
+
+func (uc *FileUseCase) CompressStoredObjects() (int, error) {
+	return uc.fileRepo.CompressStoredObjects()
+}
+
+func (uc *FileUseCase) RecoverPendingOperations(gracePeriod time.Duration) (int, error) {
+	return uc.fileRepo.RecoverPendingOperations(time.Now().Add(-gracePeriod))
+}
+
+func (uc *FileUseCase) CheckConsistency(repair bool) (*models.ConsistencyReport, error) {
+	return uc.fileRepo.CheckConsistency(repair)
 }
 
 func (uc *FileUseCase) RenameFile(id string, newName string) error {
-	return uc.fileRepo.RenameFile(id, newName)
+	changed, err := uc.fileRepo.RenameFile(id, newName)
+	if err != nil {
+		return err
+	}
+
+	// Os descendentes de uma pasta também mudam de caminho.
+	for _, item := range changed {
+		uc.publish(events.FileRenamed, item.ID)
+	}
+	return nil
+}
+
+func (uc *FileUseCase) MoveFile(id string, parentID string) error {
+	changed, err := uc.fileRepo.MoveFile(id, parentID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range changed {
+		uc.publish(events.FileMoved, item.ID)
+	}
+	return nil
+}
+
+// GetMetadata lê só os metadados do item, sem baixar o conteúdo.
+func (uc *FileUseCase) GetMetadata(id string) (*models.FileMetadata, error) {
+	metadata, err := uc.metadataRepo.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, projectError.Errorf(projectError.ENOTFOUND, "file not found: %s", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// CopyFile publica um evento para cada item criado, pais antes dos filhos, e
+// devolve a cópia de id.
+func (uc *FileUseCase) CopyFile(id string, parentID string, newName string) (*models.FileMetadata, error) {
+	created, err := uc.fileRepo.CopyFile(id, parentID, newName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range created {
+		uc.publish(events.FileCreated, item.ID)
+	}
+	return created[0], nil
 }
 
 func (uc *FileUseCase) GetFileContent(id string) (string, error) {
 	return uc.fileRepo.GetFileContent(id)
 }
+
+func (uc *FileUseCase) GetRevisions(id string) (models.FileRevisionList, error) {
+	return uc.fileRepo.GetRevisions(id)
+}
+
+func (uc *FileUseCase) GetRevisionContent(id string, revision int) (string, error) {
+	return uc.fileRepo.GetRevisionContent(id, revision)
+}
+
+func (uc *FileUseCase) RestoreRevision(id string, revision int) (*models.FileRevision, error) {
+	restored, err := uc.fileRepo.RestoreRevision(id, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.queueThumbnail(id)
+	uc.publish(events.FileSaved, id)
+	return restored, nil
+}
+
+// Revisão 0 significa o conteúdo atual do arquivo.
+func (uc *FileUseCase) GetFileContentAt(id string, revision int) (string, error) {
+	if revision > 0 {
+		return uc.fileRepo.GetRevisionContent(id, revision)
+	}
+	return uc.fileRepo.GetFileContent(id)
+}
+
+func (uc *FileUseCase) DiffFiles(fromID string, fromRevision int, toID string, toRevision int) (*scene.Diff, error) {
+	fromContent, err := uc.GetFileContentAt(fromID, fromRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	toContent, err := uc.GetFileContentAt(toID, toRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.DiffContents(fromContent, toContent)
+}
+
+func (uc *FileUseCase) DiffContents(from string, to string) (*scene.Diff, error) {
+	fromScene, err := excalidraw.Parse([]byte(from))
+	if err != nil {
+		return nil, &projectError.Error{
+			Code:      projectError.EINVALID,
+			Message:   "source content is not a valid scene",
+			PrevError: err,
+		}
+	}
+
+	toScene, err := excalidraw.Parse([]byte(to))
+	if err != nil {
+		return nil, &projectError.Error{
+			Code:      projectError.EINVALID,
+			Message:   "target content is not a valid scene",
+			PrevError: err,
+		}
+	}
+
+	return scene.Compare(fromScene, toScene), nil
+}
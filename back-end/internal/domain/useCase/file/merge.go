@@ -0,0 +1,118 @@
+package file
+
+import (
+	"encoding/json"
+
+	"myScalidraw/internal/domain/models"
+	"myScalidraw/internal/domain/scene"
+	"myScalidraw/pkg/excalidraw"
+	"myScalidraw/pkg/projectError"
+)
+
+// maxMergeAttempts limita quantas vezes uma gravação é remesclada quando outra
+// gravação entra entre a leitura da versão atual e o commit.
+const maxMergeAttempts = 3
+
+type SaveResult struct {
+	Revision *models.FileRevision
+	Content  string
+	// Scene é a cena gravada, para quem precisa dela sem ler Content de novo.
+	Scene     *excalidraw.Scene
+	Merged    bool
+	Conflicts []scene.Conflict
+}
+
+// SaveFileMerging grava content como sucessor de baseRevision. Se o arquivo já
+// avançou, as duas edições são mescladas em três vias e o resultado é gravado
+// no lugar. Sem a revisão base não há como mesclar e o erro de precondição
+// original é devolvido.
+func (uc *FileUseCase) SaveFileMerging(id string, content string, baseRevision int) (*SaveResult, error) {
+	client, err := excalidraw.Parse([]byte(content))
+	if err != nil {
+		return nil, invalidScene(err)
+	}
+	return uc.saveMerging(id, client, content, baseRevision)
+}
+
+// SaveSceneMerging é o SaveFileMerging para quem já tem a cena lida, como as
+// salas de colaboração.
+func (uc *FileUseCase) SaveSceneMerging(id string, client *excalidraw.Scene, baseRevision int) (*SaveResult, error) {
+	if err := client.Validate(); err != nil {
+		return nil, invalidScene(err)
+	}
+	content, err := json.Marshal(client)
+	if err != nil {
+		return nil, err
+	}
+	return uc.saveMerging(id, client, string(content), baseRevision)
+}
+
+// saveMerging recebe a cena já validada e o conteúdo dela.
+func (uc *FileUseCase) saveMerging(id string, client *excalidraw.Scene, content string, baseRevision int) (*SaveResult, error) {
+	result := &SaveResult{Content: content, Scene: client}
+
+	var baseScene *excalidraw.Scene
+
+	for attempt := 0; attempt < maxMergeAttempts; attempt++ {
+		revision, err := uc.saveIfMatch(id, result.Content, baseRevision)
+		if err == nil {
+			result.Revision = revision
+			return result, nil
+		}
+		if projectError.ErrorCode(err) != projectError.EPRECONDITION {
+			return nil, err
+		}
+
+		if baseScene == nil {
+			baseScene, err = uc.sceneAt(id, baseRevision)
+			if err != nil {
+				return nil, projectError.Errorf(projectError.EPRECONDITION, "base revision %d of file %s is not available", baseRevision, id)
+			}
+		}
+
+		metadata, err := uc.metadataRepo.GetByID(id)
+		if err != nil {
+			return nil, projectError.Errorf(projectError.ENOTFOUND, "file not found: %s", id)
+		}
+
+		serverScene, err := uc.sceneAt(id, metadata.Revision)
+		if err != nil {
+			return nil, err
+		}
+
+		merged := scene.Merge(baseScene, serverScene, client)
+		if err := merged.Scene.Validate(); err != nil {
+			return nil, invalidScene(err)
+		}
+		mergedContent, err := json.Marshal(merged.Scene)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Content = string(mergedContent)
+		result.Scene = merged.Scene
+		result.Merged = true
+		result.Conflicts = merged.Conflicts
+		baseRevision = metadata.Revision
+	}
+
+	return nil, projectError.Errorf(projectError.ECONFLICT, "file %s kept changing while merging, try again", id)
+}
+
+// sceneAt lê a cena de uma revisão. A revisão 0 de um arquivo ainda sem
+// conteúdo é uma cena vazia.
+func (uc *FileUseCase) sceneAt(id string, revision int) (*excalidraw.Scene, error) {
+	if revision == 0 {
+		content, err := uc.fileRepo.GetFileContent(id)
+		if err != nil {
+			return excalidraw.Parse([]byte("{}"))
+		}
+		return excalidraw.Parse([]byte(content))
+	}
+
+	content, err := uc.fileRepo.GetRevisionContent(id, revision)
+	if err != nil {
+		return nil, err
+	}
+	return excalidraw.Parse([]byte(content))
+}
@@ -0,0 +1,57 @@
+package file
+
+import (
+	"encoding/json"
+
+	"myScalidraw/internal/domain/models"
+	"myScalidraw/internal/domain/scene"
+	"myScalidraw/pkg/projectError"
+)
+
+type PatchResult struct {
+	Revision  *models.FileRevision
+	Conflicts []scene.Conflict
+}
+
+// PatchFile aplica o patch sobre a versão atual do arquivo. Com
+// requiredRevision o patch só é aceito se o arquivo ainda estiver nela; sem
+// ele, uma gravação concorrente faz o patch ser reaplicado sobre a nova versão.
+func (uc *FileUseCase) PatchFile(id string, patch *scene.Patch, requiredRevision *int) (*PatchResult, error) {
+	for attempt := 0; attempt < maxMergeAttempts; attempt++ {
+		metadata, err := uc.metadataRepo.GetByID(id)
+		if err != nil {
+			return nil, projectError.Errorf(projectError.ENOTFOUND, "file not found: %s", id)
+		}
+		if metadata.IsFolder {
+			return nil, projectError.Errorf(projectError.EINVALID, "folders cannot be patched: %s", id)
+		}
+
+		if requiredRevision != nil && metadata.Revision != *requiredRevision {
+			return nil, projectError.Errorf(projectError.EPRECONDITION, "file %s is at revision %d, not %d", id, metadata.Revision, *requiredRevision)
+		}
+
+		current, err := uc.sceneAt(id, metadata.Revision)
+		if err != nil {
+			return nil, err
+		}
+
+		conflicts := patch.Apply(current)
+		if err := current.Validate(); err != nil {
+			return nil, invalidScene(err)
+		}
+		content, err := json.Marshal(current)
+		if err != nil {
+			return nil, err
+		}
+
+		revision, err := uc.saveIfMatch(id, string(content), metadata.Revision)
+		if err == nil {
+			return &PatchResult{Revision: revision, Conflicts: conflicts}, nil
+		}
+		if projectError.ErrorCode(err) != projectError.EPRECONDITION || requiredRevision != nil {
+			return nil, err
+		}
+	}
+
+	return nil, projectError.Errorf(projectError.ECONFLICT, "file %s kept changing while patching, try again", id)
+}
@@ -0,0 +1,154 @@
+package file
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	"myScalidraw/internal/domain/models"
+	"myScalidraw/pkg/projectError"
+	"myScalidraw/pkg/uuid"
+
+	"gorm.io/gorm"
+)
+
+const sceneContentType = "application/vnd.excalidraw+json"
+
+// cleanPath deixa o caminho no formato de FileMetadata.Path: começa com "/",
+// sem barra no fim e sem segmentos vazios.
+func cleanPath(filePath string) (string, error) {
+	cleaned := path.Clean("/" + strings.TrimSpace(filePath))
+	if cleaned == "/" {
+		return "", projectError.Errorf(projectError.EINVALID, "path must name a file or folder")
+	}
+	return cleaned, nil
+}
+
+func (uc *FileUseCase) ResolvePath(filePath string) (*models.FileMetadata, error) {
+	cleaned, err := cleanPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := uc.metadataRepo.GetByPath(cleaned)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, projectError.Errorf(projectError.ENOTFOUND, "path not found: %s", cleaned)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// CreateFileAtPath cria um desenho no caminho. Com parents as pastas que
+// faltarem são criadas, como no mkdir -p; sem ele a pasta precisa existir.
+// O conteúdo é validado antes, para que um desenho recusado não deixe pastas
+// novas para trás.
+func (uc *FileUseCase) CreateFileAtPath(filePath string, content []byte, parents bool) (*models.FileMetadata, error) {
+	cleaned, err := cleanPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(content) > 0 {
+		if err := validateScene(content); err != nil {
+			return nil, err
+		}
+	}
+
+	uc.pathMu.Lock()
+	defer uc.pathMu.Unlock()
+
+	_, err = uc.metadataRepo.GetByPath(cleaned)
+	if err == nil {
+		return nil, projectError.Errorf(projectError.ECONFLICT, "path already exists: %s", cleaned)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	dir, name := path.Split(cleaned)
+	parent, err := uc.ensureFolder(path.Clean(dir), parents)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := newMetadata(parent, name, false)
+	if err != nil {
+		return nil, err
+	}
+	metadata.Size = int64(len(content))
+
+	if err := uc.CreateFile(metadata, content); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// ensureFolder devolve a pasta em folderPath, ou nil para a raiz, criando os
+// segmentos que faltarem se create for verdadeiro.
+func (uc *FileUseCase) ensureFolder(folderPath string, create bool) (*models.FileMetadata, error) {
+	var parent *models.FileMetadata
+
+	current := ""
+	for _, segment := range strings.Split(strings.Trim(folderPath, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		current += "/" + segment
+
+		folder, err := uc.metadataRepo.GetByPath(current)
+		if err == nil {
+			if !folder.IsFolder {
+				return nil, projectError.Errorf(projectError.EINVALID, "%s is not a folder", current)
+			}
+			parent = folder
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+
+		if !create {
+			return nil, projectError.Errorf(projectError.ENOTFOUND, "folder not found: %s", current)
+		}
+
+		folder, err = newMetadata(parent, segment, true)
+		if err != nil {
+			return nil, err
+		}
+		if err := uc.CreateFile(folder, nil); err != nil {
+			return nil, err
+		}
+		parent = folder
+	}
+
+	return parent, nil
+}
+
+func newMetadata(parent *models.FileMetadata, name string, isFolder bool) (*models.FileMetadata, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	parentID, filePath := "", "/"+name
+	if parent != nil {
+		parentID, filePath = parent.ID, parent.Path+"/"+name
+	}
+
+	now := time.Now()
+	return &models.FileMetadata{
+		ID:           id,
+		ParentID:     parentID,
+		Name:         name,
+		StoragePath:  filePath,
+		Path:         filePath,
+		IsFolder:     isFolder,
+		ContentType:  sceneContentType,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		LastModified: now,
+	}, nil
+}
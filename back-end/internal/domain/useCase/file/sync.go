@@ -0,0 +1,139 @@
+package file
+
+import (
+	"strconv"
+	"time"
+
+	"myScalidraw/internal/domain/models"
+	"myScalidraw/pkg/projectError"
+)
+
+const (
+	DefaultSyncLimit = 500
+	MaxSyncLimit     = 5000
+
+	// syncSettleWindow protege contra transações que pegaram uma sequência
+	// menor mas fizeram commit depois de outra. O cursor devolvido nunca passa
+	// de entradas mais novas que isso, então elas são reenviadas na próxima
+	// chamada em vez de serem puladas.
+	syncSettleWindow = time.Minute
+)
+
+// Sync devolve o que mudou desde cursor. Sem cursor, ou com um cursor cujas
+// entradas já saíram do journal, devolve todos os itens com Reset.
+func (uc *FileUseCase) Sync(cursor string, limit int) (*models.SyncResult, error) {
+	if limit <= 0 || limit > MaxSyncLimit {
+		limit = DefaultSyncLimit
+	}
+
+	settled, err := uc.metadataRepo.GetSettledSequence(time.Now().Add(-syncSettleWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	if cursor == "" {
+		return uc.syncSnapshot(settled)
+	}
+
+	since, err := strconv.ParseUint(cursor, 10, 64)
+	if err != nil {
+		return nil, projectError.Errorf(projectError.EINVALID, "invalid sync cursor: %s", cursor)
+	}
+
+	oldest, err := uc.metadataRepo.GetOldestSequence()
+	if err != nil {
+		return nil, err
+	}
+	if since+1 < oldest {
+		return uc.syncSnapshot(settled)
+	}
+
+	entries, err := uc.metadataRepo.GetChangesSince(since, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(entries) > limit
+	if hasMore {
+		entries = entries[:limit]
+	}
+
+	result := &models.SyncResult{
+		Cursor:  strconv.FormatUint(since, 10),
+		Changes: []models.SyncChange{},
+	}
+	if len(entries) == 0 {
+		return result, nil
+	}
+
+	next := entries[len(entries)-1].Sequence
+	if next > settled {
+		next = settled
+	}
+	if next > since {
+		result.Cursor = strconv.FormatUint(next, 10)
+		result.HasMore = hasMore
+	}
+
+	// Um item alterado várias vezes aparece uma vez só, na posição da última
+	// alteração.
+	last := make(map[string]int, len(entries))
+	for i, entry := range entries {
+		last[entry.FileID] = i
+	}
+
+	ids := make([]string, 0, len(last))
+	for i, entry := range entries {
+		if last[entry.FileID] == i {
+			ids = append(ids, entry.FileID)
+		}
+	}
+
+	metadata, err := uc.metadataRepo.GetByIDsIncludingTrash(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[string]*models.FileItem, len(metadata))
+	for _, item := range metadata {
+		if item.DeletedAt.Valid {
+			continue
+		}
+		fileItem := item.ToFileItem()
+		items[item.ID] = &fileItem
+	}
+
+	for _, id := range ids {
+		if item, ok := items[id]; ok {
+			result.Changes = append(result.Changes, models.SyncChange{ID: id, Item: item})
+		} else {
+			result.Changes = append(result.Changes, models.SyncChange{ID: id, Deleted: true})
+		}
+	}
+
+	return result, nil
+}
+
+// syncSnapshot lê a árvore depois de fixar o cursor, então nada anterior ao
+// cursor fica de fora; o que vier depois é reenviado sem prejuízo.
+func (uc *FileUseCase) syncSnapshot(cursor uint64) (*models.SyncResult, error) {
+	metadata, err := uc.metadataRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.SyncResult{
+		Cursor:  strconv.FormatUint(cursor, 10),
+		Reset:   true,
+		Changes: []models.SyncChange{},
+	}
+	for _, item := range metadata.ToFlatList() {
+		result.Changes = append(result.Changes, models.SyncChange{ID: item.ID, Item: &item})
+	}
+
+	return result, nil
+}
+
+func (uc *FileUseCase) PruneChangeJournal(retention time.Duration) (int64, error) {
+	return uc.metadataRepo.PruneChangesBefore(time.Now().Add(-retention))
+}
@@ -0,0 +1,160 @@
+package file
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"sync"
+
+	"myScalidraw/pkg/excalidraw"
+	"myScalidraw/pkg/projectError"
+	"myScalidraw/pkg/render"
+)
+
+const (
+	// thumbnailSize é o maior lado da miniatura, em pixels.
+	thumbnailSize    = 256
+	thumbnailPadding = 10
+)
+
+// As miniaturas são guardadas pelo digest do conteúdo: arquivos com o mesmo
+// conteúdo dividem a mesma, e a URL de um conteúdo nunca muda de imagem.
+//
+// Gravar não espera a miniatura: o arquivo entra numa fila e um worker gera a
+// miniatura do conteúdo atual dele. Várias gravações seguidas, como as da
+// colaboração, viram uma só renderização. Uma falha ou um item que não entrou
+// na fila não desfaz nada; GetThumbnail gera quando a miniatura for pedida.
+
+type thumbnailQueue struct {
+	mu      sync.Mutex
+	pending map[string]struct{}
+	wake    chan struct{}
+}
+
+func newThumbnailQueue() *thumbnailQueue {
+	return &thumbnailQueue{
+		pending: map[string]struct{}{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// queueThumbnail pede a miniatura do conteúdo atual do arquivo.
+func (uc *FileUseCase) queueThumbnail(id string) {
+	q := uc.thumbnails
+	q.mu.Lock()
+	q.pending[id] = struct{}{}
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *thumbnailQueue) next() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for id := range q.pending {
+		delete(q.pending, id)
+		return id, true
+	}
+	return "", false
+}
+
+// RunThumbnailWorker gera as miniaturas da fila até stop ser fechado.
+func (uc *FileUseCase) RunThumbnailWorker(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-uc.thumbnails.wake:
+		}
+
+		for {
+			id, ok := uc.thumbnails.next()
+			if !ok {
+				break
+			}
+			uc.generateThumbnail(id)
+
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+	}
+}
+
+func (uc *FileUseCase) generateThumbnail(id string) {
+	metadata, err := uc.metadataRepo.GetByID(id)
+	if err != nil || metadata.IsFolder || metadata.ContentDigest == "" {
+		return
+	}
+	digest := metadata.ContentDigest
+
+	exists, err := uc.fileRepo.HasThumbnail(digest)
+	if err != nil || exists {
+		return
+	}
+
+	content, err := uc.fileRepo.GetBlobContent(digest)
+	if err != nil {
+		log.Printf("Error reading content for thumbnail %s: %v", digest, err)
+		return
+	}
+
+	thumbnail, err := renderThumbnail(content)
+	if err != nil {
+		log.Printf("Error rendering thumbnail for %s: %v", digest, err)
+		return
+	}
+
+	if err := uc.fileRepo.SaveThumbnail(digest, thumbnail); err != nil {
+		log.Printf("Error saving thumbnail for %s: %v", digest, err)
+	}
+}
+
+// GetThumbnail devolve a miniatura do conteúdo, gerando na hora para cenas
+// gravadas antes das miniaturas existirem.
+func (uc *FileUseCase) GetThumbnail(digest string) ([]byte, error) {
+	thumbnail, err := uc.fileRepo.GetThumbnail(digest)
+	if projectError.ErrorCode(err) != projectError.ENOTFOUND {
+		return thumbnail, err
+	}
+
+	content, err := uc.fileRepo.GetBlobContent(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnail, err = renderThumbnail(content)
+	if err != nil {
+		return nil, projectError.Errorf(projectError.ENOTFOUND, "thumbnail not available: %s", digest)
+	}
+
+	if err := uc.fileRepo.SaveThumbnail(digest, thumbnail); err != nil {
+		log.Printf("Error saving thumbnail for %s: %v", digest, err)
+	}
+	return thumbnail, nil
+}
+
+func renderThumbnail(content []byte) ([]byte, error) {
+	scene, err := excalidraw.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	drawing, err := render.Build(scene, render.Options{Background: true, Padding: thumbnailPadding})
+	if err != nil {
+		return nil, err
+	}
+
+	scale := math.Min(1, thumbnailSize/math.Max(drawing.Width(), drawing.Height()))
+
+	var thumbnail bytes.Buffer
+	if err := render.WritePNG(&thumbnail, drawing, render.PNGOptions{Scale: scale}); err != nil {
+		return nil, err
+	}
+	return thumbnail.Bytes(), nil
+}
@@ -60,3 +60,18 @@ func GetEnvOrDieAsBool(key string) (bool, error) {
 
 	return value, nil
 }
+
+func GetEnvAsIntOrDefault(key string, defaultValue int) (int, error) {
+	if os.Getenv(key) == "" {
+		return defaultValue, nil
+	}
+	return GetEnvOrDieAsInt(key)
+}
+
+func GetEnvOrDefault(key string, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
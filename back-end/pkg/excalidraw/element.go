@@ -0,0 +1,232 @@
+package excalidraw
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+)
+
+const (
+	TypeRectangle  = "rectangle"
+	TypeDiamond    = "diamond"
+	TypeEllipse    = "ellipse"
+	TypeArrow      = "arrow"
+	TypeLine       = "line"
+	TypeFreeDraw   = "freedraw"
+	TypeText       = "text"
+	TypeImage      = "image"
+	TypeFrame      = "frame"
+	TypeMagicFrame = "magicframe"
+	TypeEmbeddable = "embeddable"
+	TypeIframe     = "iframe"
+	TypeSelection  = "selection"
+)
+
+var elementTypes = map[string]bool{
+	TypeRectangle:  true,
+	TypeDiamond:    true,
+	TypeEllipse:    true,
+	TypeArrow:      true,
+	TypeLine:       true,
+	TypeFreeDraw:   true,
+	TypeText:       true,
+	TypeImage:      true,
+	TypeFrame:      true,
+	TypeMagicFrame: true,
+	TypeEmbeddable: true,
+	TypeIframe:     true,
+	TypeSelection:  true,
+}
+
+// Point é relativo a (X, Y) do elemento.
+type Point [2]float64
+
+// Element junta os campos comuns e os específicos de cada tipo; os que não se
+// aplicam ao tipo ficam com o valor zero. O JSON de onde o elemento veio é o
+// que volta na gravação, então os campos não devem ser alterados direto: quem
+// precisa de uma versão alterada usa métodos como Tombstone.
+type Element struct {
+	ID              string         `json:"id"`
+	Type            string         `json:"type"`
+	X               float64        `json:"x"`
+	Y               float64        `json:"y"`
+	Width           float64        `json:"width"`
+	Height          float64        `json:"height"`
+	Angle           float64        `json:"angle"`
+	StrokeColor     string         `json:"strokeColor"`
+	BackgroundColor string         `json:"backgroundColor"`
+	FillStyle       string         `json:"fillStyle"`
+	StrokeWidth     float64        `json:"strokeWidth"`
+	StrokeStyle     string         `json:"strokeStyle"`
+	Roughness       float64        `json:"roughness"`
+	Opacity         *float64       `json:"opacity"`
+	Roundness       *Roundness     `json:"roundness"`
+	GroupIDs        []string       `json:"groupIds"`
+	FrameID         *string        `json:"frameId"`
+	Index           *string        `json:"index"`
+	Seed            int64          `json:"seed"`
+	Version         int64          `json:"version"`
+	VersionNonce    int64          `json:"versionNonce"`
+	IsDeleted       bool           `json:"isDeleted"`
+	BoundElements   []BoundElement `json:"boundElements"`
+	Updated         int64          `json:"updated"`
+	Link            *string        `json:"link"`
+	Locked          bool           `json:"locked"`
+
+	// text
+	Text          string   `json:"text,omitempty"`
+	OriginalText  string   `json:"originalText,omitempty"`
+	FontSize      float64  `json:"fontSize,omitempty"`
+	FontFamily    int      `json:"fontFamily,omitempty"`
+	TextAlign     string   `json:"textAlign,omitempty"`
+	VerticalAlign string   `json:"verticalAlign,omitempty"`
+	ContainerID   *string  `json:"containerId,omitempty"`
+	LineHeight    float64  `json:"lineHeight,omitempty"`
+	Baseline      *float64 `json:"baseline,omitempty"`
+
+	// arrow, line e freedraw
+	Points         []Point  `json:"points,omitempty"`
+	StartBinding   *Binding `json:"startBinding,omitempty"`
+	EndBinding     *Binding `json:"endBinding,omitempty"`
+	StartArrowhead *string  `json:"startArrowhead,omitempty"`
+	EndArrowhead   *string  `json:"endArrowhead,omitempty"`
+	Elbowed        bool     `json:"elbowed,omitempty"`
+
+	// freedraw
+	Pressures        []float64 `json:"pressures,omitempty"`
+	SimulatePressure bool      `json:"simulatePressure,omitempty"`
+
+	// image
+	FileID *string     `json:"fileId,omitempty"`
+	Status string      `json:"status,omitempty"`
+	Scale  *[2]float64 `json:"scale,omitempty"`
+
+	// frame e magicframe
+	Name *string `json:"name,omitempty"`
+
+	raw json.RawMessage
+}
+
+type Roundness struct {
+	Type  int      `json:"type"`
+	Value *float64 `json:"value,omitempty"`
+}
+
+type Binding struct {
+	ElementID  string      `json:"elementId"`
+	Focus      float64     `json:"focus"`
+	Gap        float64     `json:"gap"`
+	FixedPoint *[2]float64 `json:"fixedPoint,omitempty"`
+}
+
+type BoundElement struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// ParseElement lê e valida um elemento isolado, sem checar as referências
+// para outros elementos.
+func ParseElement(content []byte) (*Element, error) {
+	return parseElement(content, false)
+}
+
+// ParseDraftElement é o ParseElement das edições ao vivo, com as regras de
+// ValidateDraft.
+func ParseDraftElement(content []byte) (*Element, error) {
+	return parseElement(content, true)
+}
+
+func parseElement(content []byte, draft bool) (*Element, error) {
+	var element Element
+	if err := json.Unmarshal(content, &element); err != nil {
+		// Um campo com o tipo errado não impede de ler o id para a mensagem.
+		var identity struct {
+			ID   interface{} `json:"id"`
+			Type interface{} `json:"type"`
+		}
+		json.Unmarshal(content, &identity)
+		id, _ := identity.ID.(string)
+		elementType, _ := identity.Type.(string)
+
+		validationErr := &ValidationError{Index: -1, ElementID: id, ElementType: elementType, Message: typeMessage(err)}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			validationErr.Field = typeErr.Field
+		}
+		return nil, validationErr
+	}
+
+	if err := element.validate(draft); err != nil {
+		return nil, err
+	}
+	return &element, nil
+}
+
+func (e *Element) UnmarshalJSON(content []byte) error {
+	type plain Element
+	if err := json.Unmarshal(content, (*plain)(e)); err != nil {
+		return err
+	}
+	e.raw = append(json.RawMessage(nil), content...)
+	return nil
+}
+
+func (e Element) MarshalJSON() ([]byte, error) {
+	if e.raw != nil {
+		return e.raw, nil
+	}
+	type plain Element
+	return json.Marshal(plain(e))
+}
+
+// Tombstone devolve uma cópia marcada como removida, com a versão incrementada
+// para que vença a atual na reconciliação.
+func (e *Element) Tombstone() Element {
+	tombstone := *e
+	tombstone.IsDeleted = true
+	tombstone.Version = e.Version + 1
+	tombstone.VersionNonce = int64(rand.Int31())
+	tombstone.raw = withFields(e.raw, map[string]interface{}{
+		"isDeleted":    tombstone.IsDeleted,
+		"version":      tombstone.Version,
+		"versionNonce": tombstone.VersionNonce,
+	})
+	return tombstone
+}
+
+// withFields troca campos do JSON original. Sem o original, ou se ele não for
+// um objeto, devolve nil e o elemento é gravado a partir dos campos.
+func withFields(raw json.RawMessage, changes map[string]interface{}) json.RawMessage {
+	var fields map[string]json.RawMessage
+	if raw == nil || json.Unmarshal(raw, &fields) != nil || fields == nil {
+		return nil
+	}
+	for key, value := range changes {
+		content, err := json.Marshal(value)
+		if err != nil {
+			return nil
+		}
+		fields[key] = content
+	}
+	content, err := json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+	return content
+}
+
+func (e *Element) IsLinear() bool {
+	return e.Type == TypeArrow || e.Type == TypeLine
+}
+
+func (e *Element) IsFrame() bool {
+	return e.Type == TypeFrame || e.Type == TypeMagicFrame
+}
+
+// OpacityValue devolve a opacidade entre 0 e 1; sem o campo vale 100%.
+func (e *Element) OpacityValue() float64 {
+	if e.Opacity == nil {
+		return 1
+	}
+	return *e.Opacity / 100
+}
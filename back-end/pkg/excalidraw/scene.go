@@ -0,0 +1,241 @@
+package excalidraw
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const SceneType = "excalidraw"
+
+// Scene é o conteúdo de um arquivo .excalidraw. Os elementos, o appState e os
+// arquivos guardam o JSON de onde vieram, então uma cena lida e gravada de
+// volta mantém os campos que o modelo não conhece.
+type Scene struct {
+	Type     string                `json:"type"`
+	Version  int                   `json:"version"`
+	Source   string                `json:"source"`
+	Elements []Element             `json:"elements"`
+	AppState AppState              `json:"appState"`
+	Files    map[string]BinaryFile `json:"files,omitempty"`
+
+	// raw são as chaves do topo como vieram.
+	raw map[string]json.RawMessage
+}
+
+// AppState guarda as chaves como vieram; as poucas que o servidor usa são
+// lidas sob demanda.
+type AppState map[string]json.RawMessage
+
+// appStateTypes são as chaves conferidas na validação, com o tipo esperado.
+var appStateTypes = map[string]func() interface{}{
+	"viewBackgroundColor": func() interface{} { return new(string) },
+	"gridSize":            func() interface{} { return new(*float64) },
+	"theme":               func() interface{} { return new(string) },
+	"name":                func() interface{} { return new(string) },
+}
+
+func (a AppState) ViewBackgroundColor() string {
+	var color string
+	json.Unmarshal(a["viewBackgroundColor"], &color)
+	return color
+}
+
+// Validate confere o tipo das chaves conhecidas.
+func (a AppState) Validate() error {
+	keys := make([]string, 0, len(appStateTypes))
+	for key := range appStateTypes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, ok := a[key]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(value, appStateTypes[key]()); err != nil {
+			return &ValidationError{Index: -1, Field: "appState." + key, Message: typeMessage(err)}
+		}
+	}
+	return nil
+}
+
+// BinaryFile é uma imagem embutida na cena, referenciada pelo fileId dos
+// elementos de imagem.
+type BinaryFile struct {
+	ID            string `json:"id"`
+	MimeType      string `json:"mimeType"`
+	DataURL       string `json:"dataURL"`
+	Created       int64  `json:"created,omitempty"`
+	LastRetrieved int64  `json:"lastRetrieved,omitempty"`
+
+	raw json.RawMessage
+}
+
+func (f *BinaryFile) UnmarshalJSON(content []byte) error {
+	type plain BinaryFile
+	if err := json.Unmarshal(content, (*plain)(f)); err != nil {
+		return err
+	}
+	f.raw = append(json.RawMessage(nil), content...)
+	return nil
+}
+
+func (f BinaryFile) MarshalJSON() ([]byte, error) {
+	if f.raw != nil {
+		return f.raw, nil
+	}
+	type plain BinaryFile
+	return json.Marshal(plain(f))
+}
+
+// Parse lê e valida uma cena. Erros de estrutura e de validação vêm como
+// *ValidationError, com o índice e o id do elemento quando for o caso.
+func Parse(content []byte) (*Scene, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, sceneError(err)
+	}
+
+	scene := &Scene{AppState: AppState{}, raw: raw}
+
+	var elements []json.RawMessage
+	var files map[string]json.RawMessage
+	for _, field := range []struct {
+		key    string
+		target interface{}
+	}{
+		{"type", &scene.Type},
+		{"version", &scene.Version},
+		{"source", &scene.Source},
+		{"elements", &elements},
+		{"appState", &scene.AppState},
+		{"files", &files},
+	} {
+		value, ok := raw[field.key]
+		if !ok || bytes.Equal(value, []byte("null")) {
+			continue
+		}
+		if err := json.Unmarshal(value, field.target); err != nil {
+			return nil, &ValidationError{Index: -1, Field: field.key, Message: typeMessage(err)}
+		}
+	}
+
+	scene.Elements = make([]Element, 0, len(elements))
+	for i, content := range elements {
+		element, err := ParseElement(content)
+		if err != nil {
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				validationErr.Index = i
+			}
+			return nil, err
+		}
+		scene.Elements = append(scene.Elements, *element)
+	}
+
+	if len(files) > 0 {
+		scene.Files = make(map[string]BinaryFile, len(files))
+		for id, content := range files {
+			var file BinaryFile
+			if err := json.Unmarshal(content, &file); err != nil {
+				return nil, &ValidationError{Index: -1, Field: fmt.Sprintf("files[%q]", id), Message: typeMessage(err)}
+			}
+			scene.Files[id] = file
+		}
+	}
+
+	if err := scene.Validate(); err != nil {
+		return nil, err
+	}
+	return scene, nil
+}
+
+// MarshalJSON grava as chaves do topo que vieram na leitura com os valores
+// atuais da cena por cima.
+func (s Scene) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]interface{}, len(s.raw)+6)
+	for key, value := range s.raw {
+		fields[key] = value
+	}
+	set := func(key string, value interface{}, empty bool) {
+		if _, ok := s.raw[key]; ok || !empty {
+			fields[key] = value
+		}
+	}
+
+	set("type", s.Type, s.Type == "")
+	set("version", s.Version, s.Version == 0)
+	set("source", s.Source, s.Source == "")
+	set("files", s.Files, len(s.Files) == 0)
+
+	elements := s.Elements
+	if elements == nil {
+		elements = []Element{}
+	}
+	fields["elements"] = elements
+
+	appState := s.AppState
+	if appState == nil {
+		appState = AppState{}
+	}
+	fields["appState"] = appState
+
+	return json.Marshal(fields)
+}
+
+// ElementsByID indexa os elementos pelo id.
+func (s *Scene) ElementsByID() map[string]*Element {
+	elements := make(map[string]*Element, len(s.Elements))
+	for i := range s.Elements {
+		elements[s.Elements[i].ID] = &s.Elements[i]
+	}
+	return elements
+}
+
+// Visible devolve os elementos que aparecem no desenho, sem tombstones.
+func (s *Scene) Visible() []*Element {
+	elements := make([]*Element, 0, len(s.Elements))
+	for i := range s.Elements {
+		if !s.Elements[i].IsDeleted {
+			elements = append(elements, &s.Elements[i])
+		}
+	}
+	return elements
+}
+
+func sceneError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &ValidationError{Index: -1, Field: typeErr.Field, Message: typeMessage(err)}
+	}
+	return &ValidationError{Index: -1, Message: "invalid JSON: " + err.Error()}
+}
+
+func typeMessage(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Sprintf("expected %s, got %s", jsonKind(typeErr.Type.Kind().String()), typeErr.Value)
+	}
+	return "invalid JSON: " + err.Error()
+}
+
+func jsonKind(kind string) string {
+	switch kind {
+	case "float64", "float32", "int", "int64", "int32":
+		return "number"
+	case "string":
+		return "string"
+	case "bool":
+		return "boolean"
+	case "slice", "array":
+		return "array"
+	case "struct", "map", "ptr":
+		return "object"
+	default:
+		return kind
+	}
+}
@@ -0,0 +1,55 @@
+package excalidraw
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Campos que o modelo não conhece precisam sobreviver a uma leitura seguida de
+// gravação, inclusive nos tombstones.
+func TestSceneKeepsUnknownFields(t *testing.T) {
+	content := `{
+		"type": "excalidraw",
+		"custom": {"a": 1},
+		"elements": [{"id": "r", "type": "rectangle", "version": 3, "customData": {"tag": "x"}}],
+		"appState": {"viewBackgroundColor": "#fff", "zoom": {"value": 2}},
+		"files": {"f": {"id": "f", "mimeType": "image/png", "dataURL": "data:image/png;base64,AA==", "version": 2}}
+	}`
+
+	scene, err := Parse([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scene.AppState.ViewBackgroundColor() != "#fff" {
+		t.Errorf("background = %q, want #fff", scene.AppState.ViewBackgroundColor())
+	}
+
+	scene.Elements[0] = scene.Elements[0].Tombstone()
+	saved, err := json.Marshal(scene)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"custom":{"a":1}`, `"customData":{"tag":"x"}`, `"zoom":{"value":2}`, `"version":2`, `"isDeleted":true`, `"version":4`} {
+		if !strings.Contains(string(saved), field) {
+			t.Errorf("saved scene lost %s: %s", field, saved)
+		}
+	}
+
+	again, err := Parse(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.Elements[0].IsDeleted || again.Elements[0].Version != 4 {
+		t.Errorf("tombstone = %+v, want deleted at version 4", again.Elements[0])
+	}
+}
+
+func TestAppStateValidate(t *testing.T) {
+	if _, err := Parse([]byte(`{"appState":{"gridSize":"big"}}`)); err == nil || !strings.Contains(err.Error(), "appState.gridSize") {
+		t.Errorf("err = %v, want an appState.gridSize error", err)
+	}
+	if _, err := Parse([]byte(`{"appState":{"gridSize":null,"zoom":{"value":1}}}`)); err != nil {
+		t.Errorf("valid appState rejected: %v", err)
+	}
+}
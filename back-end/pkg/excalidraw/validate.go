@@ -0,0 +1,237 @@
+package excalidraw
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationError aponta o elemento e o campo inválidos. Index é -1 para erros
+// fora do array de elementos.
+type ValidationError struct {
+	Index       int
+	ElementID   string
+	ElementType string
+	Field       string
+	Message     string
+}
+
+func (e *ValidationError) Error() string {
+	var where []string
+	if e.Index >= 0 || e.ElementID != "" {
+		element := "element"
+		if e.Index >= 0 {
+			element += fmt.Sprintf(" %d", e.Index)
+		}
+		if e.ElementID != "" {
+			element += fmt.Sprintf(" (id %q", e.ElementID)
+			if e.ElementType != "" {
+				element += ", " + e.ElementType
+			}
+			element += ")"
+		}
+		where = append(where, element)
+	}
+	if e.Field != "" {
+		where = append(where, e.Field)
+	}
+
+	if len(where) == 0 {
+		return e.Message
+	}
+	return strings.Join(where, ": ") + ": " + e.Message
+}
+
+var (
+	fillStyles     = map[string]bool{"hachure": true, "cross-hatch": true, "solid": true, "zigzag": true, "zigzag-line": true, "dots": true, "dashed": true}
+	strokeStyles   = map[string]bool{"solid": true, "dashed": true, "dotted": true}
+	textAligns     = map[string]bool{"left": true, "center": true, "right": true}
+	verticalAligns = map[string]bool{"top": true, "middle": true, "bottom": true}
+)
+
+// Validate confere os campos do elemento em si.
+func (e *Element) Validate() error {
+	return e.validate(false)
+}
+
+// ValidateDraft é a validação das edições ao vivo: linhas, setas e traços
+// livres ainda sendo desenhados podem ter menos pontos do que o mínimo
+// exigido ao gravar.
+func (e *Element) ValidateDraft() error {
+	return e.validate(true)
+}
+
+func (e *Element) validate(draft bool) error {
+	fail := func(field string, format string, args ...interface{}) error {
+		return &ValidationError{Index: -1, ElementID: e.ID, ElementType: e.Type, Field: field, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if e.ID == "" {
+		return fail("id", "is required")
+	}
+	if !elementTypes[e.Type] {
+		if e.Type == "" {
+			return fail("type", "is required")
+		}
+		return fail("type", "unknown element type %q", e.Type)
+	}
+
+	if e.Width < 0 {
+		return fail("width", "must not be negative")
+	}
+	if e.Height < 0 {
+		return fail("height", "must not be negative")
+	}
+	if e.StrokeWidth < 0 {
+		return fail("strokeWidth", "must not be negative")
+	}
+	if e.Opacity != nil && (*e.Opacity < 0 || *e.Opacity > 100) {
+		return fail("opacity", "must be between 0 and 100")
+	}
+	if e.Version < 0 {
+		return fail("version", "must not be negative")
+	}
+	if e.FillStyle != "" && !fillStyles[e.FillStyle] {
+		return fail("fillStyle", "unknown fill style %q", e.FillStyle)
+	}
+	if e.StrokeStyle != "" && !strokeStyles[e.StrokeStyle] {
+		return fail("strokeStyle", "unknown stroke style %q", e.StrokeStyle)
+	}
+
+	for i, groupID := range e.GroupIDs {
+		if groupID == "" {
+			return fail(fmt.Sprintf("groupIds[%d]", i), "must not be empty")
+		}
+	}
+	for i, bound := range e.BoundElements {
+		if bound.ID == "" {
+			return fail(fmt.Sprintf("boundElements[%d].id", i), "is required")
+		}
+	}
+
+	switch {
+	case e.Type == TypeText:
+		if e.FontSize <= 0 {
+			return fail("fontSize", "must be positive")
+		}
+		if e.TextAlign != "" && !textAligns[e.TextAlign] {
+			return fail("textAlign", "unknown alignment %q", e.TextAlign)
+		}
+		if e.VerticalAlign != "" && !verticalAligns[e.VerticalAlign] {
+			return fail("verticalAlign", "unknown alignment %q", e.VerticalAlign)
+		}
+
+	case e.IsLinear():
+		if len(e.Points) < 2 && !draft {
+			return fail("points", "%s needs at least 2 points", e.Type)
+		}
+		if e.StartBinding != nil && e.StartBinding.ElementID == "" {
+			return fail("startBinding.elementId", "is required")
+		}
+		if e.EndBinding != nil && e.EndBinding.ElementID == "" {
+			return fail("endBinding.elementId", "is required")
+		}
+
+	case e.Type == TypeFreeDraw:
+		if len(e.Points) == 0 && !draft {
+			return fail("points", "freedraw needs at least 1 point")
+		}
+		if len(e.Pressures) > 0 && len(e.Pressures) != len(e.Points) {
+			return fail("pressures", "has %d values for %d points", len(e.Pressures), len(e.Points))
+		}
+	}
+
+	return nil
+}
+
+// Validate confere a cena inteira: cada elemento, ids repetidos e referências
+// entre elementos. Referências para elementos ausentes são toleradas, porque o
+// próprio Excalidraw as descarta ao abrir o arquivo, mas uma referência para
+// um elemento do tipo errado não.
+func (s *Scene) Validate() error {
+	if s.Type != "" && s.Type != SceneType {
+		return &ValidationError{Index: -1, Field: "type", Message: fmt.Sprintf("must be %q, got %q", SceneType, s.Type)}
+	}
+
+	positions := make(map[string]int, len(s.Elements))
+	for i := range s.Elements {
+		element := &s.Elements[i]
+		if err := element.Validate(); err != nil {
+			err.(*ValidationError).Index = i
+			return err
+		}
+
+		if previous, ok := positions[element.ID]; ok {
+			return &ValidationError{
+				Index:       i,
+				ElementID:   element.ID,
+				ElementType: element.Type,
+				Field:       "id",
+				Message:     fmt.Sprintf("duplicates the id of element %d", previous),
+			}
+		}
+		positions[element.ID] = i
+	}
+
+	for i := range s.Elements {
+		element := &s.Elements[i]
+		fail := func(field string, format string, args ...interface{}) error {
+			return &ValidationError{Index: i, ElementID: element.ID, ElementType: element.Type, Field: field, Message: fmt.Sprintf(format, args...)}
+		}
+		target := func(id *string) *Element {
+			if id == nil {
+				return nil
+			}
+			if position, ok := positions[*id]; ok {
+				return &s.Elements[position]
+			}
+			return nil
+		}
+
+		if frame := target(element.FrameID); frame != nil && !frame.IsFrame() {
+			return fail("frameId", "references %s element %q, not a frame", frame.Type, frame.ID)
+		}
+		if container := target(element.ContainerID); container != nil && (container.Type == TypeText || container.Type == TypeFreeDraw) {
+			return fail("containerId", "%s element %q cannot contain text", container.Type, container.ID)
+		}
+		for _, binding := range []struct {
+			field   string
+			binding *Binding
+		}{{"startBinding", element.StartBinding}, {"endBinding", element.EndBinding}} {
+			if binding.binding == nil {
+				continue
+			}
+			if bound := target(&binding.binding.ElementID); bound != nil && bound.IsLinear() {
+				return fail(binding.field+".elementId", "cannot bind to %s element %q", bound.Type, bound.ID)
+			}
+		}
+	}
+
+	if err := s.AppState.Validate(); err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(s.Files))
+	for id := range s.Files {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		file := s.Files[id]
+		fail := func(field string, format string, args ...interface{}) error {
+			return &ValidationError{Index: -1, Field: fmt.Sprintf("files[%q].%s", id, field), Message: fmt.Sprintf(format, args...)}
+		}
+		if file.ID != "" && file.ID != id {
+			return fail("id", "does not match its key")
+		}
+		if file.MimeType == "" {
+			return fail("mimeType", "is required")
+		}
+		if !strings.HasPrefix(file.DataURL, "data:") {
+			return fail("dataURL", "must be a data URL")
+		}
+	}
+
+	return nil
+}
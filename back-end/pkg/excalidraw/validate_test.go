@@ -0,0 +1,262 @@
+package excalidraw
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// elementJSON monta um elemento do tipo com os campos que o Excalidraw sempre
+// grava; fields sobrescreve ou acrescenta campos, e nil remove o campo.
+func elementJSON(t *testing.T, elementType string, fields map[string]interface{}) []byte {
+	t.Helper()
+	element := map[string]interface{}{
+		"id":              "el-1",
+		"type":            elementType,
+		"x":               10,
+		"y":               20,
+		"width":           100,
+		"height":          50,
+		"angle":           0,
+		"strokeColor":     "#1e1e1e",
+		"backgroundColor": "transparent",
+		"fillStyle":       "solid",
+		"strokeWidth":     2,
+		"strokeStyle":     "solid",
+		"roughness":       1,
+		"opacity":         100,
+		"groupIds":        []string{},
+		"frameId":         nil,
+		"seed":            1,
+		"version":         1,
+		"versionNonce":    1,
+		"isDeleted":       false,
+		"boundElements":   nil,
+		"link":            nil,
+		"locked":          false,
+	}
+
+	switch elementType {
+	case TypeText:
+		element["text"] = "hello"
+		element["originalText"] = "hello"
+		element["fontSize"] = 20
+		element["fontFamily"] = 5
+		element["textAlign"] = "left"
+		element["verticalAlign"] = "top"
+		element["containerId"] = nil
+		element["lineHeight"] = 1.25
+	case TypeArrow, TypeLine:
+		element["points"] = [][2]float64{{0, 0}, {100, 50}}
+		element["startBinding"] = nil
+		element["endBinding"] = nil
+	case TypeFreeDraw:
+		element["points"] = [][2]float64{{0, 0}, {5, 5}, {10, 3}}
+		element["pressures"] = []float64{}
+		element["simulatePressure"] = true
+	case TypeImage:
+		element["fileId"] = "file-1"
+		element["status"] = "saved"
+		element["scale"] = [2]float64{1, 1}
+	case TypeFrame, TypeMagicFrame:
+		element["name"] = nil
+	}
+
+	for key, value := range fields {
+		if value == nil {
+			delete(element, key)
+			continue
+		}
+		element[key] = value
+	}
+
+	content, err := json.Marshal(element)
+	if err != nil {
+		t.Fatalf("marshal element: %v", err)
+	}
+	return content
+}
+
+func TestParseElementTypes(t *testing.T) {
+	for elementType := range elementTypes {
+		t.Run(elementType, func(t *testing.T) {
+			if _, err := ParseElement(elementJSON(t, elementType, nil)); err != nil {
+				t.Errorf("valid %s rejected: %v", elementType, err)
+			}
+		})
+	}
+}
+
+func TestParseElementErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		elementType string
+		fields      map[string]interface{}
+		field       string
+		message     string
+	}{
+		{"missing id", TypeRectangle, map[string]interface{}{"id": ""}, "id", "is required"},
+		{"missing type", "", nil, "type", "is required"},
+		{"unknown type", "hexagon", nil, "type", `unknown element type "hexagon"`},
+		{"negative width", TypeRectangle, map[string]interface{}{"width": -1}, "width", "must not be negative"},
+		{"negative height", TypeEllipse, map[string]interface{}{"height": -1}, "height", "must not be negative"},
+		{"negative stroke width", TypeDiamond, map[string]interface{}{"strokeWidth": -2}, "strokeWidth", "must not be negative"},
+		{"opacity above 100", TypeRectangle, map[string]interface{}{"opacity": 101}, "opacity", "must be between 0 and 100"},
+		{"negative version", TypeRectangle, map[string]interface{}{"version": -1}, "version", "must not be negative"},
+		{"unknown fill style", TypeRectangle, map[string]interface{}{"fillStyle": "stripes"}, "fillStyle", `unknown fill style "stripes"`},
+		{"unknown stroke style", TypeRectangle, map[string]interface{}{"strokeStyle": "wavy"}, "strokeStyle", `unknown stroke style "wavy"`},
+		{"empty group id", TypeRectangle, map[string]interface{}{"groupIds": []string{"g1", ""}}, "groupIds[1]", "must not be empty"},
+		{"bound element without id", TypeRectangle, map[string]interface{}{"boundElements": []map[string]string{{"type": "arrow"}}}, "boundElements[0].id", "is required"},
+		{"wrong field type", TypeRectangle, map[string]interface{}{"x": "10"}, "x", "expected number"},
+		{"text without font size", TypeText, map[string]interface{}{"fontSize": 0}, "fontSize", "must be positive"},
+		{"unknown text align", TypeText, map[string]interface{}{"textAlign": "justify"}, "textAlign", `unknown alignment "justify"`},
+		{"unknown vertical align", TypeText, map[string]interface{}{"verticalAlign": "baseline"}, "verticalAlign", `unknown alignment "baseline"`},
+		{"arrow with one point", TypeArrow, map[string]interface{}{"points": [][2]float64{{0, 0}}}, "points", "arrow needs at least 2 points"},
+		{"line without points", TypeLine, map[string]interface{}{"points": nil}, "points", "line needs at least 2 points"},
+		{"binding without element", TypeArrow, map[string]interface{}{"startBinding": map[string]interface{}{"focus": 0, "gap": 1}}, "startBinding.elementId", "is required"},
+		{"freedraw without points", TypeFreeDraw, map[string]interface{}{"points": [][2]float64{}}, "points", "freedraw needs at least 1 point"},
+		{"pressures do not match points", TypeFreeDraw, map[string]interface{}{"pressures": []float64{0.5}}, "pressures", "has 1 values for 3 points"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseElement(elementJSON(t, test.elementType, test.fields))
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("err = %v, want a *ValidationError", err)
+			}
+			if validationErr.Field != test.field {
+				t.Errorf("field = %q, want %q", validationErr.Field, test.field)
+			}
+			if !strings.HasPrefix(validationErr.Message, test.message) {
+				t.Errorf("message = %q, want %q", validationErr.Message, test.message)
+			}
+			if test.field != "id" && validationErr.ElementID != "el-1" {
+				t.Errorf("element id = %q, want %q", validationErr.ElementID, "el-1")
+			}
+		})
+	}
+}
+
+func TestMinimumPoints(t *testing.T) {
+	tests := []struct {
+		elementType string
+		points      [][2]float64
+		valid       bool
+	}{
+		{TypeArrow, [][2]float64{}, false},
+		{TypeArrow, [][2]float64{{0, 0}}, false},
+		{TypeArrow, [][2]float64{{0, 0}, {1, 1}}, true},
+		{TypeLine, [][2]float64{{0, 0}}, false},
+		{TypeLine, [][2]float64{{0, 0}, {1, 1}, {2, 0}}, true},
+		{TypeFreeDraw, [][2]float64{}, false},
+		{TypeFreeDraw, [][2]float64{{0, 0}}, true},
+	}
+
+	for _, test := range tests {
+		content := elementJSON(t, test.elementType, map[string]interface{}{"points": test.points, "pressures": nil})
+
+		_, err := ParseElement(content)
+		if (err == nil) != test.valid {
+			t.Errorf("%s with %d points: err = %v, want valid %v", test.elementType, len(test.points), err, test.valid)
+		}
+
+		// Nas edições ao vivo o elemento ainda pode estar sendo desenhado.
+		if _, err := ParseDraftElement(content); err != nil {
+			t.Errorf("draft %s with %d points: %v", test.elementType, len(test.points), err)
+		}
+	}
+}
+
+func TestDraftKeepsOtherRules(t *testing.T) {
+	content := elementJSON(t, TypeArrow, map[string]interface{}{"points": [][2]float64{{0, 0}}, "width": -1})
+	if _, err := ParseDraftElement(content); err == nil {
+		t.Error("draft with negative width accepted")
+	}
+}
+
+func TestSceneValidate(t *testing.T) {
+	rectangle := `{"id":"r","type":"rectangle","width":10,"height":10}`
+	arrow := `{"id":"a","type":"arrow","points":[[0,0],[1,1]]}`
+
+	tests := []struct {
+		name    string
+		content string
+		field   string
+	}{
+		{"valid", `{"type":"excalidraw","elements":[` + rectangle + `]}`, ""},
+		{"wrong scene type", `{"type":"excalidrawlib","elements":[]}`, "type"},
+		{"duplicated id", `{"elements":[` + rectangle + `,` + rectangle + `]}`, "id"},
+		{"missing references are tolerated", `{"elements":[{"id":"t","type":"text","fontSize":20,"containerId":"gone","frameId":"gone"}]}`, ""},
+		{"frame id points to a rectangle", `{"elements":[` + rectangle + `,{"id":"x","type":"ellipse","frameId":"r"}]}`, "frameId"},
+		{"text inside text", `{"elements":[{"id":"t1","type":"text","fontSize":20},{"id":"t2","type":"text","fontSize":20,"containerId":"t1"}]}`, "containerId"},
+		{"arrow bound to an arrow", `{"elements":[` + arrow + `,{"id":"b","type":"arrow","points":[[0,0],[1,1]],"endBinding":{"elementId":"a","focus":0,"gap":1}}]}`, "endBinding.elementId"},
+		{"file without mime type", `{"elements":[],"files":{"f":{"id":"f","dataURL":"data:image/png;base64,AA=="}}}`, `files["f"].mimeType`},
+		{"file with another id", `{"elements":[],"files":{"f":{"id":"g","mimeType":"image/png","dataURL":"data:image/png;base64,AA=="}}}`, `files["f"].id`},
+		{"file that is not a data URL", `{"elements":[],"files":{"f":{"mimeType":"image/png","dataURL":"https://example.com/a.png"}}}`, `files["f"].dataURL`},
+		{"elements is not an array", `{"elements":{}}`, "elements"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.content))
+			if test.field == "" {
+				if err != nil {
+					t.Errorf("valid scene rejected: %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("err = %v, want a *ValidationError", err)
+			}
+			if validationErr.Field != test.field {
+				t.Errorf("field = %q, want %q (%v)", validationErr.Field, test.field, err)
+			}
+		})
+	}
+}
+
+func TestSceneValidateReportsIndex(t *testing.T) {
+	content := `{"elements":[{"id":"ok","type":"rectangle"},{"id":"bad","type":"line","points":[[0,0]]}]}`
+
+	_, err := Parse([]byte(content))
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("err = %v, want a *ValidationError", err)
+	}
+	if validationErr.Index != 1 {
+		t.Errorf("index = %d, want 1", validationErr.Index)
+	}
+	want := `element 1 (id "bad", line): points: line needs at least 2 points`
+	if err.Error() != want {
+		t.Errorf("message = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestParseExportedFile(t *testing.T) {
+	content, err := os.ReadFile("testdata/exported.excalidraw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scene, err := Parse(content)
+	if err != nil {
+		t.Fatalf("exported file rejected: %v", err)
+	}
+
+	if len(scene.Elements) != 11 {
+		t.Errorf("elements = %d, want 11", len(scene.Elements))
+	}
+	if len(scene.Visible()) != 10 {
+		t.Errorf("visible elements = %d, want 10", len(scene.Visible()))
+	}
+	if _, ok := scene.Files["5e1f0c2b9a"]; !ok {
+		t.Error("embedded image file missing")
+	}
+}
@@ -11,6 +11,7 @@ const (
 	EINVALID        = "invalid"
 	ENOTFOUND       = "not_found"
 	ENOTIMPLEMENTED = "not_implemented"
+	EPRECONDITION   = "precondition_failed"
 	EUNAUTHORIZED   = "unauthorized"
 )
 
@@ -0,0 +1,587 @@
+package render
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"myScalidraw/pkg/excalidraw"
+)
+
+// Options controla a exportação. Frame é o id ou o nome de um frame; quando
+// informado só o conteúdo dele é exportado, recortado pela sua borda.
+type Options struct {
+	Background bool
+	DarkMode   bool
+	Padding    float64
+	Frame      string
+}
+
+var ErrFrameNotFound = errors.New("frame not found")
+
+const (
+	defaultBackground = "#ffffff"
+
+	// Mesmo ângulo e espaçamento do preenchimento hachurado do rough.js no
+	// Excalidraw.
+	hachureAngle = -41 * math.Pi / 180
+	hachureGap   = 4
+
+	// O freedraw é um contorno gerado pelo perfect-freehand; aqui vira um
+	// traço com a espessura média dele.
+	freedrawWidth = 2.5
+
+	frameStroke          = "#bbbbbb"
+	frameStrokeWidth     = 2
+	frameRadius          = 8
+	frameNameSize        = 14
+	frameNameOffset      = 3
+	frameNameColor       = "#999999"
+	frameNameColorDark   = "#7a7a7a"
+	imagePlaceholderFill = "#f1f3f5"
+)
+
+var arrowheadSizes = map[string]float64{
+	"arrow":                25,
+	"bar":                  15,
+	"dot":                  15,
+	"circle":               15,
+	"circle_outline":       15,
+	"triangle":             15,
+	"triangle_outline":     15,
+	"diamond":              12,
+	"diamond_outline":      12,
+	"crowfoot_one":         20,
+	"crowfoot_many":        20,
+	"crowfoot_one_or_many": 20,
+}
+
+type builder struct {
+	scene    *excalidraw.Scene
+	options  Options
+	elements map[string]*excalidraw.Element
+	clips    map[string]*Path
+	items    []Item
+}
+
+// Build monta o desenho da cena. As coordenadas continuam as da cena;
+// Drawing.Bounds diz qual região exportar, já com o padding.
+func Build(scene *excalidraw.Scene, options Options) (*Drawing, error) {
+	visible := scene.Visible()
+
+	b := &builder{
+		scene:    scene,
+		options:  options,
+		elements: make(map[string]*excalidraw.Element, len(visible)),
+		clips:    map[string]*Path{},
+	}
+	for _, element := range visible {
+		b.elements[element.ID] = element
+	}
+
+	var exported *excalidraw.Element
+	if options.Frame != "" {
+		for _, element := range visible {
+			if element.IsFrame() && (element.ID == options.Frame || (element.Name != nil && *element.Name == options.Frame)) {
+				exported = element
+				break
+			}
+		}
+		if exported == nil {
+			return nil, ErrFrameNotFound
+		}
+	}
+
+	bounds := EmptyRect()
+	for _, element := range visible {
+		if exported != nil && !b.inFrame(element, exported) {
+			continue
+		}
+
+		start := len(b.items)
+		b.element(element)
+
+		clip := b.frameClip(element)
+		for i := start; i < len(b.items); i++ {
+			item := &b.items[i]
+			if clip != nil {
+				item.Clip = append([]*Path{clip}, item.Clip...)
+			}
+
+			itemBounds := item.Bounds()
+			for _, path := range item.Clip {
+				itemBounds = itemBounds.Intersect(path.Bounds())
+			}
+			if !itemBounds.IsEmpty() {
+				bounds = bounds.Union(itemBounds)
+			}
+		}
+	}
+
+	if exported != nil {
+		bounds = rectanglePath(exported.X, exported.Y, exported.Width, exported.Height, 0).
+			Transform(b.rotation(exported)).
+			Bounds()
+	}
+	if bounds.IsEmpty() {
+		bounds = Rect{Max: Point{1, 1}}
+	}
+
+	drawing := &Drawing{
+		Bounds: bounds.Expand(options.Padding),
+		Items:  b.items,
+	}
+
+	if options.Background {
+		background := scene.AppState.ViewBackgroundColor()
+		if background == "" {
+			background = defaultBackground
+		}
+		drawing.Background = b.color(background, White)
+	}
+
+	return drawing, nil
+}
+
+// BuildPages monta uma página por frame, na ordem em que estão na cena, cada
+// uma do tamanho exato do frame. Sem frames, ou com options.Frame, devolve uma
+// página só, como Build; Padding só vale para essa página.
+func BuildPages(scene *excalidraw.Scene, options Options) ([]*Drawing, error) {
+	if options.Frame == "" {
+		var pages []*Drawing
+		for _, element := range scene.Visible() {
+			if !element.IsFrame() {
+				continue
+			}
+
+			frameOptions := options
+			frameOptions.Frame = element.ID
+			frameOptions.Padding = 0
+			page, err := Build(scene, frameOptions)
+			if err != nil {
+				return nil, err
+			}
+			pages = append(pages, page)
+		}
+		if len(pages) > 0 {
+			return pages, nil
+		}
+	}
+
+	page, err := Build(scene, options)
+	if err != nil {
+		return nil, err
+	}
+	return []*Drawing{page}, nil
+}
+
+// inFrame diz se o elemento pertence ao frame, direto ou como texto de um
+// contêiner que pertence.
+func (b *builder) inFrame(element *excalidraw.Element, frame *excalidraw.Element) bool {
+	if element.FrameID != nil && *element.FrameID == frame.ID {
+		return true
+	}
+	if element.ContainerID != nil {
+		if container, ok := b.elements[*element.ContainerID]; ok && container.FrameID != nil {
+			return *container.FrameID == frame.ID
+		}
+	}
+	return false
+}
+
+func (b *builder) frameClip(element *excalidraw.Element) *Path {
+	frameID := ""
+	if element.FrameID != nil {
+		frameID = *element.FrameID
+	} else if element.ContainerID != nil {
+		if container, ok := b.elements[*element.ContainerID]; ok && container.FrameID != nil {
+			frameID = *container.FrameID
+		}
+	}
+
+	frame, ok := b.elements[frameID]
+	if !ok || !frame.IsFrame() || frame.ID == element.ID {
+		return nil
+	}
+
+	if clip, ok := b.clips[frame.ID]; ok {
+		return clip
+	}
+	clip := rectanglePath(frame.X, frame.Y, frame.Width, frame.Height, 0).Transform(b.rotation(frame))
+	b.clips[frame.ID] = clip
+	return clip
+}
+
+func (b *builder) color(value string, fallback Color) Color {
+	color, ok := ParseColor(value)
+	if !ok {
+		color = fallback
+	}
+	if b.options.DarkMode {
+		color = color.Dark()
+	}
+	return color
+}
+
+// rotation gira em torno do centro da caixa do elemento, como o Excalidraw.
+func (b *builder) rotation(element *excalidraw.Element) func(Point) Point {
+	center := b.center(element)
+	return func(point Point) Point {
+		return rotate(point, center, element.Angle)
+	}
+}
+
+func (b *builder) center(element *excalidraw.Element) Point {
+	if len(element.Points) > 0 && (element.IsLinear() || element.Type == excalidraw.TypeFreeDraw) {
+		bounds := EmptyRect()
+		for _, point := range element.Points {
+			bounds = bounds.Add(Point{element.X + point[0], element.Y + point[1]})
+		}
+		return Point{(bounds.Min.X + bounds.Max.X) / 2, (bounds.Min.Y + bounds.Max.Y) / 2}
+	}
+	return Point{element.X + element.Width/2, element.Y + element.Height/2}
+}
+
+func strokeWidth(element *excalidraw.Element) float64 {
+	if element.StrokeWidth <= 0 {
+		return 1
+	}
+	return element.StrokeWidth
+}
+
+func dash(element *excalidraw.Element) []float64 {
+	width := strokeWidth(element)
+	switch element.StrokeStyle {
+	case "dashed":
+		return []float64{8, 8 + width}
+	case "dotted":
+		return []float64{1.5, 6 + width}
+	default:
+		return nil
+	}
+}
+
+// cornerRadius segue o getCornerRadius do Excalidraw.
+func cornerRadius(element *excalidraw.Element) float64 {
+	if element.Roundness == nil {
+		return 0
+	}
+
+	size := math.Min(element.Width, element.Height)
+	if element.Roundness.Type != 3 {
+		return size * 0.25
+	}
+
+	fixed := 32.0
+	if element.Roundness.Value != nil {
+		fixed = *element.Roundness.Value
+	}
+	if size <= fixed/0.25 {
+		return size * 0.25
+	}
+	return fixed
+}
+
+func (b *builder) element(element *excalidraw.Element) {
+	opacity := element.OpacityValue()
+	stroke := b.color(element.StrokeColor, Black).WithOpacity(opacity)
+	fill := b.color(element.BackgroundColor, Transparent).WithOpacity(opacity)
+	rotation := b.rotation(element)
+
+	switch element.Type {
+	case excalidraw.TypeRectangle, excalidraw.TypeEmbeddable, excalidraw.TypeIframe:
+		path := rectanglePath(element.X, element.Y, element.Width, element.Height, cornerRadius(element))
+		b.shape(element, path, rotation, fill, stroke)
+
+	case excalidraw.TypeEllipse:
+		path := ellipsePath(element.X+element.Width/2, element.Y+element.Height/2, element.Width/2, element.Height/2)
+		b.shape(element, path, rotation, fill, stroke)
+
+	case excalidraw.TypeDiamond:
+		path := polygonPath([]Point{
+			{element.X + element.Width/2, element.Y},
+			{element.X + element.Width, element.Y + element.Height/2},
+			{element.X + element.Width/2, element.Y + element.Height},
+			{element.X, element.Y + element.Height/2},
+		})
+		b.shape(element, path, rotation, fill, stroke)
+
+	case excalidraw.TypeArrow, excalidraw.TypeLine:
+		b.linear(element, rotation, fill, stroke)
+
+	case excalidraw.TypeFreeDraw:
+		b.freedraw(element, rotation, stroke)
+
+	case excalidraw.TypeText:
+		b.text(element, stroke)
+
+	case excalidraw.TypeImage:
+		b.image(element, rotation)
+
+	case excalidraw.TypeFrame, excalidraw.TypeMagicFrame:
+		b.frame(element, rotation)
+	}
+}
+
+// shape desenha o preenchimento e o contorno de uma forma fechada. path está
+// nas coordenadas do elemento antes da rotação.
+func (b *builder) shape(element *excalidraw.Element, path *Path, rotation func(Point) Point, fill Color, stroke Color) {
+	width := strokeWidth(element)
+	rotated := path.Transform(rotation)
+
+	if fill.IsVisible() {
+		switch element.FillStyle {
+		case "", "solid":
+			b.items = append(b.items, Item{Path: rotated, Fill: fill})
+
+		default:
+			angles := []float64{hachureAngle}
+			if element.FillStyle == "cross-hatch" {
+				angles = append(angles, hachureAngle+math.Pi/2)
+			}
+			for _, angle := range angles {
+				b.items = append(b.items, Item{
+					Path:        hatchPath(path.Bounds(), angle, width*hachureGap).Transform(rotation),
+					Stroke:      fill,
+					StrokeWidth: width / 2,
+					Clip:        []*Path{rotated},
+				})
+			}
+		}
+	}
+
+	if stroke.IsVisible() {
+		b.items = append(b.items, Item{Path: rotated, Stroke: stroke, StrokeWidth: width, Dash: dash(element)})
+	}
+}
+
+func (b *builder) linear(element *excalidraw.Element, rotation func(Point) Point, fill Color, stroke Color) {
+	points := make([]Point, 0, len(element.Points))
+	for _, point := range element.Points {
+		points = append(points, Point{element.X + point[0], element.Y + point[1]})
+	}
+	if len(points) < 2 {
+		return
+	}
+
+	var path *Path
+	if element.Roundness != nil && !element.Elbowed {
+		path = curvePath(points)
+	} else {
+		path = polylinePath(points)
+	}
+
+	first, last := points[0], points[len(points)-1]
+	closed := element.Type == excalidraw.TypeLine && len(points) > 2 && math.Hypot(first.X-last.X, first.Y-last.Y) < 1
+	if closed {
+		closedPath := &Path{Segments: append(append([]Segment{}, path.Segments...), Segment{Op: Close})}
+		b.shape(element, closedPath, rotation, fill, stroke)
+		return
+	}
+
+	if !stroke.IsVisible() {
+		return
+	}
+	b.items = append(b.items, Item{Path: path.Transform(rotation), Stroke: stroke, StrokeWidth: strokeWidth(element), Dash: dash(element)})
+
+	if element.StartArrowhead != nil && *element.StartArrowhead != "" {
+		if tip, direction, length, ok := endDirection(path, true); ok {
+			b.arrowhead(element, *element.StartArrowhead, tip, direction, length, rotation, stroke)
+		}
+	}
+	if element.EndArrowhead != nil && *element.EndArrowhead != "" {
+		if tip, direction, length, ok := endDirection(path, false); ok {
+			b.arrowhead(element, *element.EndArrowhead, tip, direction, length, rotation, stroke)
+		}
+	}
+}
+
+func (b *builder) arrowhead(element *excalidraw.Element, kind string, tip Point, direction Point, length float64, rotation func(Point) Point, color Color) {
+	size, ok := arrowheadSizes[kind]
+	if !ok {
+		kind, size = "arrow", arrowheadSizes["arrow"]
+	}
+	multiplier := 0.5
+	if kind == "diamond" || kind == "diamond_outline" {
+		multiplier = 0.25
+	}
+	size = math.Min(size, length*multiplier)
+	if size <= 0 {
+		return
+	}
+
+	back := func(distance float64) Point {
+		return Point{tip.X - direction.X*distance, tip.Y - direction.Y*distance}
+	}
+	side := func(point Point, distance float64) Point {
+		return Point{point.X - direction.Y*distance, point.Y + direction.X*distance}
+	}
+	wing := func(angle float64) Point {
+		return rotate(back(size), tip, angle)
+	}
+
+	path := &Path{}
+	filled := false
+
+	switch kind {
+	case "arrow":
+		path.MoveTo(wing(20 * math.Pi / 180))
+		path.LineTo(tip)
+		path.LineTo(wing(-20 * math.Pi / 180))
+
+	case "triangle", "triangle_outline":
+		path = polygonPath([]Point{tip, wing(25 * math.Pi / 180), wing(-25 * math.Pi / 180)})
+		filled = kind == "triangle"
+
+	case "bar":
+		path.MoveTo(side(tip, size/2))
+		path.LineTo(side(tip, -size/2))
+
+	case "dot", "circle", "circle_outline":
+		center := back(size / 2)
+		path = ellipsePath(center.X, center.Y, size/2, size/2)
+		filled = kind != "circle_outline"
+
+	case "diamond", "diamond_outline":
+		middle := back(size / 2)
+		path = polygonPath([]Point{tip, side(middle, size/4), back(size), side(middle, -size/4)})
+		filled = kind == "diamond"
+
+	case "crowfoot_one":
+		middle := back(size / 2)
+		path.MoveTo(side(middle, size/2))
+		path.LineTo(side(middle, -size/2))
+
+	case "crowfoot_many", "crowfoot_one_or_many":
+		path.MoveTo(side(tip, size/2))
+		path.LineTo(back(size))
+		path.LineTo(side(tip, -size/2))
+		if kind == "crowfoot_one_or_many" {
+			path.MoveTo(side(back(size), size/2))
+			path.LineTo(side(back(size), -size/2))
+		}
+	}
+
+	item := Item{Path: path.Transform(rotation), Stroke: color, StrokeWidth: strokeWidth(element)}
+	if filled {
+		item.Fill = color
+	}
+	b.items = append(b.items, item)
+}
+
+func (b *builder) freedraw(element *excalidraw.Element, rotation func(Point) Point, stroke Color) {
+	if !stroke.IsVisible() || len(element.Points) == 0 {
+		return
+	}
+
+	width := strokeWidth(element) * freedrawWidth
+	points := make([]Point, 0, len(element.Points))
+	for _, point := range element.Points {
+		points = append(points, rotation(Point{element.X + point[0], element.Y + point[1]}))
+	}
+
+	if len(points) == 1 {
+		b.items = append(b.items, Item{Path: ellipsePath(points[0].X, points[0].Y, width/2, width/2), Fill: stroke})
+		return
+	}
+	b.items = append(b.items, Item{Path: polylinePath(points), Stroke: stroke, StrokeWidth: width})
+}
+
+func (b *builder) text(element *excalidraw.Element, color Color) {
+	if !color.IsVisible() || element.Text == "" {
+		return
+	}
+
+	lineHeight := element.LineHeight
+	if lineHeight <= 0 {
+		lineHeight = 1.25
+	}
+
+	align := element.TextAlign
+	x := element.X
+	switch align {
+	case AlignCenter:
+		x += element.Width / 2
+	case AlignRight:
+		x += element.Width
+	default:
+		align = AlignLeft
+	}
+
+	text := strings.ReplaceAll(element.Text, "\r\n", "\n")
+	b.items = append(b.items, Item{Text: &Text{
+		Lines:      strings.Split(text, "\n"),
+		X:          x,
+		Y:          element.Y,
+		Width:      element.Width,
+		FontSize:   element.FontSize,
+		LineHeight: element.FontSize * lineHeight,
+		Font:       FontFor(element.FontFamily),
+		Align:      align,
+		Color:      color,
+		Angle:      element.Angle,
+		Center:     Point{element.X + element.Width/2, element.Y + element.Height/2},
+	}})
+}
+
+// image usa o arquivo embutido na cena; sem ele desenha um retângulo no
+// lugar. As imagens não passam pelo filtro do modo escuro, como no Excalidraw.
+func (b *builder) image(element *excalidraw.Element, rotation func(Point) Point) {
+	var file excalidraw.BinaryFile
+	ok := false
+	if element.FileID != nil {
+		file, ok = b.scene.Files[*element.FileID]
+	}
+
+	if !ok || !strings.HasPrefix(file.DataURL, "data:image/") {
+		path := rectanglePath(element.X, element.Y, element.Width, element.Height, 0).Transform(rotation)
+		b.items = append(b.items, Item{Path: path, Fill: b.color(imagePlaceholderFill, White).WithOpacity(element.OpacityValue())})
+		return
+	}
+
+	image := &Image{
+		X:        element.X,
+		Y:        element.Y,
+		Width:    element.Width,
+		Height:   element.Height,
+		MimeType: file.MimeType,
+		DataURL:  file.DataURL,
+		Opacity:  element.OpacityValue(),
+		Angle:    element.Angle,
+	}
+	if element.Scale != nil {
+		image.FlipX = element.Scale[0] < 0
+		image.FlipY = element.Scale[1] < 0
+	}
+	b.items = append(b.items, Item{Image: image})
+}
+
+func (b *builder) frame(element *excalidraw.Element, rotation func(Point) Point) {
+	path := rectanglePath(element.X, element.Y, element.Width, element.Height, frameRadius).Transform(rotation)
+	b.items = append(b.items, Item{Path: path, Stroke: b.color(frameStroke, Black), StrokeWidth: frameStrokeWidth})
+
+	name := "Frame"
+	if element.Name != nil && *element.Name != "" {
+		name = *element.Name
+	} else if element.Type == excalidraw.TypeMagicFrame {
+		name = "AI Frame"
+	}
+
+	nameColor, _ := ParseColor(frameNameColor)
+	if b.options.DarkMode {
+		nameColor, _ = ParseColor(frameNameColorDark)
+	}
+
+	lineHeight := frameNameSize * 1.25
+	b.items = append(b.items, Item{Text: &Text{
+		Lines:      []string{name},
+		X:          element.X,
+		Y:          element.Y - lineHeight - frameNameOffset,
+		Width:      element.Width,
+		FontSize:   frameNameSize,
+		LineHeight: lineHeight,
+		Font:       FontFor(FontHelvetica),
+		Align:      AlignLeft,
+		Color:      nameColor,
+	}})
+}
@@ -0,0 +1,159 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Color guarda a opacidade do elemento junto com a cor; A = 0 é "sem cor".
+type Color struct {
+	R, G, B uint8
+	A       float64
+}
+
+var (
+	Transparent = Color{}
+	White       = Color{255, 255, 255, 1}
+	Black       = Color{0, 0, 0, 1}
+)
+
+var namedColors = map[string]Color{
+	"black":   Black,
+	"white":   White,
+	"red":     {255, 0, 0, 1},
+	"green":   {0, 128, 0, 1},
+	"blue":    {0, 0, 255, 1},
+	"yellow":  {255, 255, 0, 1},
+	"orange":  {255, 165, 0, 1},
+	"gray":    {128, 128, 128, 1},
+	"grey":    {128, 128, 128, 1},
+	"purple":  {128, 0, 128, 1},
+	"pink":    {255, 192, 203, 1},
+	"cyan":    {0, 255, 255, 1},
+	"magenta": {255, 0, 255, 1},
+}
+
+// ParseColor entende as formas que aparecem nas cenas: #rgb, #rgba, #rrggbb,
+// #rrggbbaa, rgb()/rgba(), alguns nomes e "transparent".
+func ParseColor(value string) (Color, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" || value == "transparent" || value == "none" {
+		return Transparent, true
+	}
+	if color, ok := namedColors[value]; ok {
+		return color, true
+	}
+
+	if strings.HasPrefix(value, "#") {
+		hex := value[1:]
+		if len(hex) == 3 || len(hex) == 4 {
+			var expanded strings.Builder
+			for _, digit := range hex {
+				expanded.WriteRune(digit)
+				expanded.WriteRune(digit)
+			}
+			hex = expanded.String()
+		}
+		if len(hex) != 6 && len(hex) != 8 {
+			return Transparent, false
+		}
+
+		parsed, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return Transparent, false
+		}
+		if len(hex) == 6 {
+			parsed = parsed<<8 | 0xff
+		}
+		return Color{
+			R: uint8(parsed >> 24),
+			G: uint8(parsed >> 16),
+			B: uint8(parsed >> 8),
+			A: float64(uint8(parsed)) / 255,
+		}, true
+	}
+
+	if strings.HasPrefix(value, "rgb") {
+		start, end := strings.Index(value, "("), strings.Index(value, ")")
+		if start < 0 || end < start {
+			return Transparent, false
+		}
+		parts := strings.FieldsFunc(value[start+1:end], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '/'
+		})
+		if len(parts) < 3 {
+			return Transparent, false
+		}
+
+		var channels [4]float64
+		channels[3] = 1
+		for i := 0; i < len(parts) && i < 4; i++ {
+			number, err := strconv.ParseFloat(strings.TrimSuffix(parts[i], "%"), 64)
+			if err != nil {
+				return Transparent, false
+			}
+			if strings.HasSuffix(parts[i], "%") {
+				if i == 3 {
+					number /= 100
+				} else {
+					number *= 2.55
+				}
+			}
+			channels[i] = number
+		}
+		return Color{
+			R: clampChannel(channels[0]),
+			G: clampChannel(channels[1]),
+			B: clampChannel(channels[2]),
+			A: math.Max(0, math.Min(1, channels[3])),
+		}, true
+	}
+
+	return Transparent, false
+}
+
+func clampChannel(value float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(255, value))))
+}
+
+func (c Color) IsVisible() bool {
+	return c.A > 0
+}
+
+func (c Color) WithOpacity(opacity float64) Color {
+	c.A *= opacity
+	return c
+}
+
+func (c Color) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Dark aplica o mesmo filtro do modo escuro do Excalidraw,
+// invert(93%) hue-rotate(180deg), para que as cores exportadas batam com a
+// tela.
+func (c Color) Dark() Color {
+	const amount = 0.93
+
+	invert := func(channel uint8) float64 {
+		value := float64(channel) / 255
+		return value*(1-amount) + (1-value)*amount
+	}
+	r, g, b := invert(c.R), invert(c.G), invert(c.B)
+
+	sin, cos := math.Sincos(math.Pi)
+	rotated := [3]float64{
+		r*(0.213+cos*0.787-sin*0.213) + g*(0.715-cos*0.715-sin*0.715) + b*(0.072-cos*0.072+sin*0.928),
+		r*(0.213-cos*0.213+sin*0.143) + g*(0.715+cos*0.285+sin*0.140) + b*(0.072-cos*0.072-sin*0.283),
+		r*(0.213-cos*0.213-sin*0.787) + g*(0.715-cos*0.715+sin*0.715) + b*(0.072+cos*0.928+sin*0.072),
+	}
+
+	return Color{
+		R: clampChannel(rotated[0] * 255),
+		G: clampChannel(rotated[1] * 255),
+		B: clampChannel(rotated[2] * 255),
+		A: c.A,
+	}
+}
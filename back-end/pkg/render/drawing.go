@@ -0,0 +1,345 @@
+// Package render transforma uma cena do Excalidraw numa lista de primitivas
+// (caminhos, textos e imagens) já posicionadas e coloridas. Os formatos de
+// exportação só precisam saber desenhar essas primitivas.
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+)
+
+type Point struct {
+	X, Y float64
+}
+
+type SegmentOp int
+
+const (
+	MoveTo SegmentOp = iota
+	LineTo
+	CubicTo
+	Close
+)
+
+// Segment usa Points[0] em MoveTo e LineTo; em CubicTo são os dois pontos de
+// controle e o ponto final.
+type Segment struct {
+	Op     SegmentOp
+	Points [3]Point
+}
+
+type Path struct {
+	Segments []Segment
+}
+
+func (p *Path) MoveTo(point Point) {
+	p.Segments = append(p.Segments, Segment{Op: MoveTo, Points: [3]Point{point}})
+}
+
+func (p *Path) LineTo(point Point) {
+	p.Segments = append(p.Segments, Segment{Op: LineTo, Points: [3]Point{point}})
+}
+
+func (p *Path) CubicTo(control1, control2, point Point) {
+	p.Segments = append(p.Segments, Segment{Op: CubicTo, Points: [3]Point{control1, control2, point}})
+}
+
+func (p *Path) Close() {
+	p.Segments = append(p.Segments, Segment{Op: Close})
+}
+
+func (p *Path) IsEmpty() bool {
+	return len(p.Segments) == 0
+}
+
+// Transform devolve uma cópia com fn aplicada a todos os pontos.
+func (p *Path) Transform(fn func(Point) Point) *Path {
+	transformed := &Path{Segments: make([]Segment, len(p.Segments))}
+	for i, segment := range p.Segments {
+		transformed.Segments[i].Op = segment.Op
+		for j, point := range segment.Points {
+			transformed.Segments[i].Points[j] = fn(point)
+		}
+	}
+	return transformed
+}
+
+// Bounds inclui os pontos de controle, o que basta para enquadrar o desenho.
+func (p *Path) Bounds() Rect {
+	bounds := EmptyRect()
+	for _, segment := range p.Segments {
+		switch segment.Op {
+		case MoveTo, LineTo:
+			bounds = bounds.Add(segment.Points[0])
+		case CubicTo:
+			for _, point := range segment.Points {
+				bounds = bounds.Add(point)
+			}
+		}
+	}
+	return bounds
+}
+
+type Rect struct {
+	Min, Max Point
+}
+
+func EmptyRect() Rect {
+	return Rect{
+		Min: Point{math.Inf(1), math.Inf(1)},
+		Max: Point{math.Inf(-1), math.Inf(-1)},
+	}
+}
+
+func (r Rect) IsEmpty() bool {
+	return r.Min.X > r.Max.X || r.Min.Y > r.Max.Y
+}
+
+func (r Rect) Add(point Point) Rect {
+	return Rect{
+		Min: Point{math.Min(r.Min.X, point.X), math.Min(r.Min.Y, point.Y)},
+		Max: Point{math.Max(r.Max.X, point.X), math.Max(r.Max.Y, point.Y)},
+	}
+}
+
+func (r Rect) Union(other Rect) Rect {
+	if other.IsEmpty() {
+		return r
+	}
+	return r.Add(other.Min).Add(other.Max)
+}
+
+func (r Rect) Intersect(other Rect) Rect {
+	return Rect{
+		Min: Point{math.Max(r.Min.X, other.Min.X), math.Max(r.Min.Y, other.Min.Y)},
+		Max: Point{math.Min(r.Max.X, other.Max.X), math.Min(r.Max.Y, other.Max.Y)},
+	}
+}
+
+func (r Rect) Expand(amount float64) Rect {
+	if r.IsEmpty() {
+		return r
+	}
+	return Rect{
+		Min: Point{r.Min.X - amount, r.Min.Y - amount},
+		Max: Point{r.Max.X + amount, r.Max.Y + amount},
+	}
+}
+
+func (r Rect) Width() float64 {
+	return r.Max.X - r.Min.X
+}
+
+func (r Rect) Height() float64 {
+	return r.Max.Y - r.Min.Y
+}
+
+// Text é um bloco de linhas. X é a âncora horizontal conforme Align e Y o topo
+// da primeira linha; a rotação é em torno de Center.
+type Text struct {
+	Lines      []string
+	X, Y       float64
+	Width      float64
+	FontSize   float64
+	LineHeight float64
+	Font       Font
+	Align      string
+	Color      Color
+	Angle      float64
+	Center     Point
+}
+
+// Baseline é a linha de base da linha i, com o texto centralizado
+// verticalmente na altura da linha como o Excalidraw faz.
+func (t *Text) Baseline(i int) float64 {
+	return t.Y + float64(i)*t.LineHeight + t.LineHeight/2 + t.FontSize*0.35
+}
+
+// Left devolve o x onde a linha começa, dada a largura medida da linha.
+func (t *Text) Left(lineWidth float64) float64 {
+	switch t.Align {
+	case AlignCenter:
+		return t.X - lineWidth/2
+	case AlignRight:
+		return t.X - lineWidth
+	default:
+		return t.X
+	}
+}
+
+const (
+	AlignLeft   = "left"
+	AlignCenter = "center"
+	AlignRight  = "right"
+)
+
+type Image struct {
+	X, Y, Width, Height float64
+	MimeType            string
+	DataURL             string
+	Opacity             float64
+	Angle               float64
+	FlipX, FlipY        bool
+}
+
+// Limites das imagens embutidas, conferidos pelo cabeçalho antes de
+// decodificar: poucos KB de PNG podem declarar 60000x60000 pixels.
+const (
+	maxImagePixels   = 16 << 20
+	maxDrawingPixels = 64 << 20
+)
+
+// imageDecoder decodifica cada data URL de um desenho uma vez só, dentro de
+// um orçamento de pixels para o desenho todo.
+type imageDecoder struct {
+	remaining int
+
+	// images guarda as imagens já decodificadas; nil quando o formato não é
+	// suportado ou a imagem passa do limite.
+	images map[string]image.Image
+}
+
+func newImageDecoder() *imageDecoder {
+	return &imageDecoder{remaining: maxDrawingPixels, images: map[string]image.Image{}}
+}
+
+func (d *imageDecoder) decode(dataURL string) image.Image {
+	if img, ok := d.images[dataURL]; ok {
+		return img
+	}
+
+	img := decodeDataURL(dataURL, min(maxImagePixels, d.remaining))
+	if img != nil {
+		d.remaining -= img.Bounds().Dx() * img.Bounds().Dy()
+	}
+	d.images[dataURL] = img
+	return img
+}
+
+// decodeDataURL decodifica a imagem de um data URL em base64. Devolve nil
+// para formatos que não dá para rasterizar, como SVG, e para imagens com mais
+// de maxPixels pixels, sem chegar a alocá-las.
+func decodeDataURL(dataURL string, maxPixels int) image.Image {
+	header, data, ok := strings.Cut(dataURL, ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return nil
+	}
+
+	content, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		content, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+	}
+	if err != nil {
+		return nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return nil
+	}
+	if int64(config.Width)*int64(config.Height) > int64(maxPixels) {
+		return nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil
+	}
+	return img
+}
+
+func (i *Image) Center() Point {
+	return Point{i.X + i.Width/2, i.Y + i.Height/2}
+}
+
+// Item é uma primitiva: exatamente um de Path, Text ou Image. Clip lista os
+// caminhos que recortam o item, todos ao mesmo tempo.
+type Item struct {
+	Path        *Path
+	Fill        Color
+	Stroke      Color
+	StrokeWidth float64
+	Dash        []float64
+
+	Text  *Text
+	Image *Image
+
+	Clip []*Path
+}
+
+func (item *Item) Bounds() Rect {
+	switch {
+	case item.Path != nil:
+		bounds := item.Path.Bounds()
+		if item.Stroke.IsVisible() {
+			bounds = bounds.Expand(item.StrokeWidth / 2)
+		}
+		return bounds
+
+	case item.Text != nil:
+		t := item.Text
+		height := float64(len(t.Lines)) * t.LineHeight
+		left := t.Left(t.Width)
+		return rotatedBounds(left, t.Y, t.Width, height, t.Center, t.Angle)
+
+	case item.Image != nil:
+		i := item.Image
+		return rotatedBounds(i.X, i.Y, i.Width, i.Height, i.Center(), i.Angle)
+	}
+	return EmptyRect()
+}
+
+type Drawing struct {
+	Bounds     Rect
+	Background Color
+	Items      []Item
+}
+
+func (d *Drawing) Width() float64 {
+	return d.Bounds.Width()
+}
+
+func (d *Drawing) Height() float64 {
+	return d.Bounds.Height()
+}
+
+// Fonts devolve as fontes usadas nos textos, sem repetição.
+func (d *Drawing) Fonts() []Font {
+	var fonts []Font
+	seen := map[int]bool{}
+	for _, item := range d.Items {
+		if item.Text != nil && !seen[item.Text.Font.ID] {
+			seen[item.Text.Font.ID] = true
+			fonts = append(fonts, item.Text.Font)
+		}
+	}
+	return fonts
+}
+
+func rotate(point Point, center Point, angle float64) Point {
+	if angle == 0 {
+		return point
+	}
+	sin, cos := math.Sincos(angle)
+	dx, dy := point.X-center.X, point.Y-center.Y
+	return Point{
+		X: center.X + dx*cos - dy*sin,
+		Y: center.Y + dx*sin + dy*cos,
+	}
+}
+
+func rotatedBounds(x, y, width, height float64, center Point, angle float64) Rect {
+	bounds := EmptyRect()
+	for _, corner := range []Point{{x, y}, {x + width, y}, {x + width, y + height}, {x, y + height}} {
+		bounds = bounds.Add(rotate(corner, center, angle))
+	}
+	return bounds
+}
@@ -0,0 +1,116 @@
+package render
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// Font é uma família de fonte do Excalidraw, identificada pelo fontFamily dos
+// elementos de texto. Dir é a pasta da família em dist/prod/fonts do pacote
+// @excalidraw/excalidraw; vazio para fontes do sistema.
+type Font struct {
+	ID      int
+	Name    string
+	Dir     string
+	Generic string
+}
+
+const (
+	FontVirgil         = 1
+	FontHelvetica      = 2
+	FontCascadia       = 3
+	FontExcalifont     = 5
+	FontNunito         = 6
+	FontLilitaOne      = 7
+	FontComicShanns    = 8
+	FontLiberationSans = 9
+)
+
+var fonts = map[int]Font{
+	FontVirgil:         {ID: FontVirgil, Name: "Virgil", Dir: "Virgil", Generic: "cursive"},
+	FontHelvetica:      {ID: FontHelvetica, Name: "Helvetica", Generic: "sans-serif"},
+	FontCascadia:       {ID: FontCascadia, Name: "Cascadia", Dir: "Cascadia", Generic: "monospace"},
+	FontExcalifont:     {ID: FontExcalifont, Name: "Excalifont", Dir: "Excalifont", Generic: "cursive"},
+	FontNunito:         {ID: FontNunito, Name: "Nunito", Dir: "Nunito", Generic: "sans-serif"},
+	FontLilitaOne:      {ID: FontLilitaOne, Name: "Lilita One", Dir: "Lilita", Generic: "sans-serif"},
+	FontComicShanns:    {ID: FontComicShanns, Name: "Comic Shanns", Dir: "ComicShanns", Generic: "monospace"},
+	FontLiberationSans: {ID: FontLiberationSans, Name: "Liberation Sans", Dir: "Liberation", Generic: "sans-serif"},
+}
+
+// FontFor devolve a fonte do fontFamily; famílias desconhecidas caem na
+// Excalifont, a padrão do Excalidraw.
+func FontFor(family int) Font {
+	if font, ok := fonts[family]; ok {
+		return font
+	}
+	return fonts[FontExcalifont]
+}
+
+// CSSFamily é a lista de font-family usada no SVG, com fallback para emojis
+// e para a família genérica.
+func (f Font) CSSFamily() string {
+	return strconv.Quote(f.Name) + `, "Segoe UI Emoji", ` + f.Generic
+}
+
+// IsMonospace indica as fontes de largura fixa, que os formatos sem as fontes
+// embutidas aproximam com uma fonte mono.
+func (f Font) IsMonospace() bool {
+	return f.Generic == "monospace"
+}
+
+// FontCatalog guarda as fontes do Excalidraw como data URLs, para que o SVG
+// exportado as leve embutidas e apareça igual ao editor mesmo aberto num
+// <img> ou sem rede. As famílias vêm divididas em vários arquivos por faixa
+// de caracteres; todos entram.
+type FontCatalog struct {
+	sources map[int][]string
+}
+
+// LoadFontCatalog lê a pasta de fontes com o layout de dist/prod/fonts do
+// pacote @excalidraw/excalidraw, uma subpasta por família. Famílias sem
+// arquivos ficam de fora e o SVG usa a fonte genérica no lugar delas.
+func LoadFontCatalog(dir string) (*FontCatalog, error) {
+	catalog := &FontCatalog{sources: map[int][]string{}}
+
+	for id, font := range fonts {
+		if font.Dir == "" {
+			continue
+		}
+
+		files, err := filepath.Glob(filepath.Join(dir, font.Dir, "*.woff2"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("error reading font %s: %w", file, err)
+			}
+			catalog.sources[id] = append(catalog.sources[id], "data:font/woff2;base64,"+base64.StdEncoding.EncodeToString(content))
+		}
+	}
+
+	return catalog, nil
+}
+
+// Families conta as famílias com arquivos carregados.
+func (c *FontCatalog) Families() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.sources)
+}
+
+// Sources devolve os data URLs dos arquivos da fonte.
+func (c *FontCatalog) Sources(font Font) []string {
+	if c == nil {
+		return nil
+	}
+	return c.sources[font.ID]
+}
@@ -0,0 +1,132 @@
+package render
+
+import (
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Os formatos rasterizados não têm as fontes do Excalidraw; os textos usam
+// a Go Regular, ou a Go Mono para as fontes de largura fixa.
+
+var (
+	outlineFonts     [2]*sfnt.Font
+	outlineFontsErr  error
+	outlineFontsOnce sync.Once
+)
+
+func outlineFont(f Font) (*sfnt.Font, error) {
+	outlineFontsOnce.Do(func() {
+		for i, data := range [][]byte{goregular.TTF, gomono.TTF} {
+			outlineFonts[i], outlineFontsErr = sfnt.Parse(data)
+			if outlineFontsErr != nil {
+				return
+			}
+		}
+	})
+	if outlineFontsErr != nil {
+		return nil, outlineFontsErr
+	}
+
+	if f.IsMonospace() {
+		return outlineFonts[1], nil
+	}
+	return outlineFonts[0], nil
+}
+
+// textPath devolve os contornos das letras já posicionados na cena, com a
+// rotação do texto aplicada.
+func textPath(t *Text) (*Path, error) {
+	face, err := outlineFont(t.Font)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer sfnt.Buffer
+	unitsPerEm := fixed.I(int(face.UnitsPerEm()))
+	// As medidas vêm em unidades da fonte (ppem = unitsPerEm) e são escaladas
+	// para o tamanho do texto.
+	size := t.FontSize / float64(face.UnitsPerEm()) / 64
+
+	path := &Path{}
+	for i, line := range t.Lines {
+		runes := []rune(line)
+		glyphs := make([]sfnt.GlyphIndex, len(runes))
+		advances := make([]float64, len(runes))
+
+		lineWidth := 0.0
+		for j, r := range runes {
+			glyphs[j], _ = face.GlyphIndex(&buffer, r)
+			advance, err := face.GlyphAdvance(&buffer, glyphs[j], unitsPerEm, font.HintingNone)
+			if err != nil {
+				advance = unitsPerEm / 2
+			}
+			advances[j] = float64(advance) * size
+			lineWidth += advances[j]
+		}
+
+		x := t.Left(lineWidth)
+		baseline := t.Baseline(i)
+
+		for j, glyph := range glyphs {
+			if glyph != 0 {
+				segments, err := face.LoadGlyph(&buffer, glyph, unitsPerEm, nil)
+				if err == nil {
+					appendGlyph(path, segments, x, baseline, size)
+				}
+			}
+			x += advances[j]
+		}
+	}
+
+	if t.Angle == 0 {
+		return path, nil
+	}
+	return path.Transform(func(point Point) Point {
+		return rotate(point, t.Center, t.Angle)
+	}), nil
+}
+
+func appendGlyph(path *Path, segments sfnt.Segments, x, y, size float64) {
+	point := func(p fixed.Point26_6) Point {
+		return Point{x + float64(p.X)*size, y + float64(p.Y)*size}
+	}
+
+	var current Point
+	started := false
+	for _, segment := range segments {
+		switch segment.Op {
+		case sfnt.SegmentOpMoveTo:
+			if started {
+				path.Close()
+			}
+			current = point(segment.Args[0])
+			path.MoveTo(current)
+			started = true
+
+		case sfnt.SegmentOpLineTo:
+			current = point(segment.Args[0])
+			path.LineTo(current)
+
+		case sfnt.SegmentOpQuadTo:
+			control, end := point(segment.Args[0]), point(segment.Args[1])
+			path.CubicTo(
+				Point{current.X + (control.X-current.X)*2/3, current.Y + (control.Y-current.Y)*2/3},
+				Point{end.X + (control.X-end.X)*2/3, end.Y + (control.Y-end.Y)*2/3},
+				end,
+			)
+			current = end
+
+		case sfnt.SegmentOpCubeTo:
+			current = point(segment.Args[2])
+			path.CubicTo(point(segment.Args[0]), point(segment.Args[1]), current)
+		}
+	}
+	if started {
+		path.Close()
+	}
+}
@@ -0,0 +1,451 @@
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Um pixel da cena vale 0,75 pt (96 dpi), o mesmo tamanho físico da tela.
+	pdfPointsPerPixel = 0.75
+	// Os leitores não abrem páginas maiores que 14400 pt de lado.
+	pdfMaxPageSide = 14400
+)
+
+// WritePDF gera um documento com uma página por desenho. O PDF é montado à
+// mão: caminhos e textos continuam vetoriais e as imagens vão como XObjects.
+func WritePDF(w io.Writer, pages []*Drawing) error {
+	doc := &pdfDocument{
+		alphas:  map[string]string{},
+		images:  map[string]*pdfImage{},
+		decoder: newImageDecoder(),
+	}
+
+	contents := make([][]byte, len(pages))
+	sizes := make([][2]float64, len(pages))
+	for i, page := range pages {
+		var err error
+		contents[i], sizes[i], err = doc.page(page)
+		if err != nil {
+			return err
+		}
+	}
+
+	return doc.write(w, contents, sizes)
+}
+
+type pdfDocument struct {
+	// alphas mapeia a opacidade ao nome do ExtGState que a aplica.
+	alphas map[string]string
+	// images guarda as imagens pelo data URL; nil quando não deu para
+	// decodificar.
+	images     map[string]*pdfImage
+	imageOrder []*pdfImage
+	decoder    *imageDecoder
+	// fonts são a sans e a mono, nil enquanto nenhum texto as usa.
+	fonts [2]*pdfFont
+}
+
+type pdfImage struct {
+	name   string
+	width  int
+	height int
+	rgb    []byte
+	alpha  []byte
+}
+
+// page devolve o content stream da página e o tamanho dela em pontos.
+func (doc *pdfDocument) page(d *Drawing) ([]byte, [2]float64, error) {
+	scale := pdfPointsPerPixel
+	if side := math.Max(d.Width(), d.Height()) * scale; side > pdfMaxPageSide {
+		scale *= pdfMaxPageSide / side
+	}
+	width, height := d.Width()*scale, d.Height()*scale
+
+	var out bytes.Buffer
+	// A cena tem o y para baixo; o PDF, para cima.
+	fmt.Fprintf(&out, "%s 0 0 %s 0 %s cm\n", number4(scale), number4(-scale), number4(height))
+	fmt.Fprintf(&out, "1 0 0 1 %s %s cm\n", number(-d.Bounds.Min.X), number(-d.Bounds.Min.Y))
+	out.WriteString("1 J 1 j\n")
+
+	if d.Background.IsVisible() {
+		doc.fillColor(&out, d.Background)
+		fmt.Fprintf(&out, "%s %s %s %s re f\n",
+			number(d.Bounds.Min.X), number(d.Bounds.Min.Y), number(d.Width()), number(d.Height()))
+	}
+
+	for i := range d.Items {
+		if err := doc.item(&out, &d.Items[i]); err != nil {
+			return nil, [2]float64{}, err
+		}
+	}
+
+	return out.Bytes(), [2]float64{width, height}, nil
+}
+
+func (doc *pdfDocument) item(out *bytes.Buffer, item *Item) error {
+	out.WriteString("q\n")
+	for _, clip := range item.Clip {
+		pdfPath(out, clip)
+		out.WriteString("W n\n")
+	}
+
+	switch {
+	case item.Path != nil:
+		if item.Path.IsEmpty() {
+			break
+		}
+		if item.Fill.IsVisible() {
+			doc.fillColor(out, item.Fill)
+			pdfPath(out, item.Path)
+			out.WriteString("f\n")
+		}
+		if item.Stroke.IsVisible() && item.StrokeWidth > 0 {
+			doc.strokeColor(out, item.Stroke)
+			fmt.Fprintf(out, "%s w\n", number(item.StrokeWidth))
+			if len(item.Dash) > 0 {
+				values := make([]string, len(item.Dash))
+				for i, value := range item.Dash {
+					values[i] = number(value)
+				}
+				fmt.Fprintf(out, "[%s] 0 d\n", strings.Join(values, " "))
+			}
+			pdfPath(out, item.Path)
+			out.WriteString("S\n")
+		}
+
+	case item.Text != nil:
+		if err := doc.text(out, item.Text); err != nil {
+			return err
+		}
+
+	case item.Image != nil:
+		doc.image(out, item.Image)
+	}
+
+	out.WriteString("Q\n")
+	return nil
+}
+
+func (doc *pdfDocument) text(out *bytes.Buffer, t *Text) error {
+	font, err := doc.font(t.Font)
+	if err != nil {
+		return err
+	}
+	sin, cos := math.Sincos(t.Angle)
+
+	doc.fillColor(out, t.Color)
+	fmt.Fprintf(out, "BT /%s %s Tf\n", font.name, number(t.FontSize))
+	for i, line := range t.Lines {
+		if line == "" {
+			continue
+		}
+		encoded, width := font.encode(line)
+
+		origin := rotate(Point{t.Left(width * t.FontSize / font.unitsPerEm()), t.Baseline(i)}, t.Center, t.Angle)
+		// O y do texto é invertido para as letras ficarem de pé no espaço da
+		// cena.
+		fmt.Fprintf(out, "%s %s %s %s %s %s Tm ",
+			number4(cos), number4(sin), number4(sin), number4(-cos), number(origin.X), number(origin.Y))
+		out.Write(encoded)
+		out.WriteString(" Tj\n")
+	}
+	out.WriteString("ET\n")
+	return nil
+}
+
+func (doc *pdfDocument) image(out *bytes.Buffer, i *Image) {
+	img := doc.loadImage(i.DataURL)
+	if img == nil {
+		placeholder, _ := ParseColor(imagePlaceholderFill)
+		doc.fillColor(out, placeholder.WithOpacity(i.Opacity))
+		fmt.Fprintf(out, "%s cm\n", rotationMatrix(i.Angle, i.Center()))
+		fmt.Fprintf(out, "%s %s %s %s re f\n", number(i.X), number(i.Y), number(i.Width), number(i.Height))
+		return
+	}
+
+	if name := doc.alpha(i.Opacity); name != "" {
+		fmt.Fprintf(out, "/%s gs\n", name)
+	}
+
+	// A imagem ocupa o quadrado unitário com a primeira linha em cima.
+	a, d := i.Width, -i.Height
+	e, f := i.X, i.Y+i.Height
+	if i.FlipX {
+		a, e = -a, i.X+i.Width
+	}
+	if i.FlipY {
+		d, f = -d, i.Y
+	}
+	fmt.Fprintf(out, "%s cm\n", rotationMatrix(i.Angle, i.Center()))
+	fmt.Fprintf(out, "%s 0 0 %s %s %s cm /%s Do\n", number(a), number(d), number(e), number(f), img.name)
+}
+
+func rotationMatrix(angle float64, center Point) string {
+	sin, cos := math.Sincos(angle)
+	return fmt.Sprintf("%s %s %s %s %s %s",
+		number4(cos), number4(sin), number4(-sin), number4(cos),
+		number(center.X-cos*center.X+sin*center.Y), number(center.Y-sin*center.X-cos*center.Y))
+}
+
+func (doc *pdfDocument) loadImage(dataURL string) *pdfImage {
+	if img, ok := doc.images[dataURL]; ok {
+		return img
+	}
+
+	decoded := doc.decoder.decode(dataURL)
+	if decoded == nil {
+		doc.images[dataURL] = nil
+		return nil
+	}
+
+	bounds := decoded.Bounds()
+	img := &pdfImage{
+		name:   "Im" + strconv.Itoa(len(doc.imageOrder)+1),
+		width:  bounds.Dx(),
+		height: bounds.Dy(),
+		rgb:    make([]byte, 0, bounds.Dx()*bounds.Dy()*3),
+	}
+
+	alpha := make([]byte, 0, bounds.Dx()*bounds.Dy())
+	opaque := true
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := decoded.At(x, y).RGBA()
+			// O PDF espera cores sem alfa pré-multiplicado.
+			if a > 0 && a < 0xffff {
+				r, g, b = r*0xffff/a, g*0xffff/a, b*0xffff/a
+			}
+			img.rgb = append(img.rgb, byte(r>>8), byte(g>>8), byte(b>>8))
+			alpha = append(alpha, byte(a>>8))
+			opaque = opaque && a == 0xffff
+		}
+	}
+	if !opaque {
+		img.alpha = alpha
+	}
+
+	doc.images[dataURL] = img
+	doc.imageOrder = append(doc.imageOrder, img)
+	return img
+}
+
+func (doc *pdfDocument) fillColor(out *bytes.Buffer, c Color) {
+	if name := doc.alpha(c.A); name != "" {
+		fmt.Fprintf(out, "/%s gs\n", name)
+	}
+	fmt.Fprintf(out, "%s rg\n", pdfRGB(c))
+}
+
+func (doc *pdfDocument) strokeColor(out *bytes.Buffer, c Color) {
+	if name := doc.alpha(c.A); name != "" {
+		fmt.Fprintf(out, "/%s gs\n", name)
+	}
+	fmt.Fprintf(out, "%s RG\n", pdfRGB(c))
+}
+
+// alpha devolve o ExtGState da opacidade, ou "" quando ela é total. Cada item
+// fica entre q e Q, então a opacidade não vaza para o próximo.
+func (doc *pdfDocument) alpha(value float64) string {
+	if value >= 1 {
+		return ""
+	}
+	key := number4(value)
+	if name, ok := doc.alphas[key]; ok {
+		return name
+	}
+	name := "GS" + strconv.Itoa(len(doc.alphas)+1)
+	doc.alphas[key] = name
+	return name
+}
+
+func pdfRGB(c Color) string {
+	return fmt.Sprintf("%s %s %s", number4(float64(c.R)/255), number4(float64(c.G)/255), number4(float64(c.B)/255))
+}
+
+func pdfPath(out *bytes.Buffer, path *Path) {
+	for _, segment := range path.Segments {
+		switch segment.Op {
+		case MoveTo:
+			fmt.Fprintf(out, "%s %s m\n", number(segment.Points[0].X), number(segment.Points[0].Y))
+		case LineTo:
+			fmt.Fprintf(out, "%s %s l\n", number(segment.Points[0].X), number(segment.Points[0].Y))
+		case CubicTo:
+			fmt.Fprintf(out, "%s %s %s %s %s %s c\n",
+				number(segment.Points[0].X), number(segment.Points[0].Y),
+				number(segment.Points[1].X), number(segment.Points[1].Y),
+				number(segment.Points[2].X), number(segment.Points[2].Y))
+		case Close:
+			out.WriteString("h\n")
+		}
+	}
+}
+
+// number4 tem mais casas que number, para senos, cossenos e cores.
+func number4(value float64) string {
+	value = math.Round(value*10000) / 10000
+	if value == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// write monta os objetos e a tabela xref. Os objetos fixos vêm primeiro:
+// 1 catálogo, 2 árvore de páginas, 3 recursos; depois as fontes usadas, as
+// imagens e as páginas.
+func (doc *pdfDocument) write(w io.Writer, contents [][]byte, sizes [][2]float64) error {
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	stream := func(dictionary string, data []byte) error {
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		if _, err := writer.Write(data); err != nil {
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n<< %s /Filter /FlateDecode /Length %d >>\nstream\n", len(offsets), dictionary, compressed.Len())
+		out.Write(compressed.Bytes())
+		out.WriteString("\nendstream\nendobj\n")
+		return nil
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	var fonts []*pdfFont
+	for _, font := range doc.fonts {
+		if font != nil {
+			fonts = append(fonts, font)
+		}
+	}
+	next := 4
+	fontIDs := make([]int, len(fonts))
+	for i := range fonts {
+		fontIDs[i] = next
+		next += pdfFontObjects
+	}
+
+	// Cada imagem ocupa um objeto, mais um para a máscara de transparência.
+	imageIDs := make([]int, len(doc.imageOrder))
+	for i, img := range doc.imageOrder {
+		imageIDs[i] = next
+		next++
+		if img.alpha != nil {
+			next++
+		}
+	}
+	firstPage := next
+
+	pageRefs := make([]string, len(contents))
+	for i := range contents {
+		pageRefs[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(pageRefs, " "), len(contents)))
+
+	var resources strings.Builder
+	resources.WriteString("<<")
+	if len(fonts) > 0 {
+		resources.WriteString(" /Font <<")
+		for i, font := range fonts {
+			fmt.Fprintf(&resources, " /%s %d 0 R", font.name, fontIDs[i])
+		}
+		resources.WriteString(" >>")
+	}
+	if len(doc.alphas) > 0 {
+		values := make([]string, 0, len(doc.alphas))
+		for value := range doc.alphas {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+
+		resources.WriteString(" /ExtGState <<")
+		for _, value := range values {
+			fmt.Fprintf(&resources, " /%s << /ca %s /CA %s >>", doc.alphas[value], value, value)
+		}
+		resources.WriteString(" >>")
+	}
+	if len(doc.imageOrder) > 0 {
+		resources.WriteString(" /XObject <<")
+		for i, img := range doc.imageOrder {
+			fmt.Fprintf(&resources, " /%s %d 0 R", img.name, imageIDs[i])
+		}
+		resources.WriteString(" >>")
+	}
+	resources.WriteString(" >>")
+	object(resources.String())
+
+	for i, font := range fonts {
+		name := font.subsetName()
+		descriptor, err := font.descriptor(name)
+		if err != nil {
+			return err
+		}
+		file, err := font.subset()
+		if err != nil {
+			return err
+		}
+
+		id := fontIDs[i]
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+			name, id+1, id+4))
+		object(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /W %s /CIDToGIDMap /Identity >>",
+			name, id+2, font.widths()))
+		object(fmt.Sprintf("<< %s /FontFile2 %d 0 R >>", descriptor, id+3))
+		if err := stream(fmt.Sprintf("/Length1 %d", len(file)), file); err != nil {
+			return err
+		}
+		if err := stream("", font.toUnicode()); err != nil {
+			return err
+		}
+	}
+
+	for i, img := range doc.imageOrder {
+		dictionary := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8", img.width, img.height)
+		if img.alpha != nil {
+			dictionary += fmt.Sprintf(" /SMask %d 0 R", imageIDs[i]+1)
+		}
+		if err := stream(dictionary, img.rgb); err != nil {
+			return err
+		}
+		if img.alpha != nil {
+			dictionary := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8", img.width, img.height)
+			if err := stream(dictionary, img.alpha); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, content := range contents {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources 3 0 R /Contents %d 0 R >>",
+			number(sizes[i][0]), number(sizes[i][1]), firstPage+2*i+1))
+		if err := stream("", content); err != nil {
+			return err
+		}
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
@@ -0,0 +1,420 @@
+package render
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// O PDF embute as mesmas fontes do PNG (Go Regular e Go Mono), só com os
+// glifos usados. O texto vai como índices de glifo (Identity-H) e o ToUnicode
+// leva de volta aos caracteres, então qualquer escrita sai e continua
+// selecionável.
+
+const (
+	pdfFontSans = "F1"
+	pdfFontMono = "F2"
+
+	// pdfFontObjects é quanto cada fonte ocupa no documento: Type0, CIDFont,
+	// descritor, arquivo da fonte e ToUnicode.
+	pdfFontObjects = 5
+)
+
+type pdfFont struct {
+	name     string
+	baseFont string
+	data     []byte
+	face     *sfnt.Font
+	buffer   sfnt.Buffer
+	// runes guarda o primeiro caractere de cada glifo usado, para o ToUnicode.
+	runes map[sfnt.GlyphIndex]rune
+}
+
+// font devolve a fonte embutida que substitui f, criando-a no primeiro uso.
+func (doc *pdfDocument) font(f Font) (*pdfFont, error) {
+	index, name, baseFont, data := 0, pdfFontSans, "GoRegular", goregular.TTF
+	if f.IsMonospace() {
+		index, name, baseFont, data = 1, pdfFontMono, "GoMono", gomono.TTF
+	}
+	if doc.fonts[index] != nil {
+		return doc.fonts[index], nil
+	}
+
+	face, err := outlineFont(f)
+	if err != nil {
+		return nil, err
+	}
+	doc.fonts[index] = &pdfFont{
+		name:     name,
+		baseFont: baseFont,
+		data:     data,
+		face:     face,
+		runes:    map[sfnt.GlyphIndex]rune{},
+	}
+	return doc.fonts[index], nil
+}
+
+// encode devolve a linha como string hexadecimal de glifos e a largura dela em
+// unidades da fonte.
+func (f *pdfFont) encode(line string) ([]byte, float64) {
+	var encoded bytes.Buffer
+	width := 0.0
+
+	encoded.WriteByte('<')
+	for _, r := range line {
+		glyph, _ := f.face.GlyphIndex(&f.buffer, r)
+		if _, ok := f.runes[glyph]; !ok && glyph != 0 {
+			f.runes[glyph] = r
+		}
+		fmt.Fprintf(&encoded, "%04X", uint16(glyph))
+		width += f.advance(glyph)
+	}
+	encoded.WriteByte('>')
+
+	return encoded.Bytes(), width
+}
+
+func (f *pdfFont) advance(glyph sfnt.GlyphIndex) float64 {
+	unitsPerEm := fixed.I(int(f.face.UnitsPerEm()))
+	advance, err := f.face.GlyphAdvance(&f.buffer, glyph, unitsPerEm, font.HintingNone)
+	if err != nil {
+		advance = unitsPerEm / 2
+	}
+	return float64(advance) / 64
+}
+
+func (f *pdfFont) unitsPerEm() float64 {
+	return float64(f.face.UnitsPerEm())
+}
+
+// glyphs devolve os glifos usados em ordem.
+func (f *pdfFont) glyphs() []sfnt.GlyphIndex {
+	glyphs := make([]sfnt.GlyphIndex, 0, len(f.runes))
+	for glyph := range f.runes {
+		glyphs = append(glyphs, glyph)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	return glyphs
+}
+
+// subsetName segue a convenção de prefixar a fonte com seis letras que
+// identificam o subconjunto.
+func (f *pdfFont) subsetName() string {
+	hash := sha1.New()
+	for _, glyph := range f.glyphs() {
+		binary.Write(hash, binary.BigEndian, uint16(glyph))
+	}
+	sum := hash.Sum(nil)
+
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + sum[i]%26
+	}
+	return string(tag) + "+" + f.baseFont
+}
+
+// widths monta o /W do CIDFont, em milésimos de em.
+func (f *pdfFont) widths() string {
+	var out strings.Builder
+	out.WriteString("[")
+	for _, glyph := range f.glyphs() {
+		fmt.Fprintf(&out, " %d [%s]", glyph, number(f.advance(glyph)*1000/f.unitsPerEm()))
+	}
+	out.WriteString(" ]")
+	return out.String()
+}
+
+// descriptor monta o FontDescriptor, sem a referência ao arquivo da fonte.
+func (f *pdfFont) descriptor(name string) (string, error) {
+	head, err := trueTypeTable(f.data, "head")
+	if err != nil {
+		return "", err
+	}
+	if len(head) < 54 {
+		return "", fmt.Errorf("font %s: head table too short", f.baseFont)
+	}
+	metrics, err := f.face.Metrics(&f.buffer, fixed.I(int(f.face.UnitsPerEm())), font.HintingNone)
+	if err != nil {
+		return "", err
+	}
+
+	scale := func(value float64) string {
+		return number(value * 1000 / f.unitsPerEm())
+	}
+	bound := func(offset int) string {
+		return scale(float64(int16(binary.BigEndian.Uint16(head[offset:]))))
+	}
+
+	// 4 marca a fonte como simbólica, o esperado para Identity-H; 1 é largura
+	// fixa.
+	flags := 4
+	if f.name == pdfFontMono {
+		flags |= 1
+	}
+	return fmt.Sprintf("/Type /FontDescriptor /FontName /%s /Flags %d /FontBBox [%s %s %s %s] /ItalicAngle 0 /Ascent %s /Descent %s /CapHeight %s /StemV 80",
+		name, flags, bound(36), bound(38), bound(40), bound(42),
+		scale(float64(metrics.Ascent)/64), scale(-float64(metrics.Descent)/64), scale(float64(metrics.CapHeight)/64)), nil
+}
+
+// toUnicode monta o CMap que leva cada glifo de volta ao caractere.
+func (f *pdfFont) toUnicode() []byte {
+	var out bytes.Buffer
+	out.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	out.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	out.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	out.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	glyphs := f.glyphs()
+	// Cada bloco bfchar aceita no máximo 100 entradas.
+	for start := 0; start < len(glyphs); start += 100 {
+		end := start + 100
+		if end > len(glyphs) {
+			end = len(glyphs)
+		}
+		fmt.Fprintf(&out, "%d beginbfchar\n", end-start)
+		for _, glyph := range glyphs[start:end] {
+			fmt.Fprintf(&out, "<%04X> <", uint16(glyph))
+			for _, unit := range utf16.Encode([]rune{f.runes[glyph]}) {
+				fmt.Fprintf(&out, "%04X", unit)
+			}
+			out.WriteString(">\n")
+		}
+		out.WriteString("endbfchar\n")
+	}
+
+	out.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return out.Bytes()
+}
+
+// trueTypeTable devolve o conteúdo de uma tabela do arquivo TrueType.
+func trueTypeTable(data []byte, tag string) ([]byte, error) {
+	tables, err := trueTypeTables(data)
+	if err != nil {
+		return nil, err
+	}
+	table, ok := tables[tag]
+	if !ok {
+		return nil, fmt.Errorf("font has no %s table", tag)
+	}
+	return table, nil
+}
+
+func trueTypeTables(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("font too short")
+	}
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+16*count {
+		return nil, fmt.Errorf("font table directory truncated")
+	}
+
+	tables := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		entry := data[12+16*i:]
+		offset, length := binary.BigEndian.Uint32(entry[8:]), binary.BigEndian.Uint32(entry[12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("font table %q out of bounds", entry[:4])
+		}
+		tables[string(entry[:4])] = data[offset : offset+length]
+	}
+	return tables, nil
+}
+
+// subsetTables são as tabelas mantidas no arquivo embutido. O texto já vem em
+// glifos, mas cmap, nomes e afins ficam para os leitores que validam a fonte
+// antes de usar; o grosso do arquivo é a glyf, que é o que o subconjunto corta.
+var subsetTables = []string{"OS/2", "cmap", "cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "name", "post", "prep"}
+
+// subset devolve o arquivo da fonte só com os contornos dos glifos usados (e
+// dos que eles compõem). Os índices não mudam; os glifos que sobram ficam
+// vazios na loca.
+func (f *pdfFont) subset() ([]byte, error) {
+	tables, err := trueTypeTables(f.data)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range []string{"glyf", "head", "loca", "maxp"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("font %s has no %s table", f.baseFont, tag)
+		}
+	}
+	head, glyf, loca, maxp := tables["head"], tables["glyf"], tables["loca"], tables["maxp"]
+	if len(head) < 54 || len(maxp) < 6 {
+		return nil, fmt.Errorf("font %s: head or maxp table too short", f.baseFont)
+	}
+
+	count := int(binary.BigEndian.Uint16(maxp[4:]))
+	longLoca := binary.BigEndian.Uint16(head[50:]) == 1
+	offsets := make([]int, count+1)
+	for i := range offsets {
+		if longLoca {
+			if len(loca) < 4*(i+1) {
+				return nil, fmt.Errorf("font %s: loca table truncated", f.baseFont)
+			}
+			offsets[i] = int(binary.BigEndian.Uint32(loca[4*i:]))
+		} else {
+			if len(loca) < 2*(i+1) {
+				return nil, fmt.Errorf("font %s: loca table truncated", f.baseFont)
+			}
+			offsets[i] = 2 * int(binary.BigEndian.Uint16(loca[2*i:]))
+		}
+	}
+	outline := func(glyph int) []byte {
+		if glyph >= count || offsets[glyph] >= offsets[glyph+1] || offsets[glyph+1] > len(glyf) {
+			return nil
+		}
+		return glyf[offsets[glyph]:offsets[glyph+1]]
+	}
+
+	// O glifo 0 (.notdef) sempre vai junto.
+	keep := map[int]bool{0: true}
+	pending := []int{0}
+	for glyph := range f.runes {
+		if !keep[int(glyph)] {
+			keep[int(glyph)] = true
+			pending = append(pending, int(glyph))
+		}
+	}
+	for len(pending) > 0 {
+		glyph := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for _, component := range glyphComponents(outline(glyph)) {
+			if !keep[component] {
+				keep[component] = true
+				pending = append(pending, component)
+			}
+		}
+	}
+
+	var newGlyf bytes.Buffer
+	newLoca := make([]byte, 4*(count+1))
+	for glyph := 0; glyph < count; glyph++ {
+		binary.BigEndian.PutUint32(newLoca[4*glyph:], uint32(newGlyf.Len()))
+		if keep[glyph] {
+			newGlyf.Write(outline(glyph))
+			for newGlyf.Len()%4 != 0 {
+				newGlyf.WriteByte(0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(newLoca[4*count:], uint32(newGlyf.Len()))
+
+	// A loca nova é sempre longa, e o ajuste do checksum é refeito no fim.
+	newHead := append([]byte(nil), head...)
+	binary.BigEndian.PutUint16(newHead[50:], 1)
+	binary.BigEndian.PutUint32(newHead[8:], 0)
+
+	tables["glyf"], tables["loca"], tables["head"] = newGlyf.Bytes(), newLoca, newHead
+
+	var present []string
+	for _, tag := range subsetTables {
+		if _, ok := tables[tag]; ok {
+			present = append(present, tag)
+		}
+	}
+
+	out, headOffset := writeTrueType(tables, present)
+	binary.BigEndian.PutUint32(out[headOffset+8:], 0xB1B0AFBA-trueTypeChecksum(out))
+	return out, nil
+}
+
+// glyphComponents devolve os glifos que um glifo composto referencia.
+func glyphComponents(outline []byte) []int {
+	if len(outline) < 10 || int16(binary.BigEndian.Uint16(outline)) >= 0 {
+		return nil
+	}
+
+	const (
+		argsAreWords   = 0x0001
+		haveScale      = 0x0008
+		moreComponents = 0x0020
+		haveXYScale    = 0x0040
+		haveTwoByTwo   = 0x0080
+	)
+
+	var components []int
+	for offset := 10; offset+4 <= len(outline); {
+		flags := binary.BigEndian.Uint16(outline[offset:])
+		components = append(components, int(binary.BigEndian.Uint16(outline[offset+2:])))
+		offset += 4
+
+		if flags&argsAreWords != 0 {
+			offset += 4
+		} else {
+			offset += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			offset += 2
+		case flags&haveXYScale != 0:
+			offset += 4
+		case flags&haveTwoByTwo != 0:
+			offset += 8
+		}
+
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return components
+}
+
+// writeTrueType monta o arquivo com as tabelas em ordem de tag e devolve onde
+// ficou a head.
+func writeTrueType(tables map[string][]byte, tags []string) ([]byte, int) {
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= len(tags) {
+		searchRange *= 2
+		entrySelector++
+	}
+
+	header := make([]byte, 12+16*len(tags))
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(len(tags)))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange*16))
+	binary.BigEndian.PutUint16(header[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(header[10:], uint16(len(tags)*16-searchRange*16))
+
+	var body bytes.Buffer
+	headOffset := 0
+	for i, tag := range tags {
+		table := tables[tag]
+		offset := len(header) + body.Len()
+		if tag == "head" {
+			headOffset = offset
+		}
+
+		entry := header[12+16*i:]
+		copy(entry, tag)
+		binary.BigEndian.PutUint32(entry[4:], trueTypeChecksum(table))
+		binary.BigEndian.PutUint32(entry[8:], uint32(offset))
+		binary.BigEndian.PutUint32(entry[12:], uint32(len(table)))
+
+		body.Write(table)
+		for body.Len()%4 != 0 {
+			body.WriteByte(0)
+		}
+	}
+
+	return append(header, body.Bytes()...), headOffset
+}
+
+func trueTypeChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
@@ -0,0 +1,236 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/vector"
+)
+
+const (
+	MaxPNGScale = 4
+
+	// Limites para um pedido não alocar uma imagem gigante: 16384 px por lado
+	// e 16 megapixels (64 MB em RGBA).
+	maxPNGSide   = 16384
+	maxPNGPixels = 16 << 20
+)
+
+var ErrImageTooLarge = errors.New("image too large")
+
+// PNGOptions controla a rasterização. Scale multiplica as dimensões do
+// desenho; 1 é um pixel por unidade da cena.
+type PNGOptions struct {
+	Scale float64
+}
+
+func WritePNG(w io.Writer, d *Drawing, options PNGOptions) error {
+	canvas, err := Rasterize(d, options.Scale)
+	if err != nil {
+		return err
+	}
+
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+	return encoder.Encode(w, canvas)
+}
+
+// Rasterize desenha em Go puro, sem navegador: os caminhos viram polígonos
+// preenchidos pelo rasterizador de x/image/vector e os textos são desenhados
+// com os contornos das fontes Go, já que as do Excalidraw não vêm embutidas.
+func Rasterize(d *Drawing, scale float64) (*image.RGBA, error) {
+	if scale <= 0 {
+		scale = 1
+	}
+	if scale > MaxPNGScale {
+		return nil, fmt.Errorf("scale must be at most %d", MaxPNGScale)
+	}
+
+	width := int(math.Ceil(d.Width() * scale))
+	height := int(math.Ceil(d.Height() * scale))
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	if width > maxPNGSide || height > maxPNGSide || width*height > maxPNGPixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrImageTooLarge, width, height)
+	}
+
+	r := &rasterizer{
+		canvas: image.NewRGBA(image.Rect(0, 0, width, height)),
+		origin: d.Bounds.Min,
+		scale:  scale,
+		images: newImageDecoder(),
+	}
+
+	if d.Background.IsVisible() {
+		draw.Draw(r.canvas, r.canvas.Bounds(), image.NewUniform(nrgba(d.Background)), image.Point{}, draw.Src)
+	}
+
+	for i := range d.Items {
+		item := &d.Items[i]
+		switch {
+		case item.Path != nil:
+			lines := flatten(item.Path, r.device)
+			if item.Fill.IsVisible() {
+				r.paint(fillPolygons(lines), item.Fill, item.Clip)
+			}
+			if item.Stroke.IsVisible() {
+				r.paint(strokePolygons(lines, item.StrokeWidth*scale, scaled(item.Dash, scale)), item.Stroke, item.Clip)
+			}
+
+		case item.Text != nil:
+			path, err := textPath(item.Text)
+			if err != nil {
+				return nil, err
+			}
+			r.paint(fillPolygons(flatten(path, r.device)), item.Text.Color, item.Clip)
+
+		case item.Image != nil:
+			r.image(item.Image, item.Clip)
+		}
+	}
+
+	return r.canvas, nil
+}
+
+type rasterizer struct {
+	canvas *image.RGBA
+	origin Point
+	scale  float64
+	images *imageDecoder
+}
+
+func (r *rasterizer) device(point Point) Point {
+	return Point{(point.X - r.origin.X) * r.scale, (point.Y - r.origin.Y) * r.scale}
+}
+
+// area devolve os pixels do canvas que cobrem bounds.
+func (r *rasterizer) area(bounds Rect) image.Rectangle {
+	if bounds.IsEmpty() {
+		return image.Rectangle{}
+	}
+	return image.Rect(
+		int(math.Floor(bounds.Min.X)), int(math.Floor(bounds.Min.Y)),
+		int(math.Ceil(bounds.Max.X)), int(math.Ceil(bounds.Max.Y)),
+	).Intersect(r.canvas.Bounds())
+}
+
+// coverage rasteriza os polígonos numa máscara do tamanho de area, com a
+// origem em area.Min.
+func (r *rasterizer) coverage(polygons []polygon, area image.Rectangle) *image.Alpha {
+	mask := image.NewAlpha(image.Rect(0, 0, area.Dx(), area.Dy()))
+
+	z := vector.NewRasterizer(area.Dx(), area.Dy())
+	z.DrawOp = draw.Src
+	offsetX, offsetY := float64(area.Min.X), float64(area.Min.Y)
+	for _, p := range polygons {
+		z.MoveTo(float32(p[0].X-offsetX), float32(p[0].Y-offsetY))
+		for _, point := range p[1:] {
+			z.LineTo(float32(point.X-offsetX), float32(point.Y-offsetY))
+		}
+		z.ClosePath()
+	}
+	z.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+
+	return mask
+}
+
+// clipMask multiplica mask pelos recortes.
+func (r *rasterizer) clipMask(mask *image.Alpha, clip []*Path, area image.Rectangle) {
+	for _, path := range clip {
+		clipCoverage := r.coverage(fillPolygons(flatten(path, r.device)), area)
+		for i, value := range clipCoverage.Pix {
+			mask.Pix[i] = uint8(uint16(mask.Pix[i]) * uint16(value) / 255)
+		}
+	}
+}
+
+func (r *rasterizer) paint(polygons []polygon, paint Color, clip []*Path) {
+	area := r.area(polygonBounds(polygons))
+	if area.Empty() {
+		return
+	}
+
+	mask := r.coverage(polygons, area)
+	r.clipMask(mask, clip, area)
+	draw.DrawMask(r.canvas, area, image.NewUniform(nrgba(paint)), image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+func (r *rasterizer) image(i *Image, clip []*Path) {
+	corners := rectanglePath(i.X, i.Y, i.Width, i.Height, 0).Transform(func(point Point) Point {
+		return r.device(rotate(point, i.Center(), i.Angle))
+	})
+
+	src := r.images.decode(i.DataURL)
+	if src == nil {
+		placeholder, _ := ParseColor(imagePlaceholderFill)
+		r.paint(fillPolygons(flatten(corners, func(point Point) Point { return point })), placeholder.WithOpacity(i.Opacity), clip)
+		return
+	}
+
+	area := r.area(corners.Bounds())
+	if area.Empty() {
+		return
+	}
+
+	sourceBounds := src.Bounds()
+	scaleX := i.Width / float64(sourceBounds.Dx())
+	scaleY := i.Height / float64(sourceBounds.Dy())
+	originX, originY := i.X, i.Y
+	if i.FlipX {
+		scaleX, originX = -scaleX, i.X+i.Width
+	}
+	if i.FlipY {
+		scaleY, originY = -scaleY, i.Y+i.Height
+	}
+
+	// Pixel da imagem -> cena (escala e espelhamento) -> rotação em torno do
+	// centro -> pixel do canvas.
+	center := i.Center()
+	sin, cos := math.Sincos(i.Angle)
+	s := r.scale
+	matrix := f64.Aff3{
+		s * cos * scaleX, -s * sin * scaleY, s * (cos*(originX-center.X) - sin*(originY-center.Y) + center.X - r.origin.X),
+		s * sin * scaleX, s * cos * scaleY, s * (sin*(originX-center.X) + cos*(originY-center.Y) + center.Y - r.origin.Y),
+	}
+	matrix[2] -= matrix[0]*float64(sourceBounds.Min.X) + matrix[1]*float64(sourceBounds.Min.Y)
+	matrix[5] -= matrix[3]*float64(sourceBounds.Min.X) + matrix[4]*float64(sourceBounds.Min.Y)
+
+	mask := image.NewAlpha(image.Rect(0, 0, area.Dx(), area.Dy()))
+	opacity := uint8(math.Round(i.Opacity * 255))
+	for j := range mask.Pix {
+		mask.Pix[j] = opacity
+	}
+	r.clipMask(mask, clip, area)
+
+	// Pixels fora da máscara contam como transparentes, então ela também
+	// limita o desenho à área da imagem.
+	draw.BiLinear.Transform(r.canvas, matrix, src, sourceBounds, draw.Over, &draw.Options{
+		DstMask:  mask,
+		DstMaskP: image.Point{-area.Min.X, -area.Min.Y},
+	})
+}
+
+func nrgba(c Color) color.NRGBA {
+	return color.NRGBA{R: c.R, G: c.G, B: c.B, A: uint8(math.Round(math.Min(c.A, 1) * 255))}
+}
+
+func scaled(values []float64, scale float64) []float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	result := make([]float64, len(values))
+	for i, value := range values {
+		result[i] = value * scale
+	}
+	return result
+}
@@ -0,0 +1,231 @@
+package render
+
+import (
+	"math"
+)
+
+// O rasterizador trabalha com polígonos em pixels: os caminhos são achatados
+// em polilinhas e os traços viram a união de um quadrilátero por segmento e
+// um círculo por vértice (juntas e pontas arredondadas, como no SVG gerado).
+
+type polygon []Point
+
+type polyline struct {
+	points []Point
+	closed bool
+}
+
+// flatten aplica transform e troca as curvas por segmentos de no máximo
+// alguns pixels.
+func flatten(path *Path, transform func(Point) Point) []polyline {
+	var lines []polyline
+	var current *polyline
+
+	for _, segment := range path.Segments {
+		switch segment.Op {
+		case MoveTo:
+			lines = append(lines, polyline{points: []Point{transform(segment.Points[0])}})
+			current = &lines[len(lines)-1]
+
+		case LineTo:
+			if current == nil {
+				continue
+			}
+			current.points = append(current.points, transform(segment.Points[0]))
+
+		case CubicTo:
+			if current == nil {
+				continue
+			}
+			start := current.points[len(current.points)-1]
+			c1, c2, end := transform(segment.Points[0]), transform(segment.Points[1]), transform(segment.Points[2])
+
+			length := distance(start, c1) + distance(c1, c2) + distance(c2, end)
+			steps := int(math.Ceil(length / 3))
+			if steps < 2 {
+				steps = 2
+			} else if steps > 100 {
+				steps = 100
+			}
+			for i := 1; i <= steps; i++ {
+				current.points = append(current.points, cubicPoint(start, c1, c2, end, float64(i)/float64(steps)))
+			}
+
+		case Close:
+			if current != nil {
+				current.closed = true
+			}
+		}
+	}
+
+	return lines
+}
+
+func cubicPoint(p0, p1, p2, p3 Point, t float64) Point {
+	u := 1 - t
+	a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+	return Point{
+		X: a*p0.X + b*p1.X + c*p2.X + d*p3.X,
+		Y: a*p0.Y + b*p1.Y + c*p2.Y + d*p3.Y,
+	}
+}
+
+func distance(a, b Point) float64 {
+	return math.Hypot(b.X-a.X, b.Y-a.Y)
+}
+
+// fillPolygons fecha todas as polilinhas, como o preenchimento do SVG faz.
+func fillPolygons(lines []polyline) []polygon {
+	polygons := make([]polygon, 0, len(lines))
+	for _, line := range lines {
+		if len(line.points) > 2 {
+			polygons = append(polygons, polygon(line.points))
+		}
+	}
+	return polygons
+}
+
+// strokePolygons gera o contorno do traço. Todos os polígonos saem com a
+// mesma orientação para que as sobreposições se somem em vez de se anularem
+// no rasterizador.
+func strokePolygons(lines []polyline, width float64, dash []float64) []polygon {
+	radius := width / 2
+	if radius <= 0 {
+		return nil
+	}
+
+	var polygons []polygon
+	for _, line := range lines {
+		points := line.points
+		if line.closed && len(points) > 1 {
+			points = append(points[:len(points):len(points)], points[0])
+		}
+		points = dedupe(points)
+
+		pieces := [][]Point{points}
+		if len(dash) > 0 {
+			pieces = dashPolyline(points, dash)
+		}
+
+		for _, piece := range pieces {
+			for i := 0; i+1 < len(piece); i++ {
+				polygons = append(polygons, segmentQuad(piece[i], piece[i+1], radius))
+			}
+			for _, point := range piece {
+				polygons = append(polygons, circlePolygon(point, radius))
+			}
+		}
+	}
+	return polygons
+}
+
+func dedupe(points []Point) []Point {
+	result := make([]Point, 0, len(points))
+	for _, point := range points {
+		if len(result) > 0 && distance(result[len(result)-1], point) < 1e-6 {
+			continue
+		}
+		result = append(result, point)
+	}
+	return result
+}
+
+// dashPolyline corta a polilinha nos trechos visíveis do padrão, que
+// alterna comprimentos desenhados e vazios.
+func dashPolyline(points []Point, pattern []float64) [][]Point {
+	total := 0.0
+	for _, length := range pattern {
+		total += length
+	}
+	if total <= 0 || len(points) < 2 {
+		return [][]Point{points}
+	}
+
+	var pieces [][]Point
+	index, remaining, on := 0, pattern[0], true
+	current := []Point{points[0]}
+
+	for i := 0; i+1 < len(points); i++ {
+		start, end := points[i], points[i+1]
+		length := distance(start, end)
+		travelled := 0.0
+
+		for length-travelled > remaining {
+			travelled += remaining
+			t := travelled / length
+			point := Point{start.X + (end.X-start.X)*t, start.Y + (end.Y-start.Y)*t}
+
+			if on {
+				pieces = append(pieces, append(current, point))
+			} else {
+				current = []Point{point}
+			}
+
+			on = !on
+			index = (index + 1) % len(pattern)
+			remaining = pattern[index]
+		}
+
+		remaining -= length - travelled
+		if on {
+			current = append(current, end)
+		}
+	}
+
+	if on && len(current) > 1 {
+		pieces = append(pieces, current)
+	}
+	return pieces
+}
+
+func segmentQuad(start, end Point, radius float64) polygon {
+	length := distance(start, end)
+	normal := Point{-(end.Y - start.Y) / length * radius, (end.X - start.X) / length * radius}
+	return oriented(polygon{
+		{start.X + normal.X, start.Y + normal.Y},
+		{end.X + normal.X, end.Y + normal.Y},
+		{end.X - normal.X, end.Y - normal.Y},
+		{start.X - normal.X, start.Y - normal.Y},
+	})
+}
+
+func circlePolygon(center Point, radius float64) polygon {
+	steps := int(math.Ceil(2 * math.Pi * radius / 2))
+	if steps < 8 {
+		steps = 8
+	} else if steps > 64 {
+		steps = 64
+	}
+
+	circle := make(polygon, steps)
+	for i := range circle {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(steps))
+		circle[i] = Point{center.X + cos*radius, center.Y + sin*radius}
+	}
+	return oriented(circle)
+}
+
+// oriented devolve o polígono no sentido de área positiva.
+func oriented(p polygon) polygon {
+	area := 0.0
+	for i := range p {
+		next := p[(i+1)%len(p)]
+		area += p[i].X*next.Y - next.X*p[i].Y
+	}
+	if area < 0 {
+		for i, j := 0, len(p)-1; i < j; i, j = i+1, j-1 {
+			p[i], p[j] = p[j], p[i]
+		}
+	}
+	return p
+}
+
+func polygonBounds(polygons []polygon) Rect {
+	bounds := EmptyRect()
+	for _, p := range polygons {
+		for _, point := range p {
+			bounds = bounds.Add(point)
+		}
+	}
+	return bounds
+}
@@ -0,0 +1,167 @@
+package render
+
+import (
+	"math"
+)
+
+// kappa posiciona os pontos de controle de uma cúbica que aproxima um quarto
+// de elipse.
+const kappa = 0.5522847498
+
+func rectanglePath(x, y, width, height, radius float64) *Path {
+	path := &Path{}
+	radius = math.Min(radius, math.Min(width, height)/2)
+
+	if radius <= 0 {
+		path.MoveTo(Point{x, y})
+		path.LineTo(Point{x + width, y})
+		path.LineTo(Point{x + width, y + height})
+		path.LineTo(Point{x, y + height})
+		path.Close()
+		return path
+	}
+
+	k := radius * (1 - kappa)
+	path.MoveTo(Point{x + radius, y})
+	path.LineTo(Point{x + width - radius, y})
+	path.CubicTo(Point{x + width - k, y}, Point{x + width, y + k}, Point{x + width, y + radius})
+	path.LineTo(Point{x + width, y + height - radius})
+	path.CubicTo(Point{x + width, y + height - k}, Point{x + width - k, y + height}, Point{x + width - radius, y + height})
+	path.LineTo(Point{x + radius, y + height})
+	path.CubicTo(Point{x + k, y + height}, Point{x, y + height - k}, Point{x, y + height - radius})
+	path.LineTo(Point{x, y + radius})
+	path.CubicTo(Point{x, y + k}, Point{x + k, y}, Point{x + radius, y})
+	path.Close()
+	return path
+}
+
+func ellipsePath(centerX, centerY, radiusX, radiusY float64) *Path {
+	path := &Path{}
+	kx, ky := radiusX*kappa, radiusY*kappa
+
+	path.MoveTo(Point{centerX + radiusX, centerY})
+	path.CubicTo(Point{centerX + radiusX, centerY + ky}, Point{centerX + kx, centerY + radiusY}, Point{centerX, centerY + radiusY})
+	path.CubicTo(Point{centerX - kx, centerY + radiusY}, Point{centerX - radiusX, centerY + ky}, Point{centerX - radiusX, centerY})
+	path.CubicTo(Point{centerX - radiusX, centerY - ky}, Point{centerX - kx, centerY - radiusY}, Point{centerX, centerY - radiusY})
+	path.CubicTo(Point{centerX + kx, centerY - radiusY}, Point{centerX + radiusX, centerY - ky}, Point{centerX + radiusX, centerY})
+	path.Close()
+	return path
+}
+
+func polygonPath(points []Point) *Path {
+	path := &Path{}
+	for i, point := range points {
+		if i == 0 {
+			path.MoveTo(point)
+		} else {
+			path.LineTo(point)
+		}
+	}
+	path.Close()
+	return path
+}
+
+func polylinePath(points []Point) *Path {
+	path := &Path{}
+	for i, point := range points {
+		if i == 0 {
+			path.MoveTo(point)
+		} else {
+			path.LineTo(point)
+		}
+	}
+	return path
+}
+
+// curvePath passa uma Catmull-Rom pelos pontos, que é como o Excalidraw
+// desenha linhas e setas com cantos arredondados.
+func curvePath(points []Point) *Path {
+	if len(points) < 3 {
+		return polylinePath(points)
+	}
+
+	path := &Path{}
+	path.MoveTo(points[0])
+	for i := 0; i < len(points)-1; i++ {
+		p0 := points[max(i-1, 0)]
+		p1 := points[i]
+		p2 := points[i+1]
+		p3 := points[min(i+2, len(points)-1)]
+
+		path.CubicTo(
+			Point{p1.X + (p2.X-p0.X)/6, p1.Y + (p2.Y-p0.Y)/6},
+			Point{p2.X - (p3.X-p1.X)/6, p2.Y - (p3.Y-p1.Y)/6},
+			p2,
+		)
+	}
+	return path
+}
+
+// hatchPath cobre bounds com linhas paralelas no ângulo dado, espaçadas de
+// gap. O resultado precisa ser recortado pela forma preenchida.
+func hatchPath(bounds Rect, angle float64, gap float64) *Path {
+	path := &Path{}
+	if bounds.IsEmpty() || gap <= 0 {
+		return path
+	}
+
+	center := Point{(bounds.Min.X + bounds.Max.X) / 2, (bounds.Min.Y + bounds.Max.Y) / 2}
+	radius := math.Hypot(bounds.Width(), bounds.Height()) / 2
+
+	for offset := -radius; offset <= radius; offset += gap {
+		start := rotate(Point{center.X - radius, center.Y + offset}, center, angle)
+		end := rotate(Point{center.X + radius, center.Y + offset}, center, angle)
+		path.MoveTo(start)
+		path.LineTo(end)
+	}
+	return path
+}
+
+// endDirection devolve a ponta do caminho, a direção com que ele chega nela e
+// o comprimento do último trecho. Com fromStart vale para o início, com a
+// direção apontando para fora.
+func endDirection(path *Path, fromStart bool) (tip Point, direction Point, length float64, ok bool) {
+	segments := path.Segments
+	if len(segments) < 2 {
+		return Point{}, Point{}, 0, false
+	}
+
+	endpoint := func(segment Segment) Point {
+		if segment.Op == CubicTo {
+			return segment.Points[2]
+		}
+		return segment.Points[0]
+	}
+
+	var before, other Point
+	if fromStart {
+		next := segments[1]
+		tip = segments[0].Points[0]
+		other = endpoint(next)
+		before = other
+		if next.Op == CubicTo && next.Points[0] != tip {
+			before = next.Points[0]
+		}
+	} else {
+		last := segments[len(segments)-1]
+		if last.Op == Close {
+			return Point{}, Point{}, 0, false
+		}
+		tip = endpoint(last)
+		other = endpoint(segments[len(segments)-2])
+		before = other
+		if last.Op == CubicTo {
+			before = last.Points[1]
+			if before == tip {
+				before = last.Points[0]
+			}
+		}
+	}
+
+	dx, dy := tip.X-before.X, tip.Y-before.Y
+	norm := math.Hypot(dx, dy)
+	if norm == 0 {
+		return tip, Point{}, 0, false
+	}
+	return tip, Point{dx / norm, dy / norm}, math.Hypot(tip.X-other.X, tip.Y-other.Y), true
+}
@@ -0,0 +1,267 @@
+package render
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SVGOptions controla detalhes que só fazem sentido no SVG. As fontes de Fonts
+// vão embutidas por @font-face; as que faltarem caem nas fontes genéricas de
+// fallback do visualizador.
+type SVGOptions struct {
+	Fonts *FontCatalog
+}
+
+func WriteSVG(w io.Writer, d *Drawing, options SVGOptions) error {
+	out := bufio.NewWriter(w)
+	s := &svgWriter{out: out, clips: map[*Path]string{}, fonts: options.Fonts}
+
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" viewBox="%s %s %s %s" width="%s" height="%s">`,
+		number(d.Bounds.Min.X), number(d.Bounds.Min.Y), number(d.Width()), number(d.Height()),
+		number(d.Width()), number(d.Height()))
+	out.WriteString("\n")
+
+	s.defs(d, options)
+
+	if d.Background.IsVisible() {
+		fmt.Fprintf(out, `<rect x="%s" y="%s" width="%s" height="%s"%s/>`,
+			number(d.Bounds.Min.X), number(d.Bounds.Min.Y), number(d.Width()), number(d.Height()),
+			paint("fill", d.Background))
+		out.WriteString("\n")
+	}
+
+	for i := range d.Items {
+		s.item(&d.Items[i])
+	}
+
+	out.WriteString("</svg>\n")
+	return out.Flush()
+}
+
+type svgWriter struct {
+	out   *bufio.Writer
+	clips map[*Path]string
+	fonts *FontCatalog
+}
+
+// faceNames dá um nome de família a cada arquivo da fonte. Os arquivos cobrem
+// faixas diferentes de caracteres e, sem unicode-range, faces com o mesmo nome
+// se sobrepõem; com nomes próprios o navegador procura o caractere em cada um.
+func (s *svgWriter) faceNames(font Font) []string {
+	sources := s.fonts.Sources(font)
+	names := make([]string, len(sources))
+	for i := range sources {
+		names[i] = font.Name
+		if i > 0 {
+			names[i] = font.Name + " " + strconv.Itoa(i+1)
+		}
+	}
+	return names
+}
+
+// fontFamily é a lista do atributo font-family, com as faces embutidas antes
+// das fontes de fallback.
+func (s *svgWriter) fontFamily(font Font) string {
+	names := s.faceNames(font)
+	if len(names) == 0 {
+		return font.CSSFamily()
+	}
+	families := make([]string, len(names))
+	for i, name := range names {
+		families[i] = strconv.Quote(name)
+	}
+	return strings.Join(families, ", ") + `, "Segoe UI Emoji", ` + font.Generic
+}
+
+// defs declara as fontes e os clipPaths. Um mesmo caminho de recorte (o de um
+// frame, por exemplo) é declarado uma vez só.
+func (s *svgWriter) defs(d *Drawing, options SVGOptions) {
+	var fontFaces []string
+	for _, font := range d.Fonts() {
+		names := s.faceNames(font)
+		for i, source := range options.Fonts.Sources(font) {
+			fontFaces = append(fontFaces, fmt.Sprintf(`@font-face { font-family: %q; src: url(%s) format("woff2"); }`, names[i], source))
+		}
+	}
+
+	var clips []*Path
+	for _, item := range d.Items {
+		for _, clip := range item.Clip {
+			if _, ok := s.clips[clip]; !ok {
+				s.clips[clip] = "clip-" + strconv.Itoa(len(s.clips)+1)
+				clips = append(clips, clip)
+			}
+		}
+	}
+
+	if len(fontFaces) == 0 && len(clips) == 0 {
+		return
+	}
+
+	s.out.WriteString("<defs>\n")
+	if len(fontFaces) > 0 {
+		s.out.WriteString("<style>\n")
+		for _, fontFace := range fontFaces {
+			xml.EscapeText(s.out, []byte(fontFace))
+			s.out.WriteString("\n")
+		}
+		s.out.WriteString("</style>\n")
+	}
+	for _, clip := range clips {
+		fmt.Fprintf(s.out, `<clipPath id="%s"><path d="%s"/></clipPath>`, s.clips[clip], pathData(clip))
+		s.out.WriteString("\n")
+	}
+	s.out.WriteString("</defs>\n")
+}
+
+func (s *svgWriter) item(item *Item) {
+	// Recortes aninhados se intersectam, que é o que Item.Clip pede.
+	for _, clip := range item.Clip {
+		fmt.Fprintf(s.out, `<g clip-path="url(#%s)">`, s.clips[clip])
+	}
+
+	switch {
+	case item.Path != nil:
+		s.path(item)
+	case item.Text != nil:
+		s.text(item.Text)
+	case item.Image != nil:
+		s.image(item.Image)
+	}
+
+	for range item.Clip {
+		s.out.WriteString("</g>")
+	}
+	s.out.WriteString("\n")
+}
+
+func (s *svgWriter) path(item *Item) {
+	if item.Path.IsEmpty() {
+		return
+	}
+
+	var attributes strings.Builder
+	if item.Fill.IsVisible() {
+		attributes.WriteString(paint("fill", item.Fill))
+	} else {
+		attributes.WriteString(` fill="none"`)
+	}
+	if item.Stroke.IsVisible() && item.StrokeWidth > 0 {
+		attributes.WriteString(paint("stroke", item.Stroke))
+		fmt.Fprintf(&attributes, ` stroke-width="%s" stroke-linecap="round" stroke-linejoin="round"`, number(item.StrokeWidth))
+		if len(item.Dash) > 0 {
+			values := make([]string, len(item.Dash))
+			for i, value := range item.Dash {
+				values[i] = number(value)
+			}
+			fmt.Fprintf(&attributes, ` stroke-dasharray="%s"`, strings.Join(values, " "))
+		}
+	}
+
+	fmt.Fprintf(s.out, `<path d="%s"%s/>`, pathData(item.Path), attributes.String())
+}
+
+func (s *svgWriter) text(t *Text) {
+	anchor := "start"
+	switch t.Align {
+	case AlignCenter:
+		anchor = "middle"
+	case AlignRight:
+		anchor = "end"
+	}
+
+	fmt.Fprintf(s.out, `<text font-family="%s" font-size="%s" text-anchor="%s" xml:space="preserve"%s%s>`,
+		escape(s.fontFamily(t.Font)), number(t.FontSize), anchor, paint("fill", t.Color), rotation(t.Angle, t.Center))
+
+	for i, line := range t.Lines {
+		fmt.Fprintf(s.out, `<tspan x="%s" y="%s">%s</tspan>`, number(t.X), number(t.Baseline(i)), escape(line))
+	}
+	s.out.WriteString("</text>")
+}
+
+func (s *svgWriter) image(i *Image) {
+	transform := rotation(i.Angle, i.Center())
+	if i.FlipX || i.FlipY {
+		scaleX, scaleY := 1.0, 1.0
+		if i.FlipX {
+			scaleX = -1
+		}
+		if i.FlipY {
+			scaleY = -1
+		}
+		center := i.Center()
+		flip := fmt.Sprintf("translate(%s %s) scale(%s %s) translate(%s %s)",
+			number(center.X), number(center.Y), number(scaleX), number(scaleY), number(-center.X), number(-center.Y))
+		if transform == "" {
+			transform = fmt.Sprintf(` transform="%s"`, flip)
+		} else {
+			transform = strings.TrimSuffix(transform, `"`) + " " + flip + `"`
+		}
+	}
+
+	opacity := ""
+	if i.Opacity < 1 {
+		opacity = fmt.Sprintf(` opacity="%s"`, number(i.Opacity))
+	}
+
+	fmt.Fprintf(s.out, `<image x="%s" y="%s" width="%s" height="%s" preserveAspectRatio="none" href="%s"%s%s/>`,
+		number(i.X), number(i.Y), number(i.Width), number(i.Height), escape(i.DataURL), opacity, transform)
+}
+
+func rotation(angle float64, center Point) string {
+	if angle == 0 {
+		return ""
+	}
+	return fmt.Sprintf(` transform="rotate(%s %s %s)"`, number(angle*180/math.Pi), number(center.X), number(center.Y))
+}
+
+func paint(attribute string, color Color) string {
+	if color.A >= 1 {
+		return fmt.Sprintf(` %s="%s"`, attribute, color.Hex())
+	}
+	return fmt.Sprintf(` %s="%s" %s-opacity="%s"`, attribute, color.Hex(), attribute, number(color.A))
+}
+
+func pathData(path *Path) string {
+	var data strings.Builder
+	for _, segment := range path.Segments {
+		if data.Len() > 0 {
+			data.WriteByte(' ')
+		}
+		switch segment.Op {
+		case MoveTo:
+			fmt.Fprintf(&data, "M%s %s", number(segment.Points[0].X), number(segment.Points[0].Y))
+		case LineTo:
+			fmt.Fprintf(&data, "L%s %s", number(segment.Points[0].X), number(segment.Points[0].Y))
+		case CubicTo:
+			fmt.Fprintf(&data, "C%s %s %s %s %s %s",
+				number(segment.Points[0].X), number(segment.Points[0].Y),
+				number(segment.Points[1].X), number(segment.Points[1].Y),
+				number(segment.Points[2].X), number(segment.Points[2].Y))
+		case Close:
+			data.WriteByte('Z')
+		}
+	}
+	return data.String()
+}
+
+// number escreve com no máximo duas casas, o bastante para um desenho em
+// pixels, e sem zeros sobrando.
+func number(value float64) string {
+	value = math.Round(value*100) / 100
+	if value == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func escape(value string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(value))
+	return escaped.String()
+}
@@ -43,8 +43,20 @@ export interface UploadFileRequest {
 }
 
 export const fileApi = {
+  // A listagem vem paginada; segue o cursor até a última página.
   getFiles: async (): Promise<FileMetadata[]> => {
-    return api.get("files").json<FileMetadata[]>();
+    const files: FileMetadata[] = [];
+    let cursor: string | null = null;
+    do {
+      const searchParams: Record<string, string> = { limit: "1000" };
+      if (cursor) {
+        searchParams.cursor = cursor;
+      }
+      const response = await api.get("files", { searchParams });
+      files.push(...(await response.json<FileMetadata[]>()));
+      cursor = response.headers.get("X-Next-Cursor");
+    } while (cursor);
+    return files;
   },
 
   getFileById: async (id: string): Promise<FileMetadata> => {